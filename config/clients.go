@@ -0,0 +1,250 @@
+package config
+
+import (
+	"auth-service/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client response modes control how the session is handed back to the client after a login.
+const (
+	// ResponseModeCookie sets the session cookie and redirects to the redirect URI.
+	ResponseModeCookie = "cookie"
+	// ResponseModeQuery adds the session ID to the redirect URI's query, for clients that cannot share cookies.
+	ResponseModeQuery = "query"
+	// ResponseModeFragment adds the session ID to the redirect URI's fragment so it never reaches a server.
+	ResponseModeFragment = "fragment"
+)
+
+// Client types control which tokens a client may receive.
+const (
+	// ClientTypeBrowser is a front-end running in the browser. It never receives refresh tokens, so an
+	// XSS cannot steal a long-lived grant; the service refreshes its tokens instead.
+	ClientTypeBrowser = "browser"
+	// ClientTypeBackend is a trusted server-side client that receives refresh tokens.
+	ClientTypeBackend = "backend"
+)
+
+// ClientCookieConfig controls the session cookie set for a client.
+type ClientCookieConfig struct {
+	Domain   string `json:"domain"`
+	SameSite string `json:"same_site"`
+}
+
+// ClientConfig is a registered client application and the policy applied to its logins.
+type ClientConfig struct {
+	ID string `json:"id"`
+	// Type is ClientTypeBrowser (default) or ClientTypeBackend.
+	Type string `json:"type"`
+	// RedirectURIs are registered redirect patterns, see utils.ParseRedirectRule.
+	RedirectURIs []string `json:"redirect_uris"`
+	// AllowedOrigins are the browser origins the client calls the API from.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// Providers lists the providers the client may log in with. Empty allows all providers.
+	Providers []string `json:"providers"`
+	// Scopes overrides the scopes requested from a provider, keyed by provider.
+	Scopes       map[string][]string `json:"scopes"`
+	Cookie       ClientCookieConfig  `json:"cookie"`
+	ResponseMode string              `json:"response_mode"`
+
+	redirectRules []utils.RedirectRule
+}
+
+var (
+	clientsMu             sync.RWMutex
+	clients               = map[string]*ClientConfig{}
+	clientRegistryModTime time.Time
+)
+
+// initClients loads the client registry from the JSON file named by CLIENT_REGISTRY_FILE.
+// Without a registry, logins fall back to the global redirect allowlist.
+func initClients() {
+	if err := ReloadClients(); err != nil {
+		log.Fatalf("Failed to load client registry: %v", err)
+	}
+}
+
+// GetClient returns the registered client with the given ID.
+func GetClient(id string) (*ClientConfig, bool) {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+	client, exists := clients[id]
+	return client, exists
+}
+
+// ReloadClients reloads the client registry and the CORS origins derived from it. The current
+// registry is kept if the file cannot be loaded.
+func ReloadClients() error {
+	path := getEnv("CLIENT_REGISTRY_FILE", "")
+	loaded := map[string]*ClientConfig{}
+	var modTime time.Time
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to read client registry %s: %w", path, err)
+		}
+		modTime = info.ModTime()
+		if loaded, err = loadClients(path); err != nil {
+			return err
+		}
+	}
+
+	cors, err := buildCORSConfig(loaded)
+	if err != nil {
+		return err
+	}
+
+	clientsMu.Lock()
+	clients = loaded
+	clientRegistryModTime = modTime
+	clientsMu.Unlock()
+	corsConfig.Store(cors)
+	return nil
+}
+
+// WatchClientRegistry reloads the client registry whenever the file changes, so clients and their
+// CORS origins can be updated without a restart.
+func WatchClientRegistry(interval time.Duration) {
+	path := getEnv("CLIENT_REGISTRY_FILE", "")
+	if path == "" {
+		return
+	}
+	for range time.Tick(interval) {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("Failed to check client registry %s: %v", path, err)
+			continue
+		}
+		clientsMu.RLock()
+		changed := !info.ModTime().Equal(clientRegistryModTime)
+		clientsMu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err = ReloadClients(); err != nil {
+			log.Printf("Failed to reload client registry, keeping the current clients: %v", err)
+			continue
+		}
+		log.Printf("Reloaded client registry %s", path)
+	}
+}
+
+func loadClients(path string) (map[string]*ClientConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client registry %s: %w", path, err)
+	}
+	var registered []*ClientConfig
+	if err = json.Unmarshal(data, &registered); err != nil {
+		return nil, fmt.Errorf("failed to parse client registry %s: %w", path, err)
+	}
+	loaded := make(map[string]*ClientConfig, len(registered))
+	for _, client := range registered {
+		if err = validateClient(client); err != nil {
+			return nil, err
+		}
+		loaded[client.ID] = client
+	}
+	return loaded, nil
+}
+
+func validateClient(client *ClientConfig) error {
+	id := client.ID
+	if id == "" {
+		return fmt.Errorf("client registry contains a client without an id")
+	}
+	if len(client.RedirectURIs) == 0 {
+		return fmt.Errorf("client %s has no redirect_uris", id)
+	}
+	for _, pattern := range client.RedirectURIs {
+		rule, err := utils.ParseRedirectRule(pattern)
+		if err != nil {
+			return fmt.Errorf("client %s has an invalid redirect URI: %w", id, err)
+		}
+		client.redirectRules = append(client.redirectRules, *rule)
+	}
+	for _, origin := range client.AllowedOrigins {
+		if _, _, err := parseCORSOrigin(origin); err != nil {
+			return fmt.Errorf("client %s has an invalid allowed origin: %w", id, err)
+		}
+	}
+	for _, provider := range client.Providers {
+		if !IsSupportedProvider(provider) {
+			return fmt.Errorf("client %s permits unknown provider: %s", id, provider)
+		}
+	}
+	switch client.ResponseMode {
+	case "":
+		client.ResponseMode = ResponseModeCookie
+	case ResponseModeCookie, ResponseModeQuery, ResponseModeFragment:
+	default:
+		return fmt.Errorf("client %s has an unsupported response_mode: %s", id, client.ResponseMode)
+	}
+	switch client.Type {
+	case "":
+		client.Type = ClientTypeBrowser
+	case ClientTypeBrowser:
+	case ClientTypeBackend:
+		// A backend client's session must reach its server rather than a browser cookie or script.
+		if client.ResponseMode != ResponseModeQuery {
+			return fmt.Errorf("client %s is a backend client and must use response_mode %s", id, ResponseModeQuery)
+		}
+	default:
+		return fmt.Errorf("client %s has an unsupported type: %s", id, client.Type)
+	}
+	switch strings.ToLower(client.Cookie.SameSite) {
+	case "", "lax", "strict", "none":
+	default:
+		return fmt.Errorf("client %s has an unsupported cookie same_site: %s", id, client.Cookie.SameSite)
+	}
+	return nil
+}
+
+// AllowsProvider reports whether the client may log in with the provider.
+func (c *ClientConfig) AllowsProvider(provider string) bool {
+	if len(c.Providers) == 0 {
+		return true
+	}
+	for _, p := range c.Providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// ReceivesRefreshTokens reports whether the client is trusted with refresh tokens.
+func (c *ClientConfig) ReceivesRefreshTokens() bool {
+	return c.Type == ClientTypeBackend
+}
+
+// ValidateRedirectURI checks the URI against the client's registered redirect URIs.
+func (c *ClientConfig) ValidateRedirectURI(uri string) error {
+	return utils.ValidateRedirectURIAgainstRules(uri, c.redirectRules)
+}
+
+// ScopesFor returns the scopes to request from the provider for this client.
+func (c *ClientConfig) ScopesFor(provider string, defaults []string) []string {
+	if scopes, exists := c.Scopes[provider]; exists {
+		return scopes
+	}
+	return defaults
+}
+
+// CookieSameSite returns the SameSite mode for the client's session cookie, defaulting to Lax.
+func (c *ClientConfig) CookieSameSite() http.SameSite {
+	switch strings.ToLower(c.Cookie.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// CORSConfig is the CORS policy for browser callers.
+type CORSConfig struct {
+	// AllowedOrigins are explicitly listed origins. Only these may make credentialed requests.
+	AllowedOrigins []string
+	// OriginPatterns are wildcard subdomain origins such as "https://*.example.com". Matching
+	// origins may call the API, but without credentials.
+	OriginPatterns []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	MaxAge         int
+}
+
+var corsConfig atomic.Pointer[CORSConfig]
+
+// GetCORSConfig returns the current CORS policy. It changes whenever the client registry is reloaded.
+func GetCORSConfig() *CORSConfig {
+	return corsConfig.Load()
+}
+
+// buildCORSConfig combines the CORS_* environment variables with the origins of the registered clients.
+func buildCORSConfig(clients map[string]*ClientConfig) (*CORSConfig, error) {
+	maxAge, err := strconv.Atoi(getNonEmptyEnv("CORS_MAX_AGE", "300"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_MAX_AGE: %w", err)
+	}
+	cors := &CORSConfig{
+		AllowedMethods: splitList(getNonEmptyEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE")),
+		AllowedHeaders: splitList(getNonEmptyEnv("CORS_ALLOWED_HEADERS", "Accept,Authorization,Content-Type")),
+		MaxAge:         maxAge,
+	}
+
+	origins := splitList(getNonEmptyEnv("CORS_ALLOWED_ORIGINS", "http://localhost:5173"))
+	for _, client := range clients {
+		origins = append(origins, client.AllowedOrigins...)
+	}
+	for _, origin := range origins {
+		normalized, wildcard, err := parseCORSOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		if wildcard {
+			cors.OriginPatterns = append(cors.OriginPatterns, normalized)
+		} else {
+			cors.AllowedOrigins = append(cors.AllowedOrigins, normalized)
+		}
+	}
+	return cors, nil
+}
+
+// parseCORSOrigin validates an origin of the form scheme://[*.]host[:port] and returns it normalized,
+// along with whether it is a wildcard subdomain pattern.
+func parseCORSOrigin(origin string) (string, bool, error) {
+	normalized := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+	wildcard := strings.Contains(normalized, "*")
+
+	parsed, err := url.Parse(strings.Replace(normalized, "://*.", "://wildcard.", 1))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" || parsed.User != nil {
+		return "", false, fmt.Errorf("invalid CORS origin %q; expected scheme://host[:port]", origin)
+	}
+	if wildcard && (!strings.HasPrefix(parsed.Host, "wildcard.") || strings.Contains(parsed.Host, "*")) {
+		return "", false, fmt.Errorf("invalid CORS origin %q; only a leading \"*.\" wildcard is supported", origin)
+	}
+	return normalized, wildcard, nil
+}
+
+// IsCredentialedOrigin reports whether the origin is explicitly listed and may make credentialed requests.
+func (c *CORSConfig) IsCredentialedOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesOriginPattern reports whether the origin is a subdomain matched by one of the wildcard patterns.
+func (c *CORSConfig) MatchesOriginPattern(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range c.OriginPatterns {
+		prefix, suffix, _ := strings.Cut(pattern, "*")
+		if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) || len(origin) <= len(prefix)+len(suffix) {
+			continue
+		}
+		// The wildcard only covers subdomain labels.
+		subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+		if !strings.ContainsAny(subdomain, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
+// getNonEmptyEnv is like getEnv, but also falls back when the variable is set to an empty value,
+// as docker-compose does for unset variables.
+func getNonEmptyEnv(key, fallback string) string {
+	if value := getEnv(key, ""); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+// CredentialProviderConfig describes a provider without OAuth support that exchanges an
+// app ID plus the user's username and password for a user auth token.
+type CredentialProviderConfig struct {
+	AppID    string
+	LoginURL string
+}
+
+var CredentialProviders map[string]*CredentialProviderConfig
+
+func initCredentialProviders() {
+	CredentialProviders = map[string]*CredentialProviderConfig{
+		"qobuz": {
+			AppID:    getEnv("QOBUZ_APP_ID", ""),
+			LoginURL: "https://www.qobuz.com/api.json/0.2/user/login",
+		},
+	}
+	validateCredentialProviders()
+}
+
+func validateCredentialProviders() {
+	for name, config := range CredentialProviders {
+		if config.AppID == "" {
+			log.Fatalf("Missing environment variable for: %s_APP_ID", strings.ToUpper(name))
+		}
+	}
+}
+
+// IsSupportedProvider reports whether the provider is configured as either an OAuth or a credential provider.
+func IsSupportedProvider(provider string) bool {
+	if _, exists := Providers[provider]; exists {
+		return true
+	}
+	_, exists := CredentialProviders[provider]
+	return exists
+}
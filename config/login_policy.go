@@ -0,0 +1,191 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// Login policy effects.
+const (
+	LoginAllow = "allow"
+	LoginDeny  = "deny"
+)
+
+// defaultLoginDenyReason is the reason given for denials by rules that do not name one.
+const defaultLoginDenyReason = "login_denied"
+
+// loginDenyReasonPattern keeps reasons usable as stable error codes in redirect URIs.
+var loginDenyReasonPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// LoginAttributes are the facts about a login that policy rules match on.
+type LoginAttributes struct {
+	Provider string
+	UserID   string
+	Email    string
+	Country  string
+	// EmailVerified is nil when the provider does not report whether the email is verified.
+	EmailVerified *bool
+}
+
+// LoginMatch matches logins. Every condition that is set must hold; a condition listing several values
+// holds when any of them matches. Emails, domains and countries are compared case-insensitively.
+type LoginMatch struct {
+	Providers    []string `json:"providers"`
+	UserIDs      []string `json:"user_ids"`
+	Emails       []string `json:"emails"`
+	EmailDomains []string `json:"email_domains"`
+	Countries    []string `json:"countries"`
+	// EmailVerified matches on whether the provider reports the email as verified. Logins whose
+	// provider does not report it never match.
+	EmailVerified *bool `json:"email_verified"`
+}
+
+// LoginRule allows or denies the logins it matches, except those matched by Unless.
+type LoginRule struct {
+	Name   string `json:"name"`
+	Effect string `json:"effect"`
+	// Reason is the error code given to the client when the rule denies a login.
+	Reason string `json:"reason"`
+	LoginMatch
+	Unless *LoginMatch `json:"unless"`
+}
+
+// LoginPolicy decides which logins are allowed. Rules are evaluated in order and the first that matches
+// decides; logins no rule matches get the default effect.
+type LoginPolicy struct {
+	Default string       `json:"default"`
+	Rules   []*LoginRule `json:"rules"`
+}
+
+// LoginDecision is the outcome of evaluating the login policy.
+type LoginDecision struct {
+	Allowed bool
+	// Rule names the rule that decided, empty for the default effect.
+	Rule string
+	// Reason is the error code of a denial.
+	Reason string
+}
+
+var loginPolicy atomic.Pointer[LoginPolicy]
+
+// initLoginPolicy loads the login policy from the JSON file named by LOGIN_POLICY_FILE. Without a
+// policy, every login is allowed.
+func initLoginPolicy() {
+	if err := ReloadLoginPolicy(); err != nil {
+		log.Fatalf("Failed to load login policy: %v", err)
+	}
+}
+
+// GetLoginPolicy returns the current login policy.
+func GetLoginPolicy() *LoginPolicy {
+	if policy := loginPolicy.Load(); policy != nil {
+		return policy
+	}
+	return &LoginPolicy{Default: LoginAllow}
+}
+
+// ReloadLoginPolicy reloads the login policy. The current policy is kept if the file cannot be loaded.
+func ReloadLoginPolicy() error {
+	policy := &LoginPolicy{Default: LoginAllow}
+	if path := getEnv("LOGIN_POLICY_FILE", ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read login policy %s: %w", path, err)
+		}
+		if err = json.Unmarshal(data, policy); err != nil {
+			return fmt.Errorf("failed to parse login policy %s: %w", path, err)
+		}
+		if err = validateLoginPolicy(policy); err != nil {
+			return err
+		}
+	}
+	loginPolicy.Store(policy)
+	return nil
+}
+
+func validateLoginPolicy(policy *LoginPolicy) error {
+	if policy.Default == "" {
+		policy.Default = LoginAllow
+	}
+	if policy.Default != LoginAllow && policy.Default != LoginDeny {
+		return fmt.Errorf("invalid login policy default %q: must be %s or %s", policy.Default, LoginAllow, LoginDeny)
+	}
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Effect != LoginAllow && rule.Effect != LoginDeny {
+			return fmt.Errorf("login policy %s has invalid effect %q: must be %s or %s", rule.Name, rule.Effect, LoginAllow, LoginDeny)
+		}
+		if rule.Reason == "" {
+			rule.Reason = defaultLoginDenyReason
+		}
+		if !loginDenyReasonPattern.MatchString(rule.Reason) {
+			return fmt.Errorf("login policy %s has invalid reason %q: use lowercase letters, digits and underscores", rule.Name, rule.Reason)
+		}
+	}
+	return nil
+}
+
+// Evaluate decides whether the login is allowed.
+func (p *LoginPolicy) Evaluate(login LoginAttributes) LoginDecision {
+	for _, rule := range p.Rules {
+		if !rule.Matches(login) || (rule.Unless != nil && rule.Unless.Matches(login)) {
+			continue
+		}
+		if rule.Effect == LoginAllow {
+			return LoginDecision{Allowed: true, Rule: rule.Name}
+		}
+		return LoginDecision{Rule: rule.Name, Reason: rule.Reason}
+	}
+	if p.Default == LoginDeny {
+		return LoginDecision{Reason: defaultLoginDenyReason}
+	}
+	return LoginDecision{Allowed: true}
+}
+
+// Matches reports whether every condition that is set holds for the login.
+func (m *LoginMatch) Matches(login LoginAttributes) bool {
+	_, domain, _ := strings.Cut(login.Email, "@")
+	switch {
+	case len(m.Providers) > 0 && !containsFold(m.Providers, login.Provider):
+		return false
+	case len(m.UserIDs) > 0 && !contains(m.UserIDs, login.UserID):
+		return false
+	case len(m.Emails) > 0 && !containsFold(m.Emails, login.Email):
+		return false
+	case len(m.EmailDomains) > 0 && !containsFold(m.EmailDomains, domain):
+		return false
+	case len(m.Countries) > 0 && !containsFold(m.Countries, login.Country):
+		return false
+	case m.EmailVerified != nil && (login.EmailVerified == nil || *login.EmailVerified != *m.EmailVerified):
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+)
+
+// Profile fields that may be required of a provider's user profile. The user ID is always required.
+const (
+	ProfileFieldDisplayName = "display_name"
+	ProfileFieldEmail       = "email"
+)
+
+// ProfileRequirements are the profile fields a provider's accounts are expected to have.
+type ProfileRequirements struct {
+	Required []string
+	// EmailScope is the scope that grants access to the email. The email is only required when it was
+	// granted; empty means the provider always returns the email it has.
+	EmailScope string
+}
+
+// Requires reports whether the field is required.
+func (p ProfileRequirements) Requires(field string) bool {
+	return contains(p.Required, field)
+}
+
+// defaultProfileRequirements are the built-in requirements of each provider. SoundCloud never exposes
+// an email address, and Qobuz returns the account's email with every login.
+var defaultProfileRequirements = map[string]ProfileRequirements{
+	"spotify":    {Required: []string{ProfileFieldDisplayName, ProfileFieldEmail}, EmailScope: "user-read-email"},
+	"tidal":      {Required: []string{ProfileFieldDisplayName, ProfileFieldEmail}, EmailScope: "user.read"},
+	"soundcloud": {Required: []string{ProfileFieldDisplayName}},
+	"qobuz":      {Required: []string{ProfileFieldDisplayName, ProfileFieldEmail}},
+}
+
+// ProfileRequirementsByProvider holds each login provider's profile requirements.
+var ProfileRequirementsByProvider map[string]ProfileRequirements
+
+// initProfileRequirements reads <PROVIDER>_REQUIRED_PROFILE_FIELDS, a comma-separated list of the
+// profile fields a provider's accounts are expected to have, overriding the built-in defaults. An
+// empty value requires none. It covers OAuth and credential providers, so it runs after both are set up.
+func initProfileRequirements() {
+	ProfileRequirementsByProvider = map[string]ProfileRequirements{}
+	for name := range Providers {
+		ProfileRequirementsByProvider[name] = loadProfileRequirements(name)
+	}
+	for name := range CredentialProviders {
+		ProfileRequirementsByProvider[name] = loadProfileRequirements(name)
+	}
+}
+
+func loadProfileRequirements(name string) ProfileRequirements {
+	requirements := defaultProfileRequirements[name]
+	key := strings.ToUpper(name) + "_REQUIRED_PROFILE_FIELDS"
+	if value, exists := os.LookupEnv(key); exists {
+		requirements.Required = parseProfileFields(key, value)
+	}
+	return requirements
+}
+
+func parseProfileFields(key, value string) []string {
+	fields := []string{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		switch field {
+		case "":
+		case ProfileFieldDisplayName, ProfileFieldEmail:
+			fields = append(fields, field)
+		default:
+			log.Fatalf("Invalid %s field %q: must be %s or %s", key, field, ProfileFieldDisplayName, ProfileFieldEmail)
+		}
+	}
+	return fields
+}
+
+// GetProfileRequirements returns the provider's profile requirements.
+var GetProfileRequirements = func(provider string) ProfileRequirements {
+	if requirements, exists := ProfileRequirementsByProvider[provider]; exists {
+		return requirements
+	}
+	return defaultProfileRequirements[provider]
+}
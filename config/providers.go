@@ -33,8 +33,25 @@ func InitConfig() {
 				AuthStyle: oauth2.AuthStyleInParams,
 			},
 		},
+		"soundcloud": {
+			ClientID:     getEnv("SOUNDCLOUD_CLIENT_ID", ""),
+			ClientSecret: getEnv("SOUNDCLOUD_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("SOUNDCLOUD_REDIRECT_URL", ""),
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://secure.soundcloud.com/authorize",
+				TokenURL:  "https://secure.soundcloud.com/oauth/token",
+				AuthStyle: oauth2.AuthStyleInHeader, // SoundCloud requires client credentials via basic auth
+			},
+		},
 	}
 	validateProviders()
+	initRevocations()
+	initCredentialProviders()
+	initSessionLimits()
+	initProfileRequirements()
+	initClients()
+	initLoginPolicy()
+	initTrustedProxies()
 }
 
 func getEnv(key, fallback string) string {
@@ -64,7 +81,34 @@ var GetProviderUserInfoURL = func(provider string) (string, error) {
 		return "https://api.spotify.com/v1/me", nil
 	case "tidal":
 		return "https://openapi.tidal.com/v2/users/me", nil
+	case "soundcloud":
+		return "https://api.soundcloud.com/me", nil
 	default:
 		return "", fmt.Errorf("provider not supported: %s", provider)
 	}
 }
+
+// GetProviderAPIBaseURL returns the base URL of the provider's web API, the only upstream the API
+// proxy forwards requests to.
+var GetProviderAPIBaseURL = func(provider string) (string, error) {
+	switch provider {
+	case "spotify":
+		return "https://api.spotify.com", nil
+	case "tidal":
+		return "https://openapi.tidal.com", nil
+	case "soundcloud":
+		return "https://api.soundcloud.com", nil
+	default:
+		return "", fmt.Errorf("provider API not supported: %s", provider)
+	}
+}
+
+// GetProviderAuthScheme returns the Authorization header scheme the provider's API expects.
+func GetProviderAuthScheme(provider string) string {
+	switch provider {
+	case "soundcloud":
+		return "OAuth"
+	default:
+		return "Bearer"
+	}
+}
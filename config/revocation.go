@@ -0,0 +1,88 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// RevocationConfig describes a provider's OAuth 2.0 token revocation endpoint (RFC 7009).
+type RevocationConfig struct {
+	URL string
+	// AuthStyle is how the client credentials are sent: in the form body or with HTTP Basic auth.
+	AuthStyle oauth2.AuthStyle
+}
+
+// Revocations holds the revocation endpoint of each OAuth provider that has one. Tokens of other
+// providers can only be deleted locally.
+var Revocations map[string]*RevocationConfig
+
+// RetainLinkedTokens keeps the tokens of accounts linked to a user when one of the user's sessions logs
+// out, so the user's offline grant and other sessions keep working. By default they are revoked.
+var RetainLinkedTokens bool
+
+// defaultRevocations are the providers' published revocation endpoints. Spotify has none. Google is not
+// a login provider yet; its endpoint applies once it is configured.
+var defaultRevocations = map[string]RevocationConfig{
+	"google": {
+		URL:       "https://oauth2.googleapis.com/revoke",
+		AuthStyle: oauth2.AuthStyleInParams,
+	},
+	"tidal": {
+		URL:       "https://auth.tidal.com/v1/oauth2/revoke",
+		AuthStyle: oauth2.AuthStyleInParams,
+	},
+	"soundcloud": {
+		URL:       "https://secure.soundcloud.com/oauth/revoke",
+		AuthStyle: oauth2.AuthStyleInHeader,
+	},
+}
+
+// initRevocations applies <PROVIDER>_REVOCATION_URL and <PROVIDER>_REVOCATION_AUTH_STYLE (params or
+// header) over the defaults. An empty URL turns revocation off for the provider.
+// RETAIN_LINKED_TOKENS_ON_LOGOUT=true keeps linked accounts' tokens at logout.
+func initRevocations() {
+	switch value := getNonEmptyEnv("RETAIN_LINKED_TOKENS_ON_LOGOUT", "false"); value {
+	case "true", "false":
+		RetainLinkedTokens = value == "true"
+	default:
+		log.Fatalf("Invalid RETAIN_LINKED_TOKENS_ON_LOGOUT %q: must be true or false", value)
+	}
+	Revocations = map[string]*RevocationConfig{}
+	for name, oauthConfig := range Providers {
+		prefix := strings.ToUpper(name)
+		revocation := defaultRevocations[name]
+		revocation.URL = getEnv(prefix+"_REVOCATION_URL", revocation.URL)
+		if style, exists := os.LookupEnv(prefix + "_REVOCATION_AUTH_STYLE"); exists {
+			switch style {
+			case "params":
+				revocation.AuthStyle = oauth2.AuthStyleInParams
+			case "header":
+				revocation.AuthStyle = oauth2.AuthStyleInHeader
+			default:
+				log.Fatalf("Invalid %s_REVOCATION_AUTH_STYLE %q: must be params or header", prefix, style)
+			}
+		}
+		if revocation.URL == "" {
+			continue
+		}
+		if revocation.AuthStyle == oauth2.AuthStyleAutoDetect {
+			// Fall back to how the token endpoint takes the credentials.
+			revocation.AuthStyle = oauthConfig.Endpoint.AuthStyle
+		}
+		Revocations[name] = &revocation
+	}
+}
+
+// GetProviderRevocation returns the provider's revocation endpoint, if it has one.
+var GetProviderRevocation = func(provider string) (*RevocationConfig, bool) {
+	revocation, exists := Revocations[provider]
+	return revocation, exists
+}
+
+// GetRetainLinkedTokens reports whether logout keeps the tokens of accounts linked to a user.
+var GetRetainLinkedTokens = func() bool {
+	return RetainLinkedTokens
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Session limit policies, applied when an account logs in to more sessions than its limit allows.
+const (
+	// SessionLimitEvictOldest logs the account out of its oldest session to make room for the new one.
+	SessionLimitEvictOldest = "evict_oldest"
+	// SessionLimitReject refuses the new login.
+	SessionLimitReject = "reject"
+)
+
+// SessionLimit caps how many sessions one provider account may be logged in to at once.
+type SessionLimit struct {
+	// Max is the number of sessions allowed; zero means unlimited.
+	Max    int
+	Policy string
+}
+
+// SessionLimits holds each login provider's session limit, OAuth and credential providers alike.
+var SessionLimits map[string]SessionLimit
+
+// initSessionLimits reads MAX_SESSIONS_PER_ACCOUNT and SESSION_LIMIT_POLICY, which
+// <PROVIDER>_MAX_SESSIONS and <PROVIDER>_SESSION_LIMIT_POLICY override per provider. It runs after
+// the credential providers are configured.
+func initSessionLimits() {
+	defaults := SessionLimit{
+		Max:    parseMaxSessions("MAX_SESSIONS_PER_ACCOUNT", 0),
+		Policy: parseSessionLimitPolicy("SESSION_LIMIT_POLICY", SessionLimitEvictOldest),
+	}
+	SessionLimits = map[string]SessionLimit{}
+	for name := range Providers {
+		SessionLimits[name] = parseSessionLimit(name, defaults)
+	}
+	for name := range CredentialProviders {
+		SessionLimits[name] = parseSessionLimit(name, defaults)
+	}
+}
+
+func parseSessionLimit(provider string, defaults SessionLimit) SessionLimit {
+	prefix := strings.ToUpper(provider)
+	return SessionLimit{
+		Max:    parseMaxSessions(prefix+"_MAX_SESSIONS", defaults.Max),
+		Policy: parseSessionLimitPolicy(prefix+"_SESSION_LIMIT_POLICY", defaults.Policy),
+	}
+}
+
+func parseMaxSessions(key string, fallback int) int {
+	value := getNonEmptyEnv(key, "")
+	if value == "" {
+		return fallback
+	}
+	max, err := strconv.Atoi(value)
+	if err != nil || max < 0 {
+		log.Fatalf("Invalid %s %q: must be a non-negative number", key, value)
+	}
+	return max
+}
+
+func parseSessionLimitPolicy(key, fallback string) string {
+	policy := getNonEmptyEnv(key, fallback)
+	if policy != SessionLimitEvictOldest && policy != SessionLimitReject {
+		log.Fatalf("Invalid %s %q: must be %s or %s", key, policy, SessionLimitEvictOldest, SessionLimitReject)
+	}
+	return policy
+}
+
+// GetSessionLimit returns the provider's session limit.
+var GetSessionLimit = func(provider string) SessionLimit {
+	return SessionLimits[provider]
+}
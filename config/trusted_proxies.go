@@ -0,0 +1,25 @@
+package config
+
+import (
+	"auth-service/utils"
+	"log"
+	"net"
+)
+
+// TrustedProxies are the proxies in front of the service whose X-Forwarded-For header is believed.
+var TrustedProxies []*net.IPNet
+
+// initTrustedProxies reads TRUSTED_PROXIES, a comma-separated list of proxy addresses and CIDR ranges.
+// Without it, clients are identified by the connection's remote address.
+func initTrustedProxies() {
+	proxies, err := utils.ParseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	TrustedProxies = proxies
+}
+
+// GetTrustedProxies returns the trusted proxies.
+var GetTrustedProxies = func() []*net.IPNet {
+	return TrustedProxies
+}
@@ -6,18 +6,245 @@ package generated
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/go-chi/chi/v5"
 	"github.com/oapi-codegen/runtime"
 )
 
+const (
+	InternalApiKeyScopes = "InternalApiKey.Scopes"
+)
+
+// AccountSignOut defines model for AccountSignOut.
+type AccountSignOut struct {
+	// Revocation The least complete revocation of the account's tokens, one of revoked, pending, failed or local_only, as reported by logout.
+	Revocation string `json:"revocation"`
+
+	// Sessions How many sessions the account was logged out of.
+	Sessions int `json:"sessions"`
+}
+
+// AccountToken The token of one linked account, or the error that prevented returning it.
+type AccountToken struct {
+	// Error RFC 7807 problem details returned with Content-Type application/problem+json by every error response.
+	Error          *Problem       `json:"error,omitempty"`
+	Provider       string         `json:"provider"`
+	ProviderUserId string         `json:"provider_user_id"`
+	Token          *ProviderToken `json:"token,omitempty"`
+}
+
+// AccountTokens defines model for AccountTokens.
+type AccountTokens struct {
+	Accounts []AccountToken `json:"accounts"`
+}
+
+// CredentialLoginRequest defines model for CredentialLoginRequest.
+type CredentialLoginRequest struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
+
+// DeviceTokenRequest defines model for DeviceTokenRequest.
+type DeviceTokenRequest struct {
+	DeviceCode string `json:"device_code"`
+}
+
+// LinkIdentityRequest defines model for LinkIdentityRequest.
+type LinkIdentityRequest struct {
+	Provider string `json:"provider"`
+
+	// UserId The provider user ID of an account logged in to the session.
+	UserId string `json:"user_id"`
+}
+
+// LinkedAccount An account linked to the session.
+type LinkedAccount struct {
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+
+	// ExpiresAt When the access token expires. Omitted for tokens that do not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Health One of ok, expiring (the access token expires within five minutes, or has expired and will be refreshed on next use), needs_reauth (the token cannot be refreshed and the user must log in again) or needs_upgrade (the provider granted fewer scopes than are now requested, so the user must log in again to grant them).
+	Health string `json:"health"`
+
+	// LastRefreshedAt When the token was last refreshed. Omitted if it never was.
+	LastRefreshedAt *time.Time `json:"last_refreshed_at,omitempty"`
+
+	// LinkedAt When the user logged in with the account. Omitted for accounts linked before this was recorded.
+	LinkedAt *time.Time `json:"linked_at,omitempty"`
+	LoggedIn bool       `json:"logged_in"`
+
+	// Primary Whether this is the session's default account for the provider.
+	Primary bool `json:"primary"`
+
+	// Profile How the account's profile met the provider's required profile fields at login. Omitted for accounts linked before profiles were validated.
+	Profile  *ProfileValidation `json:"profile,omitempty"`
+	Provider string             `json:"provider"`
+
+	// Scopes The scopes the provider granted.
+	Scopes *[]string `json:"scopes,omitempty"`
+	UserId string    `json:"user_id"`
+}
+
+// MassRevocation An emergency revocation and its progress.
+type MassRevocation struct {
+	// ClientId The registered client whose tokens are revoked. Omitted when all of the provider's tokens are.
+	ClientId    *string    `json:"client_id,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// Deleted How many stored tokens have been deleted.
+	Deleted int `json:"deleted"`
+
+	// Error Why the revocation failed.
+	Error    *string `json:"error,omitempty"`
+	Id       string  `json:"id"`
+	Provider string  `json:"provider"`
+
+	// Revocations How many tokens were revoked at the provider, by outcome as reported by logout (revoked, pending, failed or local_only).
+	Revocations map[string]int `json:"revocations"`
+
+	// Scanned How many stored keys have been looked at.
+	Scanned   int       `json:"scanned"`
+	StartedAt time.Time `json:"started_at"`
+
+	// Status One of running, failed (resume it to continue) or completed.
+	Status string `json:"status"`
+
+	// UpdatedAt When the last batch completed.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MassRevocationRequest defines model for MassRevocationRequest.
+type MassRevocationRequest struct {
+	// ClientId Only revoke tokens issued through this registered client.
+	ClientId *string `json:"client_id,omitempty"`
+	Provider string  `json:"provider"`
+}
+
+// PrimaryAccountRequest defines model for PrimaryAccountRequest.
+type PrimaryAccountRequest struct {
+	// UserId The provider user ID of the account to make primary.
+	UserId string `json:"user_id"`
+}
+
+// Problem RFC 7807 problem details returned with Content-Type application/problem+json by every error response.
+type Problem struct {
+	// Code Stable machine-readable error code. One of invalid_request, unsupported_provider, unknown_client, provider_not_permitted, invalid_redirect_uri, invalid_state, login_expired, session_missing, token_not_found, reauth_required, invalid_credentials, provider_error, unauthorized, internal_api_disabled, device_flow_disabled, invalid_user_code, rate_limited, token_not_refreshable, user_not_found, identity_linked, identity_not_found, last_identity, cross_origin_request, revocation_not_found, revocation_in_progress, revocation_completed, login_suspended, session_limit_reached, login_denied, link_session_mismatch or internal_error.
+	Code string `json:"code"`
+
+	// Detail Human-readable explanation of this occurrence of the problem.
+	Detail *string `json:"detail,omitempty"`
+
+	// Instance The request path that produced the problem.
+	Instance *string `json:"instance,omitempty"`
+
+	// Status The HTTP status code.
+	Status int `json:"status"`
+
+	// Title Short summary of the HTTP status.
+	Title string `json:"title"`
+
+	// Type URI reference identifying the problem type.
+	Type string `json:"type"`
+}
+
+// ProfileValidation How the account's profile met the provider's required profile fields at login. Omitted for accounts linked before profiles were validated.
+type ProfileValidation struct {
+	// Fallbacks The fields filled in from other fields, such as display_name from the user ID.
+	Fallbacks *[]string `json:"fallbacks,omitempty"`
+
+	// Missing The required fields the provider did not return. The email is only required when its scope was granted.
+	Missing *[]string `json:"missing,omitempty"`
+
+	// Valid Whether the profile has every required field. Accounts with invalid profiles are still logged in.
+	Valid bool `json:"valid"`
+}
+
+// ProviderToken defines model for ProviderToken.
+type ProviderToken struct {
+	AccessToken string `json:"access_token"`
+
+	// ExpiresAt When the token expires. Omitted for tokens that do not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// ExpiresIn Seconds until the token expires. Omitted for tokens that do not expire.
+	ExpiresIn      *int   `json:"expires_in,omitempty"`
+	Provider       string `json:"provider"`
+	ProviderUserId string `json:"provider_user_id"`
+
+	// RefreshStatus One of valid (not refreshed), refreshed (refreshed by this request) or not_refreshable (the token cannot be refreshed).
+	RefreshStatus string  `json:"refresh_status"`
+	RefreshToken  *string `json:"refresh_token,omitempty"`
+
+	// Scope The scopes the provider granted.
+	Scope     *[]string `json:"scope,omitempty"`
+	TokenType string    `json:"token_type"`
+}
+
+// User A durable internal user, which outlives sessions.
+type User struct {
+	CreatedAt time.Time `json:"created_at"`
+	Id        string    `json:"id"`
+
+	// Identities The provider identities linked to the user, in the order they were linked.
+	Identities []UserIdentity `json:"identities"`
+}
+
+// UserIdentity A provider account linked to a user. Logging in with it resolves to the user.
+type UserIdentity struct {
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	LinkedAt    time.Time `json:"linked_at"`
+	Provider    string    `json:"provider"`
+	UserId      string    `json:"user_id"`
+}
+
+// GetAuthDeviceParams defines parameters for GetAuthDevice.
+type GetAuthDeviceParams struct {
+	// UserCode The user code shown on the device.
+	UserCode string `form:"user_code" json:"user_code"`
+}
+
+// PostAuthDeviceParams defines parameters for PostAuthDevice.
+type PostAuthDeviceParams struct {
+	// UserCode The user code shown on the device.
+	UserCode string `form:"user_code" json:"user_code"`
+}
+
+// GetAuthLogoutParams defines parameters for GetAuthLogout.
+type GetAuthLogoutParams struct {
+	// RedirectUri The URI to redirect the browser to after logout. It is validated like the login's redirect URI.
+	RedirectUri string `form:"redirect_uri" json:"redirect_uri"`
+
+	// ClientId The registered client the session was started by. Its redirect URIs and cookie settings apply.
+	ClientId *string `form:"client_id,omitempty" json:"client_id,omitempty"`
+}
+
+// PostAuthLogoutParams defines parameters for PostAuthLogout.
+type PostAuthLogoutParams struct {
+	// RedirectUri The URI to redirect the browser to after logout. It is validated like the login's redirect URI.
+	RedirectUri string `form:"redirect_uri" json:"redirect_uri"`
+
+	// ClientId The registered client the session was started by. Its redirect URIs and cookie settings apply.
+	ClientId *string `form:"client_id,omitempty" json:"client_id,omitempty"`
+}
+
+// GetAuthTokensParams defines parameters for GetAuthTokens.
+type GetAuthTokensParams struct {
+	// Provider Only return tokens for accounts with this provider.
+	Provider *string `form:"provider,omitempty" json:"provider,omitempty"`
+}
+
 // GetAuthProviderCallbackParams defines parameters for GetAuthProviderCallback.
 type GetAuthProviderCallbackParams struct {
 	// State The state parameter containing redirect URI and anti-CSRF token.
@@ -28,6 +255,12 @@ type GetAuthProviderCallbackParams struct {
 type GetAuthProviderLoginParams struct {
 	// RedirectUri The URI to redirect the user to after authentication.
 	RedirectUri string `form:"redirect_uri" json:"redirect_uri"`
+
+	// ClientId The registered client starting the login. Its redirect URIs, providers, scopes, cookie and response mode apply to the whole login.
+	ClientId *string `form:"client_id,omitempty" json:"client_id,omitempty"`
+
+	// Link Links the account to the user of the caller's existing session and logs it in to that session, instead of starting a new session.
+	Link *bool `form:"link,omitempty" json:"link,omitempty"`
 }
 
 // PostAuthProviderLogoutParams defines parameters for PostAuthProviderLogout.
@@ -36,47 +269,212 @@ type PostAuthProviderLogoutParams struct {
 	UserId *string `form:"user_id,omitempty" json:"user_id,omitempty"`
 }
 
+// PostAuthProviderRefreshParams defines parameters for PostAuthProviderRefresh.
+type PostAuthProviderRefreshParams struct {
+	// UserId The provider user ID whose token is refreshed. Defaults to the session's primary account for the provider.
+	UserId *string `form:"user_id,omitempty" json:"user_id,omitempty"`
+}
+
 // GetAuthProviderTokenParams defines parameters for GetAuthProviderToken.
 type GetAuthProviderTokenParams struct {
-	// UserId The unique identifier of the user for whom the token is being retrieved.
-	UserId string `form:"user_id" json:"user_id"`
+	// UserId The unique identifier of the user for whom the token is being retrieved. Defaults to the session's primary account for the provider.
+	UserId *string `form:"user_id,omitempty" json:"user_id,omitempty"`
 }
 
+// GetV2AuthProviderTokenParams defines parameters for GetV2AuthProviderToken.
+type GetV2AuthProviderTokenParams struct {
+	// UserId The provider user ID whose token is being retrieved. Defaults to the session's primary account for the provider.
+	UserId *string `form:"user_id,omitempty" json:"user_id,omitempty"`
+}
+
+// PostAdminRevocationsJSONRequestBody defines body for PostAdminRevocations for application/json ContentType.
+type PostAdminRevocationsJSONRequestBody = MassRevocationRequest
+
+// PostAuthDeviceTokenJSONRequestBody defines body for PostAuthDeviceToken for application/json ContentType.
+type PostAuthDeviceTokenJSONRequestBody = DeviceTokenRequest
+
+// PostAuthProviderCredentialsJSONRequestBody defines body for PostAuthProviderCredentials for application/json ContentType.
+type PostAuthProviderCredentialsJSONRequestBody = CredentialLoginRequest
+
+// PutAuthProviderPrimaryJSONRequestBody defines body for PutAuthProviderPrimary for application/json ContentType.
+type PutAuthProviderPrimaryJSONRequestBody = PrimaryAccountRequest
+
+// PostUsersMeIdentitiesJSONRequestBody defines body for PostUsersMeIdentities for application/json ContentType.
+type PostUsersMeIdentitiesJSONRequestBody = LinkIdentityRequest
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// Start an emergency revocation of every stored token of a provider or client.
+	// (POST /admin/revocations)
+	PostAdminRevocations(w http.ResponseWriter, r *http.Request)
+	// Report the progress of an emergency revocation.
+	// (GET /admin/revocations/{revocation_id})
+	GetAdminRevocationsRevocationId(w http.ResponseWriter, r *http.Request, revocationId string)
+	// Resume a failed emergency revocation.
+	// (POST /admin/revocations/{revocation_id}/resume)
+	PostAdminRevocationsRevocationIdResume(w http.ResponseWriter, r *http.Request, revocationId string)
+	// Verification URL for the device flow.
+	// (GET /auth/device)
+	GetAuthDevice(w http.ResponseWriter, r *http.Request, params GetAuthDeviceParams)
+	// Confirm the device and start the provider login.
+	// (POST /auth/device)
+	PostAuthDevice(w http.ResponseWriter, r *http.Request, params PostAuthDeviceParams)
+	// Poll for the result of a device authorization grant.
+	// (POST /auth/device/token)
+	PostAuthDeviceToken(w http.ResponseWriter, r *http.Request)
+	// End the browser session and redirect.
+	// (GET /auth/logout)
+	GetAuthLogout(w http.ResponseWriter, r *http.Request, params GetAuthLogoutParams)
+	// End the browser session and redirect.
+	// (POST /auth/logout)
+	PostAuthLogout(w http.ResponseWriter, r *http.Request, params PostAuthLogoutParams)
 	// Retrieve a list of connected providers that the user is logged in with
 	// (GET /auth/status)
 	GetAuthStatus(w http.ResponseWriter, r *http.Request)
+	// Retrieve valid tokens for every account linked to the session.
+	// (GET /auth/tokens)
+	GetAuthTokens(w http.ResponseWriter, r *http.Request, params GetAuthTokensParams)
+	// Sign a provider account out of every session.
+	// (DELETE /auth/{provider}/accounts/{user_id}/sessions)
+	DeleteAuthProviderAccountsUserIdSessions(w http.ResponseWriter, r *http.Request, provider string, userId string)
+	// Retrieve a client-credentials app token for a provider.
+	// (GET /auth/{provider}/app-token)
+	GetAuthProviderAppToken(w http.ResponseWriter, r *http.Request, provider string)
 	// Handle OAuth callback and store tokens.
 	// (GET /auth/{provider}/callback)
 	GetAuthProviderCallback(w http.ResponseWriter, r *http.Request, provider string, params GetAuthProviderCallbackParams)
+	// Log in to a credential provider with a username and password.
+	// (POST /auth/{provider}/credentials)
+	PostAuthProviderCredentials(w http.ResponseWriter, r *http.Request, provider string)
+	// Start a device authorization grant for a client without a usable browser.
+	// (POST /auth/{provider}/device/code)
+	PostAuthProviderDeviceCode(w http.ResponseWriter, r *http.Request, provider string)
 	// Redirect to the provider's OAuth login page.
 	// (GET /auth/{provider}/login)
 	GetAuthProviderLogin(w http.ResponseWriter, r *http.Request, provider string, params GetAuthProviderLoginParams)
 	// Log out a user or all users from a provider.
 	// (POST /auth/{provider}/logout)
 	PostAuthProviderLogout(w http.ResponseWriter, r *http.Request, provider string, params PostAuthProviderLogoutParams)
+	// Set the session's primary account for a provider.
+	// (PUT /auth/{provider}/primary)
+	PutAuthProviderPrimary(w http.ResponseWriter, r *http.Request, provider string)
+	// Force a refresh of the OAuth token for a specific provider and user.
+	// (POST /auth/{provider}/refresh)
+	PostAuthProviderRefresh(w http.ResponseWriter, r *http.Request, provider string, params PostAuthProviderRefreshParams)
 	// Retrieve an OAuth token for a specific provider and user.
 	// (GET /auth/{provider}/token)
 	GetAuthProviderToken(w http.ResponseWriter, r *http.Request, provider string, params GetAuthProviderTokenParams)
+	// Retrieve the internal user the session belongs to.
+	// (GET /users/me)
+	GetUsersMe(w http.ResponseWriter, r *http.Request)
+	// Link an account in the session to the session's user.
+	// (POST /users/me/identities)
+	PostUsersMeIdentities(w http.ResponseWriter, r *http.Request)
+	// Unlink an identity from the session's user.
+	// (DELETE /users/me/identities/{provider}/{user_id})
+	DeleteUsersMeIdentitiesProviderUserId(w http.ResponseWriter, r *http.Request, provider string, userId string)
+	// Retrieve an access token from a user's offline grant for a provider.
+	// (GET /users/{user_id}/tokens/{provider})
+	GetUsersUserIdTokensProvider(w http.ResponseWriter, r *http.Request, userId string, provider string)
+	// Retrieve an OAuth token for a specific provider and user, with expiry and scope details.
+	// (GET /v2/auth/{provider}/token)
+	GetV2AuthProviderToken(w http.ResponseWriter, r *http.Request, provider string, params GetV2AuthProviderTokenParams)
 }
 
 // Unimplemented server implementation that returns http.StatusNotImplemented for each endpoint.
 
 type Unimplemented struct{}
 
+// Start an emergency revocation of every stored token of a provider or client.
+// (POST /admin/revocations)
+func (_ Unimplemented) PostAdminRevocations(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Report the progress of an emergency revocation.
+// (GET /admin/revocations/{revocation_id})
+func (_ Unimplemented) GetAdminRevocationsRevocationId(w http.ResponseWriter, r *http.Request, revocationId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Resume a failed emergency revocation.
+// (POST /admin/revocations/{revocation_id}/resume)
+func (_ Unimplemented) PostAdminRevocationsRevocationIdResume(w http.ResponseWriter, r *http.Request, revocationId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Verification URL for the device flow.
+// (GET /auth/device)
+func (_ Unimplemented) GetAuthDevice(w http.ResponseWriter, r *http.Request, params GetAuthDeviceParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Confirm the device and start the provider login.
+// (POST /auth/device)
+func (_ Unimplemented) PostAuthDevice(w http.ResponseWriter, r *http.Request, params PostAuthDeviceParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Poll for the result of a device authorization grant.
+// (POST /auth/device/token)
+func (_ Unimplemented) PostAuthDeviceToken(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// End the browser session and redirect.
+// (GET /auth/logout)
+func (_ Unimplemented) GetAuthLogout(w http.ResponseWriter, r *http.Request, params GetAuthLogoutParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// End the browser session and redirect.
+// (POST /auth/logout)
+func (_ Unimplemented) PostAuthLogout(w http.ResponseWriter, r *http.Request, params PostAuthLogoutParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Retrieve a list of connected providers that the user is logged in with
 // (GET /auth/status)
 func (_ Unimplemented) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Retrieve valid tokens for every account linked to the session.
+// (GET /auth/tokens)
+func (_ Unimplemented) GetAuthTokens(w http.ResponseWriter, r *http.Request, params GetAuthTokensParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Sign a provider account out of every session.
+// (DELETE /auth/{provider}/accounts/{user_id}/sessions)
+func (_ Unimplemented) DeleteAuthProviderAccountsUserIdSessions(w http.ResponseWriter, r *http.Request, provider string, userId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Retrieve a client-credentials app token for a provider.
+// (GET /auth/{provider}/app-token)
+func (_ Unimplemented) GetAuthProviderAppToken(w http.ResponseWriter, r *http.Request, provider string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Handle OAuth callback and store tokens.
 // (GET /auth/{provider}/callback)
 func (_ Unimplemented) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request, provider string, params GetAuthProviderCallbackParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Log in to a credential provider with a username and password.
+// (POST /auth/{provider}/credentials)
+func (_ Unimplemented) PostAuthProviderCredentials(w http.ResponseWriter, r *http.Request, provider string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Start a device authorization grant for a client without a usable browser.
+// (POST /auth/{provider}/device/code)
+func (_ Unimplemented) PostAuthProviderDeviceCode(w http.ResponseWriter, r *http.Request, provider string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Redirect to the provider's OAuth login page.
 // (GET /auth/{provider}/login)
 func (_ Unimplemented) GetAuthProviderLogin(w http.ResponseWriter, r *http.Request, provider string, params GetAuthProviderLoginParams) {
@@ -89,12 +487,54 @@ func (_ Unimplemented) PostAuthProviderLogout(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Set the session's primary account for a provider.
+// (PUT /auth/{provider}/primary)
+func (_ Unimplemented) PutAuthProviderPrimary(w http.ResponseWriter, r *http.Request, provider string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Force a refresh of the OAuth token for a specific provider and user.
+// (POST /auth/{provider}/refresh)
+func (_ Unimplemented) PostAuthProviderRefresh(w http.ResponseWriter, r *http.Request, provider string, params PostAuthProviderRefreshParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // Retrieve an OAuth token for a specific provider and user.
 // (GET /auth/{provider}/token)
 func (_ Unimplemented) GetAuthProviderToken(w http.ResponseWriter, r *http.Request, provider string, params GetAuthProviderTokenParams) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
+// Retrieve the internal user the session belongs to.
+// (GET /users/me)
+func (_ Unimplemented) GetUsersMe(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Link an account in the session to the session's user.
+// (POST /users/me/identities)
+func (_ Unimplemented) PostUsersMeIdentities(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Unlink an identity from the session's user.
+// (DELETE /users/me/identities/{provider}/{user_id})
+func (_ Unimplemented) DeleteUsersMeIdentitiesProviderUserId(w http.ResponseWriter, r *http.Request, provider string, userId string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Retrieve an access token from a user's offline grant for a provider.
+// (GET /users/{user_id}/tokens/{provider})
+func (_ Unimplemented) GetUsersUserIdTokensProvider(w http.ResponseWriter, r *http.Request, userId string, provider string) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+// Retrieve an OAuth token for a specific provider and user, with expiry and scope details.
+// (GET /v2/auth/{provider}/token)
+func (_ Unimplemented) GetV2AuthProviderToken(w http.ResponseWriter, r *http.Request, provider string, params GetV2AuthProviderTokenParams) {
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
 // ServerInterfaceWrapper converts contexts to parameters.
 type ServerInterfaceWrapper struct {
 	Handler            ServerInterface
@@ -104,12 +544,393 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// PostAdminRevocations operation middleware
+func (siw *ServerInterfaceWrapper) PostAdminRevocations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, InternalApiKeyScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAdminRevocations(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetAdminRevocationsRevocationId operation middleware
+func (siw *ServerInterfaceWrapper) GetAdminRevocationsRevocationId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "revocation_id" -------------
+	var revocationId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "revocation_id", runtime.ParamLocationPath, chi.URLParam(r, "revocation_id"), &revocationId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "revocation_id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, InternalApiKeyScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAdminRevocationsRevocationId(w, r, revocationId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostAdminRevocationsRevocationIdResume operation middleware
+func (siw *ServerInterfaceWrapper) PostAdminRevocationsRevocationIdResume(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "revocation_id" -------------
+	var revocationId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "revocation_id", runtime.ParamLocationPath, chi.URLParam(r, "revocation_id"), &revocationId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "revocation_id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, InternalApiKeyScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAdminRevocationsRevocationIdResume(w, r, revocationId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetAuthDevice operation middleware
+func (siw *ServerInterfaceWrapper) GetAuthDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuthDeviceParams
+
+	// ------------- Required query parameter "user_code" -------------
+
+	if paramValue := r.URL.Query().Get("user_code"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "user_code"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "user_code", r.URL.Query(), &params.UserCode)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_code", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthDevice(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostAuthDevice operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthDevice(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostAuthDeviceParams
+
+	// ------------- Required query parameter "user_code" -------------
+
+	if paramValue := r.URL.Query().Get("user_code"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "user_code"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "user_code", r.URL.Query(), &params.UserCode)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_code", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthDevice(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostAuthDeviceToken operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthDeviceToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthDeviceToken(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetAuthLogout operation middleware
+func (siw *ServerInterfaceWrapper) GetAuthLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuthLogoutParams
+
+	// ------------- Required query parameter "redirect_uri" -------------
+
+	if paramValue := r.URL.Query().Get("redirect_uri"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "redirect_uri"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "redirect_uri", r.URL.Query(), &params.RedirectUri)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "redirect_uri", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "client_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client_id", r.URL.Query(), &params.ClientId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client_id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthLogout(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostAuthLogout operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostAuthLogoutParams
+
+	// ------------- Required query parameter "redirect_uri" -------------
+
+	if paramValue := r.URL.Query().Get("redirect_uri"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "redirect_uri"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "redirect_uri", r.URL.Query(), &params.RedirectUri)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "redirect_uri", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "client_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client_id", r.URL.Query(), &params.ClientId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client_id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthLogout(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // GetAuthStatus operation middleware
 func (siw *ServerInterfaceWrapper) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetAuthStatus(w, r)
+		siw.Handler.GetAuthStatus(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetAuthTokens operation middleware
+func (siw *ServerInterfaceWrapper) GetAuthTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuthTokensParams
+
+	// ------------- Optional query parameter "provider" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "provider", r.URL.Query(), &params.Provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthTokens(w, r, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// DeleteAuthProviderAccountsUserIdSessions operation middleware
+func (siw *ServerInterfaceWrapper) DeleteAuthProviderAccountsUserIdSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "user_id" -------------
+	var userId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "user_id", runtime.ParamLocationPath, chi.URLParam(r, "user_id"), &userId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_id", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, InternalApiKeyScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteAuthProviderAccountsUserIdSessions(w, r, provider, userId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetAuthProviderAppToken operation middleware
+func (siw *ServerInterfaceWrapper) GetAuthProviderAppToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, InternalApiKeyScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthProviderAppToken(w, r, provider)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetAuthProviderCallback operation middleware
+func (siw *ServerInterfaceWrapper) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuthProviderCallbackParams
+
+	// ------------- Required query parameter "state" -------------
+
+	if paramValue := r.URL.Query().Get("state"); paramValue != "" {
+
+	} else {
+		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "state"})
+		return
+	}
+
+	err = runtime.BindQueryParameter("form", true, true, "state", r.URL.Query(), &params.State)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "state", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthProviderCallback(w, r, provider, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -119,8 +940,8 @@ func (siw *ServerInterfaceWrapper) GetAuthStatus(w http.ResponseWriter, r *http.
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
-// GetAuthProviderCallback operation middleware
-func (siw *ServerInterfaceWrapper) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request) {
+// PostAuthProviderCredentials operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthProviderCredentials(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
@@ -134,26 +955,34 @@ func (siw *ServerInterfaceWrapper) GetAuthProviderCallback(w http.ResponseWriter
 		return
 	}
 
-	// Parameter object where we will unmarshal all parameters from the context
-	var params GetAuthProviderCallbackParams
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthProviderCredentials(w, r, provider)
+	}))
 
-	// ------------- Required query parameter "state" -------------
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
 
-	if paramValue := r.URL.Query().Get("state"); paramValue != "" {
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
 
-	} else {
-		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "state"})
-		return
-	}
+// PostAuthProviderDeviceCode operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthProviderDeviceCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
-	err = runtime.BindQueryParameter("form", true, true, "state", r.URL.Query(), &params.State)
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "state", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
 		return
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetAuthProviderCallback(w, r, provider, params)
+		siw.Handler.PostAuthProviderDeviceCode(w, r, provider)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -196,6 +1025,22 @@ func (siw *ServerInterfaceWrapper) GetAuthProviderLogin(w http.ResponseWriter, r
 		return
 	}
 
+	// ------------- Optional query parameter "client_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "client_id", r.URL.Query(), &params.ClientId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "client_id", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "link" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "link", r.URL.Query(), &params.Link)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "link", Err: err})
+		return
+	}
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetAuthProviderLogin(w, r, provider, params)
 	}))
@@ -244,6 +1089,69 @@ func (siw *ServerInterfaceWrapper) PostAuthProviderLogout(w http.ResponseWriter,
 	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// PutAuthProviderPrimary operation middleware
+func (siw *ServerInterfaceWrapper) PutAuthProviderPrimary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PutAuthProviderPrimary(w, r, provider)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostAuthProviderRefresh operation middleware
+func (siw *ServerInterfaceWrapper) PostAuthProviderRefresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostAuthProviderRefreshParams
+
+	// ------------- Optional query parameter "user_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "user_id", r.URL.Query(), &params.UserId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostAuthProviderRefresh(w, r, provider, params)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
 // GetAuthProviderToken operation middleware
 func (siw *ServerInterfaceWrapper) GetAuthProviderToken(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -262,23 +1170,155 @@ func (siw *ServerInterfaceWrapper) GetAuthProviderToken(w http.ResponseWriter, r
 	// Parameter object where we will unmarshal all parameters from the context
 	var params GetAuthProviderTokenParams
 
-	// ------------- Required query parameter "user_id" -------------
+	// ------------- Optional query parameter "user_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "user_id", r.URL.Query(), &params.UserId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_id", Err: err})
+		return
+	}
 
-	if paramValue := r.URL.Query().Get("user_id"); paramValue != "" {
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAuthProviderToken(w, r, provider, params)
+	}))
 
-	} else {
-		siw.ErrorHandlerFunc(w, r, &RequiredParamError{ParamName: "user_id"})
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetUsersMe operation middleware
+func (siw *ServerInterfaceWrapper) GetUsersMe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetUsersMe(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// PostUsersMeIdentities operation middleware
+func (siw *ServerInterfaceWrapper) PostUsersMeIdentities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostUsersMeIdentities(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// DeleteUsersMeIdentitiesProviderUserId operation middleware
+func (siw *ServerInterfaceWrapper) DeleteUsersMeIdentitiesProviderUserId(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
 		return
 	}
 
-	err = runtime.BindQueryParameter("form", true, true, "user_id", r.URL.Query(), &params.UserId)
+	// ------------- Path parameter "user_id" -------------
+	var userId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "user_id", runtime.ParamLocationPath, chi.URLParam(r, "user_id"), &userId)
 	if err != nil {
 		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_id", Err: err})
 		return
 	}
 
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetAuthProviderToken(w, r, provider, params)
+		siw.Handler.DeleteUsersMeIdentitiesProviderUserId(w, r, provider, userId)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetUsersUserIdTokensProvider operation middleware
+func (siw *ServerInterfaceWrapper) GetUsersUserIdTokensProvider(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "user_id" -------------
+	var userId string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "user_id", runtime.ParamLocationPath, chi.URLParam(r, "user_id"), &userId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_id", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	ctx = context.WithValue(ctx, InternalApiKeyScopes, []string{})
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetUsersUserIdTokensProvider(w, r, userId, provider)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// GetV2AuthProviderToken operation middleware
+func (siw *ServerInterfaceWrapper) GetV2AuthProviderToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "provider" -------------
+	var provider string
+
+	err = runtime.BindStyledParameterWithLocation("simple", false, "provider", runtime.ParamLocationPath, chi.URLParam(r, "provider"), &provider)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "provider", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetV2AuthProviderTokenParams
+
+	// ------------- Optional query parameter "user_id" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "user_id", r.URL.Query(), &params.UserId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "user_id", Err: err})
+		return
+	}
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetV2AuthProviderToken(w, r, provider, params)
 	}))
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -401,48 +1441,225 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/revocations", wrapper.PostAdminRevocations)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/admin/revocations/{revocation_id}", wrapper.GetAdminRevocationsRevocationId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/admin/revocations/{revocation_id}/resume", wrapper.PostAdminRevocationsRevocationIdResume)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/auth/device", wrapper.GetAuthDevice)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/device", wrapper.PostAuthDevice)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/device/token", wrapper.PostAuthDeviceToken)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/auth/logout", wrapper.GetAuthLogout)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/logout", wrapper.PostAuthLogout)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/auth/status", wrapper.GetAuthStatus)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/auth/tokens", wrapper.GetAuthTokens)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/auth/{provider}/accounts/{user_id}/sessions", wrapper.DeleteAuthProviderAccountsUserIdSessions)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/auth/{provider}/app-token", wrapper.GetAuthProviderAppToken)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/auth/{provider}/callback", wrapper.GetAuthProviderCallback)
 	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/{provider}/credentials", wrapper.PostAuthProviderCredentials)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/{provider}/device/code", wrapper.PostAuthProviderDeviceCode)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/auth/{provider}/login", wrapper.GetAuthProviderLogin)
 	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/auth/{provider}/logout", wrapper.PostAuthProviderLogout)
 	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/auth/{provider}/primary", wrapper.PutAuthProviderPrimary)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/auth/{provider}/refresh", wrapper.PostAuthProviderRefresh)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/auth/{provider}/token", wrapper.GetAuthProviderToken)
 	})
 
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/users/me", wrapper.GetUsersMe)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/users/me/identities", wrapper.PostUsersMeIdentities)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/users/me/identities/{provider}/{user_id}", wrapper.DeleteUsersMeIdentitiesProviderUserId)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/users/{user_id}/tokens/{provider}", wrapper.GetUsersUserIdTokensProvider)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/v2/auth/{provider}/token", wrapper.GetV2AuthProviderToken)
+	})
 	return r
 }
 
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/8xXX2/bNhD/KgRf9qLYTtMVhZ+WtdjmIcOCun0aioCRTtIlFKmQJ7de4O8+HPXfUtYk",
-	"xYI9Wabujr/797vTvUSTWrm+lwn42GFJaI1cy/PLjUitE4UyKkOTiT/PK8oF2VswXiiTCFVRDoYwVqyy",
-	"kJEkJA2sy5JbcDuMQZxfbmQkd+B8bfh0sVqs5CGStgSjSpRreRaOIlkqyj1DWbLtpSdFVfifAU0RfgCq",
-	"HGMRGj0Jm4rS2R0m4LxQ2ppMfEHKhbYZGlHbCrgrD04kQAq1Z9S2BBdc2CRyLX8FYvjb+u5IOvClNR4C",
-	"jlerFf/E1hCYAEmVpW4isLzxjOtewldVlLrW+KPShKUGcdlhu7BZBonYBNmd0hXI9V/3MkFfarW/Mqrg",
-	"GP5ucyPeW5CRhEKhlmt5Y3PzU2N8EdtCRlIHW1do5JpcBZFsQyDX0peWMN3LSLLHV8je8dPpqzN5iCYX",
-	"nmvO1rZAygd3Kj59wqWEidKTK1//+EYePh8iuUWTDYLx/4jF58Mhkj7OoVAMAwmKkLvScWkQ1pkcY+qy",
-	"PIZH+5JPPDk0GRd5g3coPgN9ojbwZaBau9UIX1urQRmW7h0d3tP7PDHfBWEo38ZjIn/oTuz1DcQk+wPl",
-	"nNrLA4uMm/OiacnYGgMxQdI354L1X9edNFb6WSXCwV0FniJRoPfMOx48M4fYvG8UT6eKnwwThnX4NyRC",
-	"xTF4LwpQhvVDu6MXxtIADVmhzH4EiougKgrl9jW5OIQdDNhlxhVBuSJBOXS31IkTaAL5BKM1md23Sodl",
-	"rLS+VvHtgNlmOajtknetPDOkUwUQOB/6hOsjsKaMZNMrXS0wdd1V6CBpC6cv8UmCjwP6MYdAmSC6G9l/",
-	"Uhhi6iBBBzGJTx829SwwhCfvth9+qQcE02rAdleB2/fggsknIfs8z8BjtNsqpDyttN4PxxIkx2n9TZlE",
-	"QzPL2jwEDzxZB814W8znLUySxybtIgi/bMY4G2T77HSFydWecg6nM3suTa2Bq8rhd2XrbPVqbm7X1v0I",
-	"Hz/XaWnj0UzuUmUw7c7Ww1qxVfnBNzaOVedyaauQxdL62eWisDvghWG4+ISNSAlfQowpxjV4PtI6PHtR",
-	"GUauOkTTBePS+uNCYSQv3NsjDzbvOZDaZsJWtBCbVNgCiSCJ+NDz6cRFytGPvJwrpHbOPKPDv7Fj9fbG",
-	"U7oA71V2NKBH/NAwNHv6mFE3HW0PWvtPx1rUafAgS21lJuR2USdQqJm6TJ0tRmU52xWhyB/LcB+D8MvW",
-	"bWXwrgKBCZNYiuxl2tMId+eX3BbhpG5Y9OIa6pFVD/Tk27X63dPpmbVbLy5XXRL6Ai5sfHtSvz4Jr09O",
-	"Z5fNryU68Mdr49mb1aqTRkOQgWNxB6kDnz94YfP+4Rsf0y3tV1rP7z2TUs9Eg62q+0h7dkdx6TfE9qSd",
-	"sRF+PRUOtd533iPRP7BR/utImbXDhsDt2harHH8C5UTlernUNlY6t57Wb1dvV/w1808AAAD//78ktOrT",
-	"DwAA",
+	"H4sIAAAAAAAC/+x9+3PbOPLnv4LSXVXsOsqv2JnEW1t3niQz4928znZ2r25rSgWRLQlrCuAAoB3NlP/3",
+	"b6HxIECR8mNsy9mvf5nKyCQINBqffjf+GORiXgkOXKvB4R8Dlc9gTvGfR3kuaq5P2ZR/rrX5pZKiAqkZ",
+	"4N8lXIicaia4+b8CVC5ZZf93cDYDUgJVmpjhS9BAmseJmBA9A0LtB14oosU5cJURwcH80Tx6DkVGKuAF",
+	"49OMTCgroSBCklLktBwJXi4yQhWRUAmpoSDjBSnFVNR6a5AN4Bs1Xx0cDtxQg2ygF5X5QWnJ+HRwlQ0U",
+	"KMUEV8uz/0VckjnlC+IfiadLLqkyn5qa+dSaiEnyxb3wJcY1TEEOrq6ygYTfaiahGBz+q/luFpPw1/Ce",
+	"GP8bcm1m6HbgzFCnm8ZIOEMyQ7mS8XMo/DwzQy0zb5AS/0U1qSRcADfkkqBryRmfEoYUS7cWXzH/+J8S",
+	"JoPDwf/Ybrhk27HI9hcpxiXMzUQrKS5YAfhKQ3tVCc0miy7a+xdGtQI5YkX6ovlxd+9l14va0+KameHo",
+	"lnBt+ofJdkzjul1Qy8fAkRv/zTTM1XWzS3b1KnyQSkkXS5MNo3fN7K2EArhmtPwgpoyfwG81qI6TWlGl",
+	"LoVsUVlBLkEPssFEyDnVg8PmuQ7CGwpxOod0jH+LGf8/7n+3cjFffrO1njBM1nyta2nv4ILlgDTqXVaB",
+	"z4xyUbRm9fP8ZDaf5d9ml0e/n4v3v7GP76ejd3zxfqE+ndefZur348Xp+Rs4vXa68Se6pvmB8fNj3AO9",
+	"6Cd/5+nQrKBlH6XdkVg+8H4sYp4ix+/M2ac8YJPDJcaJFnj4HdikoNh7vvpPyqoD8gFxx7H18rSPounh",
+	"kx1za20sU1VJF6NlfvubmHHyTkAX3WBOWXlb9jRkqZgENaIdU//nDLjHflBOThH3xhb5PGfagOlESPsn",
+	"ZWG2EIQL7Z5LSb+3s3cw3Nkd7uye7e4d7uwc7uz8//gIFlTDULN55wpnQEs9W57mZys2xXlmv2lgfaNv",
+	"2uSS6RnjZMIugMwZrzUolBUzqtwzBaG8IJesLMkYiISJBDUz4o4TDt+04b3NjHCAQo0k0FrP7Ofsd3LK",
+	"zeqTN8145gnk2nmtkFMNm9IpZXzTfN4OV1dTSQuw4wVmn0qKUmsClyCJykUFSGpOqATCxSWR9vAZpUGJ",
+	"FZ8yvIejmWfmm+nmiPMuqpdU6VFYymo+sRRADYEq3RCg4RU2IUwTDhcgzXP93LF7W+6wh2v1/JAoDUgY",
+	"Voh1m5Sl3Y/KH9sxTIQEomdM4RIl5EIWUPSvYefWa8CpjRhPzrGWNYSHx0KUQLnVIticykXnevUMpJ0q",
+	"UzHavFCkgAmtSx1QaeIUJc9uyXr6vy0mrIQbqCLmsX/QkhVW0buLumQ5vlsihNOwfF6ShVjZO5RAi6GF",
+	"yiz6pZLsgmoUckGJWZpGqqkkkupehEuWIr+H9JgtAgg2u98lkj5SpU5W2CdHnMAc5BR4vogtE4NTTCtD",
+	"yakEpZZlU14y4LpXPkuYMqXBYKh9klzOhAIvHgxcOZOkOWuX5nTSsvR2kafMCxW9lh6yuRizEoa0qrrY",
+	"xdtcHgx6D+fBrQ5nATjoKntJCwmFn/WMXgAZA3Di3kzWsLv7emfZVsoa26N9pBdInGizrFGYEqZgtCQ6",
+	"rw5JLjiHHB+UMKlVtxHY5t6X473J7uQ1DF/R/ZfD/fF+PnxT7MBwb7I/2Z3s5j/Qg4NVBs2Nj3SzDmtF",
+	"FAUz/0PLLwm7LdOnh/SO5pfQcBihOuGnzBjJota5mEO37Uw2bmZ6p3Lzj2BlH77a37nqOI7K6AQ34Zxz",
+	"WMR8Uwq3jpRz9nY6OUdpKm/A9LeTSEpTXatenUvWnMdk2pCg6jkQpokWhgc14zWgghNOZctDYUfotASq",
+	"gjYL6hHpqGiMqc5nfV9orX/3FutvYTZidATcjjjNBjcgkXJ4sjnJwq5H7167agUUf+blwh0DfzKYUrXB",
+	"ppkU9XRmVYMltL4Nyt7yyPeJvy4CfLGizZlUvQS4taUYu7G0IHN6bp7Db93NQlxlFnr/0NLsTn56S354",
+	"vfMDqewTpABNWamcUwoKq5e+FVwD18OzRQWEVlXJLD9su9f+17+V4Aa7jC69cG4uCaoSXEGH4HZugnQu",
+	"p5qOSyBzms8YB9SF8Ac7mnlni7ijzviF0eJGztTISM1VXVkIHTUYW/NzLi75yDJUFvZhxIUeVSCt0M+i",
+	"4QomIdejWrLmV3OyIDOwzPjIWWWZ12FHc6YUog7yNo48ETUvMmLNsZHfo2bAPPiKVDQnXKaZs3lLSPa7",
+	"fUWD5LQc0YqNCqYMQYqMOF/IpBSX0a9+fOQEQ6+MSKphVLI5w3U2U3TWkHkxQ66MJ86cF2VkjY3oh+gh",
+	"NMX8HzKSS6HUSEhmiBS2pcGdlDDhV8ZHXr9Lfg/w6emuamXEYEx5XJYxevNZ81wBnOH/MX4+ivZojqgs",
+	"ZENRpHd61Fpb2K14aefaaEnPek55xLPfqpLyyMXOFBF5XksJPIdIuzSnJ50EOtrQadE7CcaVpjyHPr0X",
+	"qU8qqmfe1yyKOoei/6Pbhum2HWBuIx1uI4HNZ385O/tC7AP2sMYf2N/Z71ITNNNlFxLMhNRE1XODhp5Y",
+	"0fjp3D8JTX7qI5X9of2BryfHRhMFuxuWiycL46uJKETMu+mn6FjU+nBcUn5+LR7jX/0SI/nc68Bctk87",
+	"dbQ0XONsXzIH3TZY/GTCMxMGZaEI1fas3MjD4N512uyFnRsUy5g+oWU5pvl5D3e4b09YWVp/x0SKORHo",
+	"GrB/y4iq8xmhisSWp30u+EuO37UM6fjZ21nMDrj7zxDSzs07MekLVuD5tDJyi5gX0Dwm5pRbdce9jvYk",
+	"08q6BtBV0+0QwPdvtwDcjFUOl7B71p+Iojld2BY58tuOct5JkGbXqQSitPE+Bk9VMvEJLVWHT6Z1FOxM",
+	"e3g+Cg91BXRAqVGINMX6YH4+tH8e4p+Hu3f2KK/ZleznyDoO/CnkgheK1Fyz8n4m+/JVt8n2eIFDp3yM",
+	"rrHnLCtu2JPmnLebWfNvstH8c7zwVgTKPuvFTvWca/ziLf8zfnzV5HV3JNhZO0551oLktCxBGqAGBVx7",
+	"GePVEHL05diY2tYtL5owt5gQA6fAvT2kMnzGuqu18Et1J5dy8hmVLzIDahyn5EcpLhVI/zaRUC6I4M4B",
+	"K40CaYfB9ZifmWehtumVnw/dY6vOGkLck3OMWp3OqwHNqn4EKtFwXi3FEwRKRstWRq+XuLwL/b4q6HCv",
+	"HZGilsixgUnMqBm5nDGjw9a6ZBegAqt02FcS6L17X9oH/NVkN9+j+zB8M94thvv5wZsh3Z3sD/cmu8UP",
+	"sJO/Gb+k3eOgzcD6nOiBQZoHW8FKSw1mWVnIwgq7hVVR7KNbg4hHVgUFzBb4qPG1GQC4rxFxk8X0bXAY",
+	"vWOjw1qXo7IUl7lFPojpFHNDXIiIaSJBidJwQESPNUduk4DXPTHcrQXS48VAwmqXNx2zmfJaMr04NTxm",
+	"N+PYneSjiv0dkBUYHxwOLFYPsoHdpMH/G/oHh0cVG5pHG5a0r15doQE4ER389OUY9YA55RR55vORiQf7",
+	"wAUviLHyDDdaK3uLnGqzAfmMcnw+iJQNyhd6Zn4a15r8/P4sI7+8P3qHY3z+cnb8+dPpptUzKBk7OaPA",
+	"KCqorlNuFXvFNLg4iyGO9ybt77wMUeguv0EwwKwPo+YlRs6NHGEaXihiHydMmVCNuIQieNAa1woU5O3n",
+	"k9Owpq1gix0OkCynTgYefTkeZIMLkMpScXdrZ2vHsJOogNOKmUgE/pQNjEWN27lNiznj263AQSVUh5r5",
+	"U+R0CNqATZqDrekWoRNtAKDBAhessjlBpASKEap36Mz1anwc3WnHqjCDwHyAaIx3LXlbu0NjwEnw4hrS",
+	"KtCZ3U4vaDIPTxEym30Uk0nJOFiJ7tQU6/DFXZu3gx/mYxLSn5z7TkVOoC1yrImsufJQb/ShqTSWvvkF",
+	"/eyg/kIqUZZuVU7tigNTQjYRRPIJLq3tqwjTJBdm1x2DYljK8ufBzsu218kp4KxJokSGMniL3zkuBoeD",
+	"L0LpI8MYJ4m73XHgj6JYWOcn+lLNP2NPqvGgNlmf14mtbrf8VQpqWtaAP1g/LPLo3s7eA03Cfr3LlA6b",
+	"YcxfF3zYMidsf2dnxWRi3/LNJxVSIZdn87VxEjeMJ6R3E4e4A85s9zFn9tH6Iqx/0to9bQPBTevlY07r",
+	"rG2oMGWtSo4eZzelN485paOO5GVl/EQhtERoaayFBWG8Oflmpgc7O49NvGiuuahL6zYaQ3QIInVhcPiv",
+	"ZUXhX79e/ZoNnCvUBkmkJrQnZ0JMeuQDTRg+MPpV1iHKtv+IHfTFlaHBFJBgKd79DEtw1/zzGOOTVNI5",
+	"aJAKF4c6jxGijcaTfGrQRq8s2oa2+vbrErLtPCKynaCsUe08iOWklWcwuRWY7D/mlD6JRDr5DLzjd08N",
+	"Lwyg3QEsTjCrxCtayJAuSbkLPW6KB9s2r6Jf433rUi0UofGK0F6wuRmooiotqgoKo7gbdg0zRFfmpWgS",
+	"YkMIoIyrWAqrAaKFzDgqFgvy5xW2GMFO7EIfG8fWrKHZ7S2eoev7ha5HVspaTMSUixm5fCqf0R7lRD01",
+	"ePUcf3uENW8S6nPOVuGqibDbxI1Io2pFDyrgPprhIp7WY88kqWaCwxY5nYlL5VzBdrQm4d6CpIHDJPM+",
+	"p5zkM8jPCdMuBZtJZ6dTde6MdJsjN2Fy3mSXRwa6G1ZTqdUWeY9apskJrCviIoiY1K+suLF+jheK0KJA",
+	"UE+qDnx6wLI6WeuZLTdaBt3l7QxjETUz9psllaOKGR9x+rca5KIB6pAcc8/KpoZvenum52XKre2BOvnS",
+	"0d1yZkWnkOGKonwEn9AUVuzpaRf7Qtk/0SlwbffV0n0dZvaxQ24hQw1LtOtmPnuPi05C2MTWC5Bswrym",
+	"rjXMK60a7cKzqpChDKGh90acSbW5RU5Ay8XwCH13ii6UdaBpQbRc2JOwLpBzC2sjnK8PubqK8esfMUm+",
+	"nnwIK3fQYjLMcCHdmt5pPfbxZ0/FJV5GL7PU1lTixvNftlGl9VFbFOSyclgLD7uxAzW57wU8XlrtpW1Q",
+	"2txD1SzVhXesF79FMqTskzzcj6+aJSxnNFjDrFB0xCI2usINm+vQ37p8kc/g+OCsYg9Pjy/OzOhRmdei",
+	"FfEJv0F7Tm2LBLDfOhUtgksj7nERHQrbsu65HRJXPKSvQtIzl/fwEDGMjrryGwUwbufmu0n996pyP5dC",
+	"3A4r72P2wxsY7ox/wOyHg+EberA73MkP4PVkp9gb79J7iVG3w8udbG2Vv8qsLrYCbGqiWwLJhThn4EJ7",
+	"QYH0fz1+R1iU9m8OuWUYl1CGQWVwg1iTQYHJQriBFLpuV0KdWUOS5EyMXAVUF0Hx3VFCknicQJ0Zteeq",
+	"cR81xtIC9N1p77SVdHRLeZwbwaRQzGjrXFRGFKbxi0ueuSpxl8UeCVmbgLQu1Ez0sj7HZICoLyYe64WE",
+	"Ma1LjYsPeJXAHQ4aw5Qtf+s1kT+IqQ+C+6QZ2/ol5mZbS3DuDKiQ10ZKdr5s1prXCwEqC2X5evnU2Jh2",
+	"UNGEW5z9gXw9OcZid6bR2B4jzxnjmlBSlZRxotiUD82XTAh9i/yT6Zn5P7p0OrXPIHbf2iKf6AWbIrFU",
+	"nDWtmAZFNk4hH/4EOp8NT5l2+RRD87fNJPMCp8cFvoVzNBPCk6GQfjaJEGf3l2iQkD1YK0u4L59Pz8zm",
+	"zr12FVIwrFLVa9Z/sNt6A83cJOdrEShgQ/8uyUQLly/hGgyZFAGmmrz0ZofxZL9QzTBfT4771Pq47udW",
+	"mn12s7rjmJ+iGDgZL8wC0jlaV4njBgVaMz5VWHO16Jt+U3z3p62Qs9ZMrS/dR7XcrBwmGT0vtlmiPWof",
+	"jnVaKvE8eqP9j26zJHiApJ4Id5CWzJZ1wX7TpsHBZwv650bbaKP/e14k3OC5KQbQVR4FaguizRA/v7dQ",
+	"k5n/NhQb11oLn+eu0ANhHLBCzvv9As/o84w+TxV9/sNOdlDkmvKOTkUuzqBo5pM2zlMhssVc8bSrT87i",
+	"OphFFroS2UKDjNjOJMHI8Z0WfFUJ1Y1q6GukQllcnwZz6iv47mCPOoME3/hYl5pVJRBf/KQwyRsKcozP",
+	"XtCyBotOSQp3nLft0rS7crPjkqe+wiTfvWrge1QlSc29edtRSyCLTaHrj/9fb11HJnWweIOZe5UtLe2o",
+	"ZDmQ0znTs2h11PzaWl6YetzzavX0D24/fd8RLp38/sGrwdWvV9nglPFptIHr2z9sk9XRFauvfdVDbbLv",
+	"inTjAp4OrvgV4SbA243KN9J+d8v1G0vo94EpNBZdS5rI+7qWWNmPtCChWt5VpEYumXXkYHyNGhD4rnVz",
+	"oBjIRwgOrg5PQS2I8SCnhFyDVOvY0+u9FcZXzeAC0Pjt5Q2rbAavN1Otzm2R2NOhOWmn2HvvoiZJFyp3",
+	"dM2XbY8AXS6iKPzCPWZ9c1skauMYtbRKqhrtoySnUto0fe+M4koDte4AwQHzFszO+vEKAXZ7zR9wwZcz",
+	"UYYcA6N1uXrBZv6GADMoneXi1QNX6tArTl0T12tU8qie0n8yqVZ3+gFTScu4Lj017pWzlqTStHltDw+7",
+	"NYrJar3oqaTPrzNJrAWT64gmtZTVm4ONNQ4ihk49mj3NWRuQ+cNvwNW2//r2H06mXm2n7bRL0HCrsiTf",
+	"h8FtN9FCWIywlUo8ab9tqCbFnKm4SKmrpbhFB7tOv3UzURZOE59nTUgiTl5fKjCyvmQLw2h+B2Jl5m0e",
+	"lx4FlLpjAZLZZN/jBhNM/dcyLIyLlrnc3nQZ+Cx1DPZ5xdFBgrJ1oqdNN/LrE04jOPuTHoNrulMFPE2/",
+	"Hxc6P4WU/VaL/L7kmIhzjUPJNo5/RtPvMuV2HclVbVBb4Ti5VVmPicbQ5XpwF9pKULNPDlTVMMT2O1XP",
+	"XsjfIt4ZY8C9qhz0os7r6K5Zo+jbwvSzRgHMsQWXS7lXMyHb6qu1aW2mwoxiV2uMMREJuGH9WmJAyqry",
+	"uQgPhI2/3muiwXWda6oq6V5z331hkoYvB/udDV/u0Bnj2oB47NRrGGm5q/Mz3n6XeLv32FNqiuIDzmI5",
+	"ewJUd8rbD1a/9cQPo6aMLc6lMb90IW/uWp/1Am/oNNUsh1aKzJbutol06zk1EJreIkE1ETyHLFE8WesK",
+	"HIyViLIApZuBfX8EKcQ83EZjk18w6ydtz/Cmr8diAS5phYh0QS9COxqCr5BKlCxfhOKs5nP2DwQzXLz7",
+	"w8YOsKIrP/dWj29MYKaEvou/pskx3lqwA2KsjSrBDanw8ZH9/2sly1u/e4+rdWNXURK+iP2JKeO2DUcU",
+	"MzLLpFyz4dvTk588v3d7OHDI+5B57SRvpPukLstF3EHk6RTxh9anxPVqZR3Rt8a/miYg2SYjyZHwmc0b",
+	"Sc/XTdwLtPOatNEonXUNiB3wxKF16GkbRG6S5fBmPem2xmROsM0rlr5SP+p65NIebIsnt4ZlpHNwqNrw",
+	"qXzxtdOiTUaFWkp1jBGqgb81x2LbSclCPimpC9+wS5B1mpgjYzbH+2WdW7xpR7qOZGonaUIpZMMFEqK6",
+	"4MsZBnt7GijImqu20+4XyovSl2F4We+yrtHCcT3zunWDRq1Y3SKoCTVcutxA+0HvhNvAZkH/V4zr3zet",
+	"YyrRWCSELSqc1mKTgkMHKFQdrDiR4NoIOjYjZ61TZs8j414U+2q+kF6JH+Bw6X8hrOnsQpq70HyzHXfs",
+	"xSWVrWaqS4oDptDYpduX+5N6ggSPaPyw5uH9J8H33Bv34Inwj9sd7pZXCXXl6P9mOP/GGfW7ey/3D179",
+	"cDcjdoXC059Q/2Six/5aP3NG/b1+6zB0E0ESLAwvK3pm+HI9GkpslCzVT9iUI393VcvcWHftWCME2l6N",
+	"N2vyjSYhmUShIxutmwU2UZC01TpD5Tn9xub1nPB6PgZprNrIRi7aEsRtn2UyRSa1xG864bPRacxuPut7",
+	"q/Q9G8DMZ2FS/xm63AcboDOM2XV0fBlEQCjDbBFEdWl4rqzO32nSreGZe1Zev9p7PVR6UabV1SSqsFHY",
+	"3CEtrjRTuEjLtI+z+BnX3qarXYPg4QDaL2StxgHYPFGR5QLBYEBGN4Ou1sJsOd9bWxz9XfjoH+LS2rbb",
+	"Pgza03Xd/FNe0I4bRT4yvgSB1uk/Bn0JwO3eJa7+g65PNEXrySL/+e5vPw4//u2Xs65FxAyHeenJqzOt",
+	"K3W4jVyzFel+MRvdZNRwzcvth//fYVV/XbGQ24YsooracADVkyoPf1LVf64V9hMtnc7sRXkPJElcI8YV",
+	"RY3ODI/82Lbir8b7mnyeXI9UwXmv7LwYQS+Ky0f2X3fVydRJkUyr2fSaql/QK+E76TitfqnqpbmGS2Wh",
+	"hiCp/LRCh8xRIptyGK+p2vTIUDlw9wqZpfV8QLdp6564QGYxSV0z8I0pHafFmXmXYqowS8op1lQ3LhyX",
+	"CWoGCjRKfDp9yzG6e9dKmttflot99r77hidpKCEt43H+MFvKt1TZG9K4n2x4IJq/4YIQF5wwzvz1Kk2j",
+	"d7/C2KXOdFJahql+G513sG3+dxenT6sFyYNZWidBMIi2uR55d/2x7hGBrhFAj02F2VYYWIouOnBSV1WQ",
+	"G13TgSXGgvDfitS8SBrupw3rmb5ztiiG+blo8oGl8Zdy374+IK/u9CFCKAwQJh/LD9uy67osdLtkWyEg",
+	"mfOjJP3yQ7aWmDSza981gkOcQ6UzohjPIY7rpBm4mMOFE5v/hdTc40YsquL+/9fbjn3luQ8bgU9Y5Pid",
+	"oYRJ4LWVvRMi/PWgKEUR01s8dKO6gyZR9rHs2jkoRactoypxajcJI1urL8Tuv2zZ3Wu9oVM3r/21Sb3e",
+	"DNdXk43rGdk7J9JmomNgfOqZe7O537n1aXuDQ/o63hemKeP++W4npeN/XpCT92dHx59GH44//f39u9HZ",
+	"57+//3Q6+vxp9OHzz5+/noV7MZTASHN807c5OUlvrKVjY8Z3Vf3OmbmZ3uXdWpJtWRMTA3hRCcbtBBoi",
+	"2wngCO7OZ4KQ5TpDLVy5QRvXQvKgT9Bs3YHmdvMegigxvz0X2bWK7MKNts2lr+vSTW5RDf7BIqU/O4mE",
+	"tR0srkubC3WlfwyqWvddBobPxMfW3tNiRK1DVvOjaLehdOmw7rwoBHTdPWDDnFY0A5UlA6Wd+I6r48aQ",
+	"izmo+KLulmCrE7nmLg//7qLS3Zee3ygovX+zjbyka4uadmeQLeOC4ek4qNpAxf66YkZOItgCLU/Jtuq+",
+	"LvBob3F3gCt1ooFu1Tm2B7lRAq4roF1lKuADcVEYgQvghE0I06E3XLjcfYKd11AOOmeW7oxmM41oYTJ1",
+	"c1or8Haw14OClS1y2+RHncMlpsiEQt9Q/auWr7NBjbyAYVHbrbNdT/5s/W1b+XYjrrvqLK5kxqQ8VxRt",
+	"qgontC6btmuruSU9CuvTya85SdG1x9deL+MoQZomgE/k5qynoUwFzaWtuHi6Jc3nOwo11wDq6SX/TbTV",
+	"2qQxmX0wal1pHZaywRaJ0Ccj1HmOOkLpaq06bJsTbLVH4a6deQqpFs6ITW9g9nTFXE+mu/MxSLNKpqzZ",
+	"6RmcwzftTR23arUOr+IZm7t6lEvKMLKActylIrgJvggg7yWfXZQWzvHc1hZ+EjIHQttvrXD+dR2iLgVi",
+	"uW6ykoAC10u3dvTR/50wTib0QtQYkdm+2Ose28htJ0uwJ4fxp/tBULED6qdJiQn8BFlnzWght8gnocHG",
+	"cJoUA5+Vhh8x122MlShr7bqkLWwH0q+cfSOazUFpOq8y5CefSxBsnGvrZB64/LJTSag5+62G5iqAEPZC",
+	"JDebfTlLDL6Wt+ji+1UfbldOGpWSdl/OvqqYNOUPQrW7XmZ1WenuDy8PXr1+05PXcttr8rWsla8Awzvv",
+	"rfaaEQVAEhbcSoa+y1X1t80KaQPMzQT1U3JxYefBxIR9VtAeRkF7VnieFZ57U3iaKmV+Fy3H/FNtz/uv",
+	"GzsK3cxcJpcv650wqVw7TJcSy5s+OHFbCBdDWYDeIh+ohjThyhTnRQL7hYpvDpegRHkBQSrTuX3KJ9wY",
+	"rkWfcjpCu+dSKFJqZa4s6TKmx476CIMHNOvNJ64TJmGJrAnBNlRZh+z42GfEr83NGZrQjaEUfNqE6NYF",
+	"sS4Z9KbdvXTcTAHfjUP+dlGKaNE6p9sNH/S7Mo9C+D5pQIis9FejZKNL0DGW4PY8/sWGql0hrh8BDzLF",
+	"UxZuzI5TvslR0405yl4ESaaglUsWs9vS5WN0B+64WdTDBCuMueQ+snjA+rl7OfjBWR/Q1O7UcxzkgeIg",
+	"a3CYha3tLYRaF4yFmSVQFjgwje8aJ0RUs59iw7It3aF3RHgWO0RCn8JV7QlPoiZ+ftqdCRVRGkYrvyG7",
+	"YeJW1qTKxhkiy7fKdKk4TBtGNIAOsq3SIEnIUUgGMTZvswWhWWvN/Q50NwxcwlFvDtuugU+kW6Bf2HfS",
+	"LvBWiO2zXZs1PmtpK7S00LgjRsLIWtCCMP0U0DnO1IoP59PC5wYfUoT+ykuH0eG9kP6yApebNrEWNiNo",
+	"vn3nwKZZrHfc/VuMjYrZ2MDpMaLt6yO2yHuaz+xDph2sIhThdFgy9F8kgF+BjODdG62YDBSTYTmbuRHF",
+	"Ta6ve9E2kQ1EJisbdPf35D5rIrWNr8J22QgP9ZqlFsltju6XBqqvvawmNTKO390j+GZPp2rz3iLpdl9S",
+	"f0LCYM+NCVc2JgzA3nkT4K2cuU+uueEaBGl0L2UL5zobZj67lp9dy3/WtXy3Hpnc3wPi3M82vbczx70z",
+	"X68vKn63SzL6ZfC93E3xj711R72vS437D4lw37NYf6KR2efUuefI7LP4fI7MFs41YvsZu+ws7m4s9Nca",
+	"btkVKJAXXtLUsnQtUQ63t7FUbCaUPny983rHXJn2XwMAjO1/5zXCAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/services"
+	"encoding/json"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"time"
+)
+
+// GetAuthProviderAppToken returns a client-credentials app token that is not tied to any user.
+// It is only available to internal callers.
+func (s *Server) GetAuthProviderAppToken(w http.ResponseWriter, r *http.Request, provider string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Getting auth provider app token", map[string]interface{}{
+		"provider": provider,
+	})
+
+	if !authorizeInternalCaller(w, r) {
+		return
+	}
+
+	if _, exists := config.Providers[provider]; !exists {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	token, err := services.GetAppToken(ctx, provider)
+	if err != nil {
+		slog.Error(ctx, "Failed to get app token", err, map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadGateway, problemProviderError, "Failed to get app token")
+		return
+	}
+
+	var expiresIn int64
+	if !token.Expiry.IsZero() {
+		expiresIn = int64(time.Until(token.Expiry).Seconds())
+	}
+
+	response := map[string]interface{}{
+		"access_token": token.AccessToken,
+		"token_type":   token.Type(),
+		"expires_in":   expiresIn,
+	}
+
+	slog.Info(ctx, "Successfully retrieved app token", map[string]interface{}{
+		"provider":   provider,
+		"expires_at": token.Expiry,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
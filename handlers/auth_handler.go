@@ -7,10 +7,12 @@ import (
 	"auth-service/utils"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/monzo/slog"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/google/uuid"
@@ -31,20 +33,58 @@ func (s *Server) GetAuthProviderLogin(w http.ResponseWriter, r *http.Request, pr
 		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
 			"provider": provider,
 		})
-		http.Error(w, "Unsupported provider", http.StatusNotFound)
+		writeProblem(w, r, http.StatusNotFound, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	// Resolve the registered client, whose policy applies to the whole login.
+	var clientID string
+	if params.ClientId != nil {
+		clientID = *params.ClientId
+	}
+	client, err := lookupClient(clientID)
+	if err != nil {
+		slog.Error(ctx, "Unknown client", err, map[string]interface{}{
+			"client_id": clientID,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnknownClient, "Unknown client")
+		return
+	}
+	if client != nil && !client.AllowsProvider(provider) {
+		slog.Error(ctx, "Provider not permitted for client", fmt.Errorf("provider not permitted"), map[string]interface{}{
+			"client_id": clientID,
+			"provider":  provider,
+		})
+		writeProblem(w, r, http.StatusForbidden, problemProviderNotPermitted, "Provider is not permitted for this client")
+		return
+	}
+	if !checkLoginAllowed(w, r, provider, clientID) {
 		return
 	}
 
 	// Access and validate the redirect URI.
 	redirectURI := params.RedirectUri
-	if err := utils.ValidateRedirectURIFromEnv(redirectURI); err != nil {
+	if err := validateClientRedirectURI(client, redirectURI); err != nil {
 		slog.Error(ctx, "Invalid redirect URI", err, map[string]interface{}{
 			"redirect_uri": redirectURI,
 		})
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeRedirectURIProblem(w, r, err)
 		return
 	}
 
+	// A linking login logs the account in to the caller's existing session, and links it to the
+	// session's user, instead of starting a new session.
+	var linkSessionID string
+	if params.Link != nil && *params.Link {
+		sessionCookie, err := r.Cookie("session_id")
+		if err != nil || sessionCookie.Value == "" {
+			slog.Error(ctx, "Session ID is required to link an account", fmt.Errorf("missing session cookie"), nil)
+			writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
+			return
+		}
+		linkSessionID = sessionCookie.Value
+	}
+
 	// Generate a state token (for CSRF protection) and combine it with the redirect URI.
 	stateToken := uuid.New().String()
 	state := stateToken + "|" + redirectURI
@@ -53,27 +93,34 @@ func (s *Server) GetAuthProviderLogin(w http.ResponseWriter, r *http.Request, pr
 	verifier, err := utils.GenerateCodeVerifier()
 	if err != nil {
 		slog.Error(ctx, "Failed to generate code verifier", err, nil)
-		http.Error(w, "Server error while generating code verifier", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while generating code verifier")
 		return
 	}
 	challenge := utils.GenerateCodeChallenge(verifier)
 
-	// Store the PKCE data
-	if err = services.StorePKCEData(stateToken, verifier); err != nil {
+	// Store the PKCE data, along with the client so the callback can enforce its policy.
+	if client == nil && linkSessionID == "" {
+		err = services.StorePKCEData(stateToken, verifier)
+	} else {
+		pkceData := services.PKCEData{
+			CodeVerifier:  verifier,
+			LinkSessionID: linkSessionID,
+		}
+		if client != nil {
+			pkceData.ClientID = client.ID
+		}
+		err = services.SavePKCEData(stateToken, pkceData)
+	}
+	if err != nil {
 		slog.Error(ctx, "Failed to store PKCE data", err, map[string]interface{}{
 			"state_token": stateToken,
 		})
-		http.Error(w, "Server error while storing PKCE data", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while storing PKCE data")
 		return
 	}
 
 	// Generate the authorization URL including the PKCE parameters.
-	authURL := oauthConfig.AuthCodeURL(
-		state,
-		oauth2.SetAuthURLParam("code_challenge", challenge),
-		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
-		oauth2.AccessTypeOffline,
-	)
+	authURL := providerAuthCodeURL(clientOAuthConfig(oauthConfig, client, provider), state, challenge)
 
 	slog.Info(ctx, "Redirecting to auth provider", map[string]interface{}{
 		"provider": provider,
@@ -97,7 +144,7 @@ func (s *Server) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request,
 		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
 			"provider": provider,
 		})
-		http.Error(w, "Unsupported provider", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
 		return
 	}
 
@@ -107,28 +154,75 @@ func (s *Server) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request,
 		slog.Error(ctx, "Invalid state parameter", fmt.Errorf("invalid state format"), map[string]interface{}{
 			"state": params.State,
 		})
-		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidState, "Invalid state parameter")
 		return
 	}
 	stateToken := parts[0]
 	redirectURI := parts[1]
 
+	// Retrieve the PKCE data previously stored with this state token. It records the client that
+	// started the login, whose policy decides which redirect URIs are valid.
+	pkceData, pkceErr := services.GetPKCEData(stateToken)
+	var client *config.ClientConfig
+	if pkceErr == nil {
+		var err error
+		client, err = lookupClient(pkceData.ClientID)
+		if err != nil {
+			slog.Error(ctx, "Unknown client", err, map[string]interface{}{
+				"client_id": pkceData.ClientID,
+			})
+			writeProblem(w, r, http.StatusBadRequest, problemUnknownClient, "Unknown client")
+			return
+		}
+		if client != nil && !client.AllowsProvider(provider) {
+			slog.Error(ctx, "Provider not permitted for client", fmt.Errorf("provider not permitted"), map[string]interface{}{
+				"client_id": client.ID,
+				"provider":  provider,
+			})
+			writeProblem(w, r, http.StatusForbidden, problemProviderNotPermitted, "Provider is not permitted for this client")
+			return
+		}
+	}
+
 	// Validate the redirect URI.
-	if err := utils.ValidateRedirectURIFromEnv(redirectURI); err != nil {
+	if err := validateClientRedirectURI(client, redirectURI); err != nil {
 		slog.Error(ctx, "Invalid redirect URI", err, map[string]interface{}{
 			"redirect_uri": redirectURI,
 		})
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeRedirectURIProblem(w, r, err)
 		return
 	}
 
-	// Retrieve the code verifier previously stored with this state token.
-	codeVerifier, err := services.GetCodeVerifier(stateToken)
-	if err != nil || codeVerifier == "" {
-		slog.Error(ctx, "Failed to retrieve code verifier", err, map[string]interface{}{
+	// An expired or unknown login is the user's to restart, not a server fault.
+	if errors.Is(pkceErr, services.ErrPKCEDataNotFound) || (pkceErr == nil && pkceData.CodeVerifier == "") {
+		slog.Info(ctx, "Login expired", map[string]interface{}{
 			"state_token": stateToken,
 		})
-		http.Error(w, "Failed to retrieve code verifier", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, problemLoginExpired, "The login has expired, start it again")
+		return
+	}
+	if pkceErr != nil {
+		slog.Error(ctx, "Failed to retrieve code verifier", pkceErr, map[string]interface{}{
+			"state_token": stateToken,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to retrieve code verifier")
+		return
+	}
+
+	// A linking login must be finished by the browser that started it. Otherwise anyone could start one
+	// and send the provider's login page to a victim, whose account would be linked into their session.
+	if pkceData.LinkSessionID != "" && pkceData.DeviceCode == "" {
+		if sessionCookie, err := r.Cookie("session_id"); err != nil || sessionCookie.Value != pkceData.LinkSessionID {
+			slog.Error(ctx, "Linking login finished outside its session", fmt.Errorf("link session mismatch"), map[string]interface{}{
+				"provider": provider,
+			})
+			writeProblem(w, r, http.StatusForbidden, problemLinkSessionMismatch, "The account must be linked from the browser that started linking it")
+			return
+		}
+	}
+
+	// Logins started before an emergency revocation began must not store new tokens during it.
+	if !checkLoginAllowed(w, r, provider, pkceData.ClientID) {
 		return
 	}
 
@@ -142,22 +236,37 @@ func (s *Server) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request,
 		}
 	}(stateToken)
 
+	// The user declined a device login at the provider; let the waiting device know.
+	if providerError := r.URL.Query().Get("error"); providerError != "" && pkceData.DeviceCode != "" {
+		slog.Info(ctx, "Device login declined at provider", map[string]interface{}{
+			"provider": provider,
+			"error":    providerError,
+		})
+		if err := services.DenyDeviceGrant(pkceData.DeviceCode); err != nil {
+			slog.Error(ctx, "Failed to deny device grant", err, map[string]interface{}{
+				"provider": provider,
+			})
+		}
+		http.Redirect(w, r, withQueryParam(redirectURI, "error", services.ErrAccessDenied.Error()), http.StatusTemporaryRedirect)
+		return
+	}
+
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		slog.Error(ctx, "Authorization code not provided", fmt.Errorf("missing code"), nil)
-		http.Error(w, "Authorization code not provided", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Authorization code not provided")
 		return
 	}
 
 	// Exchange the authorization code for an access token
 	token, err := oauthConfig.Exchange(r.Context(), code,
-		oauth2.SetAuthURLParam("code_verifier", codeVerifier),
+		oauth2.SetAuthURLParam("code_verifier", pkceData.CodeVerifier),
 	)
 	if err != nil {
 		slog.Error(ctx, "Failed to exchange token", err, map[string]interface{}{
 			"code": code,
 		})
-		http.Error(w, "Failed to exchange token: "+err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadGateway, problemProviderError, "Failed to exchange token")
 		return
 	}
 
@@ -167,22 +276,46 @@ func (s *Server) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request,
 		slog.Error(ctx, "Failed to fetch user information", err, map[string]interface{}{
 			"provider": provider,
 		})
-		http.Error(w, "Failed to fetch user information: "+err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadGateway, problemProviderError, "Failed to fetch user information")
+		return
+	}
+
+	// Logins the policy denies are sent back to the client with the reason, like a declined login.
+	if decision := checkLoginPolicy(r, provider, user, token); !decision.Allowed {
+		if pkceData.DeviceCode != "" {
+			if err := services.DenyDeviceGrant(pkceData.DeviceCode); err != nil {
+				slog.Error(ctx, "Failed to deny device grant", err, map[string]interface{}{
+					"provider": provider,
+				})
+			}
+		}
+		http.Redirect(w, r, loginDeniedRedirectURI(redirectURI, decision), http.StatusTemporaryRedirect)
 		return
 	}
 
-	// Generate a session ID and store the token
+	// Generate a session ID, or join the session a linking login started from, and store the token
+	// along with the client whose token policy applies to it.
 	sessionID := uuid.New().String()
-	if err = services.StoreAuthToken(sessionID, provider, user, token); err != nil {
-		slog.Error(ctx, "Failed to store token", err, map[string]interface{}{
-			"session_id": sessionID,
-			"provider":   provider,
-			"user_id":    user.ID,
-		})
-		http.Error(w, "Failed to store token", http.StatusInternalServerError)
+	link := pkceData.LinkSessionID != "" && pkceData.DeviceCode == ""
+	if link {
+		sessionID = pkceData.LinkSessionID
+		if !checkIdentityLinkable(w, r, sessionID, provider, user.ID) {
+			return
+		}
+	}
+	var sessionClientID string
+	if client != nil {
+		sessionClientID = client.ID
+	}
+	stored := storeWithinSessionLimit(w, r, sessionID, provider, user.ID, token, func() error {
+		return services.StoreClientAuthToken(sessionID, provider, sessionClientID, user, token)
+	})
+	if !stored {
 		return
 	}
 
+	resolveSessionUser(r, sessionID, provider, user.ID, link)
+
 	slog.Info(ctx, "Successfully authenticated user", map[string]interface{}{
 		"session_id":   sessionID,
 		"provider":     provider,
@@ -190,18 +323,113 @@ func (s *Server) GetAuthProviderCallback(w http.ResponseWriter, r *http.Request,
 		"redirect_uri": redirectURI,
 	})
 
-	// Set the session ID as a secure cookie.
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    sessionID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
+	// A device login hands the session to the waiting device instead of this browser.
+	if pkceData.DeviceCode != "" {
+		if err = services.ApproveDeviceGrant(pkceData.DeviceCode, sessionID, user.ID); err != nil {
+			slog.Error(ctx, "Failed to approve device grant", err, map[string]interface{}{
+				"session_id": sessionID,
+				"provider":   provider,
+			})
+			writeProblem(w, r, http.StatusBadRequest, problemInvalidUserCode, "Failed to approve device")
+			return
+		}
+		http.Redirect(w, r, redirectURI, http.StatusTemporaryRedirect)
+		return
+	}
+
+	// Hand the session to the client and redirect the user back to the original redirect URI.
+	completeLogin(w, r, client, sessionID, redirectURI)
+}
+
+// checkIdentityLinkable reports whether the account can be linked to the session's user, writing a
+// problem if it cannot.
+func checkIdentityLinkable(w http.ResponseWriter, r *http.Request, sessionID, provider, userID string) bool {
+	err := services.CheckIdentityLinkable(r.Context(), sessionID, provider, userID)
+	if errors.Is(err, services.ErrIdentityLinked) {
+		slog.Error(r.Context(), "Account is linked to another user", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+		writeProblem(w, r, http.StatusConflict, problemIdentityLinked, "The account is linked to another user")
+		return false
+	}
+	if err != nil {
+		slog.Error(r.Context(), "Failed to check identity", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to link account")
+		return false
+	}
+	return true
+}
+
+// storeWithinSessionLimit stores the account's token in the session with store, under the provider's
+// session limit, writing a problem if the login is rejected or the token cannot be stored.
+func storeWithinSessionLimit(w http.ResponseWriter, r *http.Request, sessionID, provider, userID string, token *oauth2.Token, store func() error) bool {
+	err := services.EnforceSessionLimit(r.Context(), provider, userID, sessionID, token, store)
+	if errors.Is(err, services.ErrSessionLimitReached) {
+		slog.Error(r.Context(), "Account is logged in to too many sessions", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+		writeProblem(w, r, http.StatusConflict, problemSessionLimitReached, "The account is logged in to the maximum number of sessions")
+		return false
+	}
+	if err != nil {
+		slog.Error(r.Context(), "Failed to store token", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to store token")
+		return false
+	}
+	return true
+}
+
+// resolveSessionUser attaches the session to the internal user of the account that just logged in,
+// restoring the user's other linked accounts into it. The login has succeeded regardless, so failures
+// are only logged.
+func resolveSessionUser(r *http.Request, sessionID, provider, userID string, link bool) {
+	user, err := services.ResolveSessionUser(r.Context(), sessionID, provider, userID, link)
+	if err != nil {
+		slog.Error(r.Context(), "Failed to resolve user", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+		return
+	}
+	slog.Info(r.Context(), "Resolved user", map[string]interface{}{
+		"session_id":       sessionID,
+		"internal_user_id": user.ID,
 	})
+}
+
+// providerAuthCodeURL generates the provider's authorization URL including the PKCE parameters.
+func providerAuthCodeURL(oauthConfig *oauth2.Config, state, challenge string) string {
+	return oauthConfig.AuthCodeURL(
+		state,
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.AccessTypeOffline,
+	)
+}
 
-	// Redirect the user back to the original redirect URI.
-	http.Redirect(w, r, redirectURI, http.StatusTemporaryRedirect)
+// withQueryParam returns the URI with the query parameter added.
+func withQueryParam(uri, key, value string) string {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := parsedURL.Query()
+	query.Set(key, value)
+	parsedURL.RawQuery = query.Encode()
+	return parsedURL.String()
 }
 
 // PostAuthProviderLogout handles logout requests.
@@ -212,11 +440,11 @@ func (s *Server) PostAuthProviderLogout(w http.ResponseWriter, r *http.Request,
 		"user_id":  params.UserId,
 	})
 
-	if _, exists := config.Providers[provider]; !exists {
+	if !config.IsSupportedProvider(provider) {
 		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
 			"provider": provider,
 		})
-		http.Error(w, "Unsupported provider", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
 		return
 	}
 
@@ -224,20 +452,30 @@ func (s *Server) PostAuthProviderLogout(w http.ResponseWriter, r *http.Request,
 	sessionCookie, err := r.Cookie("session_id")
 	if err != nil || sessionCookie.Value == "" {
 		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
-		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
 		return
 	}
 	sessionID := sessionCookie.Value
 
-	// If a specific user is specified, log out that user.
+	// The client is looked up before its accounts are deleted, so an ended session's cookie is expired
+	// with the same settings it was set with.
+	var userID string
+	if params.UserId != nil {
+		userID = *params.UserId
+	}
+	client := sessionClient(sessionID, provider, userID)
+
+	// If a specific user is specified, log out that user. Tokens are also revoked at the provider where
+	// it supports revocation, and the response reports how far that got.
 	if params.UserId != nil && *params.UserId != "" {
-		if err := services.DeleteAuthToken(sessionID, provider, *params.UserId); err != nil {
+		revocation, err := services.LogoutAccount(ctx, sessionID, provider, *params.UserId)
+		if err != nil {
 			slog.Error(ctx, "Failed to log out user", err, map[string]interface{}{
 				"session_id": sessionID,
 				"provider":   provider,
 				"user_id":    *params.UserId,
 			})
-			http.Error(w, "Failed to log out user", http.StatusInternalServerError)
+			writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to log out user")
 			return
 		}
 		log.Printf("Logged out user %s from provider %s", *params.UserId, provider)
@@ -245,30 +483,61 @@ func (s *Server) PostAuthProviderLogout(w http.ResponseWriter, r *http.Request,
 			"session_id": sessionID,
 			"provider":   provider,
 			"user_id":    *params.UserId,
+			"revocation": revocation,
 		})
+		endSessionIfEmpty(w, r, sessionID, client)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
-			"message": fmt.Sprintf("Successfully logged out user %s from provider %s", *params.UserId, provider),
+			"message":    fmt.Sprintf("Successfully logged out user %s from provider %s", *params.UserId, provider),
+			"revocation": revocation,
 		})
 		return
 	}
 
 	// Otherwise, log out all users for the provider.
-	if err := services.DeleteAllAuthTokensForProvider(sessionID, provider); err != nil {
+	revocation, err := services.LogoutProvider(ctx, sessionID, provider)
+	if err != nil {
 		slog.Error(ctx, "Failed to log out all users", err, map[string]interface{}{
 			"session_id": sessionID,
 			"provider":   provider,
 		})
-		http.Error(w, "Failed to log out all users", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to log out all users")
 		return
 	}
 	log.Printf("Logged out all users from provider %s", provider)
 	slog.Info(ctx, "Successfully logged out all users", map[string]interface{}{
 		"session_id": sessionID,
 		"provider":   provider,
+		"revocation": revocation,
 	})
+	endSessionIfEmpty(w, r, sessionID, client)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": fmt.Sprintf("Successfully logged out all users from provider %s", provider),
+		"message":    fmt.Sprintf("Successfully logged out all users from provider %s", provider),
+		"revocation": revocation,
 	})
 }
+
+// endSessionIfEmpty ends the session and expires its cookie once its last account is logged out.
+func endSessionIfEmpty(w http.ResponseWriter, r *http.Request, sessionID string, client *config.ClientConfig) {
+	ended, err := services.EndEmptySession(r.Context(), sessionID)
+	if err != nil {
+		slog.Error(r.Context(), "Failed to end empty session", err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+		return
+	}
+	if ended {
+		clearSessionCookie(w, client)
+	}
+}
+
+// sessionClient returns the registered client the logged-out accounts logged in through, or nil when
+// they used none or the client is no longer registered.
+func sessionClient(sessionID, provider, userID string) *config.ClientConfig {
+	client, err := lookupClient(services.GetAccountClientID(sessionID, provider, userID))
+	if err != nil {
+		return nil
+	}
+	return client
+}
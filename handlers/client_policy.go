@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// lookupClient returns the registered client for the ID, or nil when no client ID was given.
+func lookupClient(clientID string) (*config.ClientConfig, error) {
+	if clientID == "" {
+		return nil, nil
+	}
+	client, exists := config.GetClient(clientID)
+	if !exists {
+		return nil, fmt.Errorf("unknown client: %s", clientID)
+	}
+	return client, nil
+}
+
+// validateClientRedirectURI checks the URI against the client's registered redirect URIs, or the
+// global allowlist when the login did not come from a registered client.
+func validateClientRedirectURI(client *config.ClientConfig, uri string) error {
+	if client == nil {
+		return utils.ValidateRedirectURIFromEnv(uri)
+	}
+	return client.ValidateRedirectURI(uri)
+}
+
+// writeRedirectURIProblem explains why the redirect URI was rejected. Errors loading the redirect
+// configuration itself are not the caller's fault and are only logged.
+func writeRedirectURIProblem(w http.ResponseWriter, r *http.Request, err error) {
+	var redirectErr *utils.RedirectURIError
+	if !errors.As(err, &redirectErr) {
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Redirect URIs are not configured")
+		return
+	}
+	writeProblem(w, r, http.StatusBadRequest, problemInvalidRedirectURI, redirectErr.Error())
+}
+
+// clientOAuthConfig returns the provider config with the scopes the client is permitted to request.
+func clientOAuthConfig(oauthConfig *oauth2.Config, client *config.ClientConfig, provider string) *oauth2.Config {
+	if client == nil {
+		return oauthConfig
+	}
+	clientConfig := *oauthConfig
+	clientConfig.Scopes = client.ScopesFor(provider, oauthConfig.Scopes)
+	return &clientConfig
+}
+
+// completeLogin hands the new session to the client using its response mode and redirects back to it.
+func completeLogin(w http.ResponseWriter, r *http.Request, client *config.ClientConfig, sessionID, redirectURI string) {
+	responseMode := config.ResponseModeCookie
+	if client != nil {
+		responseMode = client.ResponseMode
+	}
+
+	switch responseMode {
+	case config.ResponseModeQuery:
+		redirectURI = withQueryParam(redirectURI, "session_id", sessionID)
+	case config.ResponseModeFragment:
+		redirectURI = withFragmentParam(redirectURI, "session_id", sessionID)
+	default:
+		setClientSessionCookie(w, client, sessionID)
+	}
+
+	http.Redirect(w, r, redirectURI, http.StatusTemporaryRedirect)
+}
+
+// withFragmentParam returns the URI with the parameter added to its fragment.
+func withFragmentParam(uri, key, value string) string {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	fragment, _ := url.ParseQuery(parsedURL.Fragment)
+	fragment.Set(key, value)
+	parsedURL.Fragment = fragment.Encode()
+	return parsedURL.String()
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/generated"
+	"auth-service/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PostAuthProviderCredentials handles logins for credential providers that have no OAuth flow.
+// The credentials are exchanged for a user auth token once and are never stored.
+func (s *Server) PostAuthProviderCredentials(w http.ResponseWriter, r *http.Request, provider string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Starting credential provider login", map[string]interface{}{
+		"provider": provider,
+	})
+
+	if _, exists := config.CredentialProviders[provider]; !exists {
+		slog.Error(ctx, "Unsupported credential provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusNotFound, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+	if !checkLoginAllowed(w, r, provider, "") {
+		return
+	}
+
+	var body generated.PostAuthProviderCredentialsJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		slog.Error(ctx, "Invalid credentials request body", err, map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(body.Username) == "" || body.Password == "" {
+		slog.Error(ctx, "Username and password are required", fmt.Errorf("missing credentials"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Username and password are required")
+		return
+	}
+
+	// Exchange the credentials for a user auth token.
+	user, token, err := services.ExchangeCredentials(ctx, provider, body.Username, body.Password)
+	if err != nil {
+		slog.Error(ctx, "Failed to exchange credentials", err, map[string]interface{}{
+			"provider": provider,
+		})
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			writeProblem(w, r, http.StatusUnauthorized, problemInvalidCredentials, "Invalid username or password")
+			return
+		}
+		writeProblem(w, r, http.StatusBadGateway, problemProviderError, "Failed to exchange credentials")
+		return
+	}
+
+	if decision := checkLoginPolicy(r, provider, user, token); !decision.Allowed {
+		writeProblem(w, r, http.StatusForbidden, problemLoginDenied, decision.Reason)
+		return
+	}
+
+	// Link the account into the caller's existing session, and to its user, or start a new one.
+	sessionID := uuid.New().String()
+	link := false
+	if sessionCookie, err := r.Cookie("session_id"); err == nil && sessionCookie.Value != "" {
+		sessionID = sessionCookie.Value
+		link = true
+		if !checkIdentityLinkable(w, r, sessionID, provider, user.ID) {
+			return
+		}
+	}
+
+	stored := storeWithinSessionLimit(w, r, sessionID, provider, user.ID, token, func() error {
+		return services.StoreAuthToken(sessionID, provider, user, token)
+	})
+	if !stored {
+		return
+	}
+
+	resolveSessionUser(r, sessionID, provider, user.ID, link)
+
+	slog.Info(ctx, "Successfully authenticated user with credentials", map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    user.ID,
+	})
+
+	setSessionCookie(w, sessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services.LoggedInProvider{
+		Provider:    provider,
+		UserID:      user.ID,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		LoggedIn:    true,
+	})
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/generated"
+	"auth-service/services"
+	"auth-service/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// deviceCompleteRedirectURI is where the user's phone is sent once the device login finishes.
+func deviceCompleteRedirectURI() string {
+	return os.Getenv("DEVICE_COMPLETE_REDIRECT_URI")
+}
+
+// deviceVerificationURI is the page the user opens on their phone to enter the user code.
+func deviceVerificationURI(r *http.Request) string {
+	if uri := os.Getenv("DEVICE_VERIFICATION_URI"); uri != "" {
+		return uri
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/auth/device"
+}
+
+// PostAuthProviderDeviceCode starts a device authorization grant for clients without a usable browser.
+func (s *Server) PostAuthProviderDeviceCode(w http.ResponseWriter, r *http.Request, provider string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Starting device authorization", map[string]interface{}{
+		"provider": provider,
+	})
+
+	if _, exists := config.Providers[provider]; !exists {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusNotFound, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	if deviceCompleteRedirectURI() == "" {
+		slog.Error(ctx, "Device authorization is not enabled", fmt.Errorf("DEVICE_COMPLETE_REDIRECT_URI is not set"), nil)
+		writeProblem(w, r, http.StatusServiceUnavailable, problemDeviceFlowDisabled, "Device authorization is not enabled")
+		return
+	}
+	if !checkLoginAllowed(w, r, provider, "") {
+		return
+	}
+
+	grant, err := services.CreateDeviceGrant(provider, requestIP(r), r.UserAgent())
+	if err != nil {
+		slog.Error(ctx, "Failed to create device grant", err, map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while creating device grant")
+		return
+	}
+
+	verificationURI := deviceVerificationURI(r)
+	response := map[string]interface{}{
+		"device_code":               grant.DeviceCode,
+		"user_code":                 grant.UserCode,
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": withQueryParam(verificationURI, "user_code", grant.UserCode),
+		"expires_in":                int64(services.DeviceGrantLifetime.Seconds()),
+		"interval":                  grant.Interval,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deviceConfirmationPage asks the user to check the device before the login starts, so a code someone
+// else sent them cannot sign that person's device into the user's account with a single tap.
+var deviceConfirmationPage = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Confirm device sign-in</title>
+</head>
+<body>
+<h1>Sign in a device with {{.Provider}}?</h1>
+<p>Only continue if this code is shown on a device you are signing in to yourself. If someone sent you this link or code, close this page.</p>
+<dl>
+<dt>Code</dt><dd>{{.UserCode}}</dd>
+<dt>Device</dt><dd>{{if .DeviceUserAgent}}{{.DeviceUserAgent}}{{else}}Unknown{{end}}</dd>
+<dt>Requested from</dt><dd>{{if .DeviceIP}}{{.DeviceIP}}{{else}}Unknown{{end}}</dd>
+<dt>Requested at</dt><dd>{{.RequestedAt}}</dd>
+</dl>
+<form method="post" action="?user_code={{.UserCode}}">
+<button type="submit">Continue to {{.Provider}}</button>
+</form>
+</body>
+</html>
+`))
+
+// requestIP returns the address of the client, looking through the trusted proxies in front of the
+// service.
+func requestIP(r *http.Request) string {
+	return utils.ClientIP(r, config.GetTrustedProxies())
+}
+
+// GetAuthDevice is the verification URL the user opens on their phone. It shows which device asked to
+// sign in and only starts the provider login once the user confirms with PostAuthDevice.
+func (s *Server) GetAuthDevice(w http.ResponseWriter, r *http.Request, params generated.GetAuthDeviceParams) {
+	ctx := r.Context()
+	grant, ok := verifyUserCode(w, r, params.UserCode, false)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// The confirmation must not be framed, or another site could trick the user into clicking it.
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	w.Header().Set("X-Frame-Options", "DENY")
+	err := deviceConfirmationPage.Execute(w, map[string]string{
+		"Provider":        grant.Provider,
+		"UserCode":        grant.UserCode,
+		"DeviceUserAgent": grant.DeviceUserAgent,
+		"DeviceIP":        grant.DeviceIP,
+		"RequestedAt":     grant.ExpiresAt.Add(-services.DeviceGrantLifetime).UTC().Format("2 Jan 2006 15:04 MST"),
+	})
+	if err != nil {
+		slog.Error(ctx, "Failed to render device confirmation", err, map[string]interface{}{
+			"user_code": grant.UserCode,
+		})
+	}
+}
+
+// PostAuthDevice is submitted from the confirmation page and starts the normal provider login for the
+// device grant identified by the user code.
+func (s *Server) PostAuthDevice(w http.ResponseWriter, r *http.Request, params generated.PostAuthDeviceParams) {
+	ctx := r.Context()
+	grant, ok := verifyUserCode(w, r, params.UserCode, true)
+	if !ok {
+		return
+	}
+
+	oauthConfig, exists := config.Providers[grant.Provider]
+	if !exists {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": grant.Provider,
+		})
+		writeProblem(w, r, http.StatusNotFound, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	// The phone is sent to the completion page once the login finishes.
+	redirectURI := deviceCompleteRedirectURI()
+	if err := utils.ValidateRedirectURIFromEnv(redirectURI); err != nil {
+		slog.Error(ctx, "Invalid device completion redirect URI", err, map[string]interface{}{
+			"redirect_uri": redirectURI,
+		})
+		writeProblem(w, r, http.StatusServiceUnavailable, problemDeviceFlowDisabled, "Device authorization is not enabled")
+		return
+	}
+
+	stateToken := uuid.New().String()
+	state := stateToken + "|" + redirectURI
+
+	verifier, err := utils.GenerateCodeVerifier()
+	if err != nil {
+		slog.Error(ctx, "Failed to generate code verifier", err, nil)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while generating code verifier")
+		return
+	}
+	challenge := utils.GenerateCodeChallenge(verifier)
+
+	// Store the PKCE data along with the device code so the callback can approve the device.
+	if err = services.SavePKCEData(stateToken, services.PKCEData{
+		CodeVerifier: verifier,
+		DeviceCode:   grant.DeviceCode,
+	}); err != nil {
+		slog.Error(ctx, "Failed to store PKCE data", err, map[string]interface{}{
+			"state_token": stateToken,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while storing PKCE data")
+		return
+	}
+
+	authURL := providerAuthCodeURL(oauthConfig, state, challenge)
+
+	slog.Info(ctx, "Redirecting device verification to auth provider", map[string]interface{}{
+		"provider": grant.Provider,
+		"auth_url": authURL,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+// verifyUserCode looks up the pending grant for a user code typed or scanned by the user. Confirmations
+// and lookups of unknown codes count against the address and the code, so codes cannot be guessed or a
+// leaked code reused at will, while viewing the confirmation page does not use the code up.
+func verifyUserCode(w http.ResponseWriter, r *http.Request, rawUserCode string, confirming bool) (*services.DeviceGrant, bool) {
+	ctx := r.Context()
+	userCode := services.NormalizeUserCode(rawUserCode)
+	ip := requestIP(r)
+	slog.Info(ctx, "Starting device verification", map[string]interface{}{
+		"user_code":  userCode,
+		"confirming": confirming,
+	})
+
+	var err error
+	if confirming {
+		err = services.RecordVerificationAttempt(ip, userCode)
+	} else {
+		err = services.CheckVerificationAttempts(ip, userCode)
+	}
+	if err != nil {
+		writeVerificationAttemptProblem(w, r, userCode, err)
+		return nil, false
+	}
+
+	grant, err := services.GetDeviceGrantByUserCode(userCode)
+	if err != nil || grant.Status != services.DeviceGrantPending {
+		slog.Error(ctx, "Invalid or expired user code", err, map[string]interface{}{
+			"user_code": userCode,
+		})
+		if !confirming {
+			if err := services.RecordVerificationAttempt(ip, userCode); err != nil {
+				writeVerificationAttemptProblem(w, r, userCode, err)
+				return nil, false
+			}
+		}
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidUserCode, "Invalid or expired user code")
+		return nil, false
+	}
+	return grant, true
+}
+
+// writeVerificationAttemptProblem reports an address or code that has run out of attempts, or a failure
+// to count the attempt.
+func writeVerificationAttemptProblem(w http.ResponseWriter, r *http.Request, userCode string, err error) {
+	if errors.Is(err, services.ErrTooManyVerificationAttempts) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(services.DeviceVerificationWindow.Seconds())))
+		writeProblem(w, r, http.StatusTooManyRequests, problemRateLimited, "Too many attempts to verify a device, retry later")
+		return
+	}
+	slog.Error(r.Context(), "Failed to record device verification attempt", err, map[string]interface{}{
+		"user_code": userCode,
+	})
+	writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while verifying user code")
+}
+
+// PostAuthDeviceToken is polled by the device until the user approves or denies the login. Unfinished
+// grants are reported in the RFC 8628 error format that device libraries expect rather than as problems.
+func (s *Server) PostAuthDeviceToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body generated.PostAuthDeviceTokenJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.DeviceCode == "" {
+		slog.Error(ctx, "Device code is required", fmt.Errorf("missing device code"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Device code is required")
+		return
+	}
+
+	grant, err := services.PollDeviceGrant(body.DeviceCode)
+	if err != nil {
+		var description string
+		switch {
+		case errors.Is(err, services.ErrAuthorizationPending):
+			description = "The user has not completed the login yet"
+		case errors.Is(err, services.ErrSlowDown):
+			description = "Polling too quickly, increase the interval"
+		case errors.Is(err, services.ErrAccessDenied):
+			description = "The user declined the login"
+		case errors.Is(err, services.ErrExpiredToken):
+			description = "The device code has expired"
+		default:
+			slog.Error(ctx, "Failed to poll device grant", err, nil)
+			writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Server error while polling device grant")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":             err.Error(),
+			"error_description": description,
+		})
+		return
+	}
+
+	slog.Info(ctx, "Device authorization completed", map[string]interface{}{
+		"session_id": grant.SessionID,
+		"provider":   grant.Provider,
+		"user_id":    grant.UserID,
+	})
+
+	setSessionCookie(w, grant.SessionID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{
+		"session_id": grant.SessionID,
+		"provider":   grant.Provider,
+		"user_id":    grant.UserID,
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"os"
+)
+
+// internalAPIKeyHeader carries the shared secret that internal backend callers authenticate with.
+const internalAPIKeyHeader = "X-Internal-Api-Key"
+
+// authorizeInternalCaller checks the internal API key and writes an error response when it is
+// missing or wrong. Internal endpoints are disabled entirely while INTERNAL_API_KEY is unset.
+func authorizeInternalCaller(w http.ResponseWriter, r *http.Request) bool {
+	ctx := r.Context()
+
+	expectedKey := os.Getenv("INTERNAL_API_KEY")
+	if expectedKey == "" {
+		slog.Error(ctx, "Internal API key is not configured", fmt.Errorf("INTERNAL_API_KEY is not set"), nil)
+		writeProblem(w, r, http.StatusForbidden, problemInternalAPIDisabled, "Internal API is not enabled")
+		return false
+	}
+
+	apiKey := r.Header.Get(internalAPIKeyHeader)
+	if apiKey == "" {
+		slog.Error(ctx, "Internal API key is required", fmt.Errorf("missing %s header", internalAPIKeyHeader), nil)
+		writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "Internal API key is required")
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) != 1 {
+		slog.Error(ctx, "Invalid internal API key", fmt.Errorf("internal API key mismatch"), nil)
+		writeProblem(w, r, http.StatusUnauthorized, problemUnauthorized, "Invalid internal API key")
+		return false
+	}
+	return true
+}
+
+// hasInternalAPIKey reports whether the request carries the correct internal API key, without
+// rejecting requests that do not.
+func hasInternalAPIKey(r *http.Request) bool {
+	expectedKey := os.Getenv("INTERNAL_API_KEY")
+	apiKey := r.Header.Get(internalAPIKeyHeader)
+	return expectedKey != "" && apiKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedKey)) == 1
+}
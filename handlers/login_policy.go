@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/services"
+	"context"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// checkLoginPolicy evaluates the login policy for the account that just authenticated. A denied login's
+// token is revoked, as it will never be stored, and the denial is recorded in the audit trail.
+func checkLoginPolicy(r *http.Request, provider string, user *models.UserInfo, token *oauth2.Token) config.LoginDecision {
+	ctx := r.Context()
+	decision := config.GetLoginPolicy().Evaluate(config.LoginAttributes{
+		Provider:      provider,
+		UserID:        user.ID,
+		Email:         user.Email,
+		Country:       user.Country,
+		EmailVerified: user.EmailVerified,
+	})
+	if decision.Allowed {
+		return decision
+	}
+
+	slog.Error(ctx, "Login denied by policy", fmt.Errorf("login denied: %s", decision.Reason), map[string]interface{}{
+		"provider": provider,
+		"user_id":  user.ID,
+		"rule":     decision.Rule,
+		"reason":   decision.Reason,
+	})
+	err := services.RecordAuditEvent(ctx, services.AuditEvent{
+		Type:     services.AuditLoginDenied,
+		Provider: provider,
+		UserID:   user.ID,
+		Details: map[string]string{
+			"rule":   decision.Rule,
+			"reason": decision.Reason,
+		},
+	})
+	if err != nil {
+		slog.Error(ctx, "Failed to record login denial", err, map[string]interface{}{
+			"provider": provider,
+			"user_id":  user.ID,
+		})
+	}
+	go services.RevokeToken(context.Background(), provider, token)
+	return decision
+}
+
+// loginDeniedRedirectURI returns the redirect URI carrying a denied login's error: access_denied, with
+// the policy's reason in error_reason.
+func loginDeniedRedirectURI(redirectURI string, decision config.LoginDecision) string {
+	redirectURI = withQueryParam(redirectURI, "error", services.ErrAccessDenied.Error())
+	return withQueryParam(redirectURI, "error_reason", decision.Reason)
+}
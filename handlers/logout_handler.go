@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"auth-service/generated"
+	"auth-service/services"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+)
+
+// GetAuthLogout ends the browser session and redirects, so it can be used as a plain sign-out link.
+// Links followed from other sites are rejected, so no site can sign users out with a navigation.
+func (s *Server) GetAuthLogout(w http.ResponseWriter, r *http.Request, params generated.GetAuthLogoutParams) {
+	if isCrossSiteNavigation(r) {
+		slog.Error(r.Context(), "Rejected cross-site sign-out link", fmt.Errorf("cross-site request"), map[string]interface{}{
+			"referer": r.Header.Get("Referer"),
+		})
+		writeProblem(w, r, http.StatusForbidden, problemCrossOriginRequest, "Sign-out links cannot be followed from other sites, use POST instead")
+		return
+	}
+	logoutSession(w, r, params.RedirectUri, params.ClientId)
+}
+
+// PostAuthLogout ends the browser session and redirects, for sign-out buttons that submit a form.
+func (s *Server) PostAuthLogout(w http.ResponseWriter, r *http.Request, params generated.PostAuthLogoutParams) {
+	logoutSession(w, r, params.RedirectUri, params.ClientId)
+}
+
+// logoutSession logs every account out of the session, expires the session cookie and redirects to the
+// validated redirect URI.
+func logoutSession(w http.ResponseWriter, r *http.Request, redirectURI string, clientIDParam *string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Starting session logout", map[string]interface{}{
+		"redirect_uri": redirectURI,
+	})
+
+	var clientID string
+	if clientIDParam != nil {
+		clientID = *clientIDParam
+	}
+	client, err := lookupClient(clientID)
+	if err != nil {
+		slog.Error(ctx, "Unknown client", err, map[string]interface{}{
+			"client_id": clientID,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnknownClient, "Unknown client")
+		return
+	}
+
+	// The redirect URI is validated before anything is logged out, so a bad link changes nothing.
+	if err := validateClientRedirectURI(client, redirectURI); err != nil {
+		slog.Error(ctx, "Invalid redirect URI", err, map[string]interface{}{
+			"redirect_uri": redirectURI,
+		})
+		writeRedirectURIProblem(w, r, err)
+		return
+	}
+
+	// Without a session there is nothing to log out, but the cookie is still expired and the browser
+	// redirected, so signing out twice is harmless.
+	if sessionCookie, err := r.Cookie("session_id"); err == nil && sessionCookie.Value != "" {
+		sessionID := sessionCookie.Value
+		revocation, err := services.LogoutSession(ctx, sessionID)
+		if err != nil {
+			slog.Error(ctx, "Failed to log out session", err, map[string]interface{}{
+				"session_id": sessionID,
+			})
+			writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to log out session")
+			return
+		}
+		slog.Info(ctx, "Successfully logged out session", map[string]interface{}{
+			"session_id": sessionID,
+			"revocation": revocation,
+		})
+	}
+
+	clearSessionCookie(w, client)
+	http.Redirect(w, r, redirectURI, http.StatusSeeOther)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/generated"
+	"auth-service/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+)
+
+// PostAdminRevocations starts an emergency revocation of every stored token of a provider, or of those
+// issued through one client, e.g. after a client secret leaked. It is only available to internal callers.
+func (s *Server) PostAdminRevocations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !authorizeInternalCaller(w, r) {
+		return
+	}
+
+	var body generated.PostAdminRevocationsJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		slog.Error(ctx, "Invalid mass revocation request body", err, nil)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Invalid request body")
+		return
+	}
+	if !config.IsSupportedProvider(body.Provider) {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": body.Provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+	var clientID string
+	if body.ClientId != nil {
+		clientID = *body.ClientId
+		if _, err := lookupClient(clientID); err != nil {
+			slog.Error(ctx, "Unknown client", err, map[string]interface{}{
+				"client_id": clientID,
+			})
+			writeProblem(w, r, http.StatusBadRequest, problemUnknownClient, "Unknown client")
+			return
+		}
+	}
+
+	job, err := services.StartMassRevocation(ctx, body.Provider, clientID)
+	switch {
+	case errors.Is(err, services.ErrMassRevocationInProgress):
+		writeProblem(w, r, http.StatusConflict, problemRevocationInProgress, "A revocation of these tokens is already in progress")
+		return
+	case err != nil:
+		slog.Error(ctx, "Failed to start mass revocation", err, map[string]interface{}{
+			"provider":  body.Provider,
+			"client_id": clientID,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to start revocation")
+		return
+	}
+
+	writeMassRevocation(w, http.StatusAccepted, job)
+}
+
+// GetAdminRevocationsRevocationId reports the progress of an emergency revocation.
+func (s *Server) GetAdminRevocationsRevocationId(w http.ResponseWriter, r *http.Request, revocationId string) {
+	ctx := r.Context()
+
+	if !authorizeInternalCaller(w, r) {
+		return
+	}
+
+	job, err := services.GetMassRevocation(ctx, revocationId)
+	switch {
+	case errors.Is(err, services.ErrMassRevocationNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemRevocationNotFound, "Revocation not found")
+		return
+	case err != nil:
+		slog.Error(ctx, "Failed to get mass revocation", err, map[string]interface{}{
+			"mass_revocation_id": revocationId,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to get revocation")
+		return
+	}
+
+	writeMassRevocation(w, http.StatusOK, job)
+}
+
+// PostAdminRevocationsRevocationIdResume resumes a failed emergency revocation from its last batch.
+func (s *Server) PostAdminRevocationsRevocationIdResume(w http.ResponseWriter, r *http.Request, revocationId string) {
+	ctx := r.Context()
+
+	if !authorizeInternalCaller(w, r) {
+		return
+	}
+
+	job, err := services.ResumeMassRevocation(ctx, revocationId)
+	switch {
+	case errors.Is(err, services.ErrMassRevocationNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemRevocationNotFound, "Revocation not found")
+		return
+	case errors.Is(err, services.ErrMassRevocationInProgress):
+		writeProblem(w, r, http.StatusConflict, problemRevocationInProgress, "The revocation is still running")
+		return
+	case errors.Is(err, services.ErrMassRevocationCompleted):
+		writeProblem(w, r, http.StatusConflict, problemRevocationCompleted, "The revocation has completed")
+		return
+	case err != nil:
+		slog.Error(ctx, "Failed to resume mass revocation", err, map[string]interface{}{
+			"mass_revocation_id": revocationId,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to resume revocation")
+		return
+	}
+
+	writeMassRevocation(w, http.StatusAccepted, job)
+}
+
+func writeMassRevocation(w http.ResponseWriter, status int, job *services.MassRevocation) {
+	response := generated.MassRevocation{
+		Id:          job.ID,
+		Provider:    job.Provider,
+		Status:      job.Status,
+		Scanned:     job.Scanned,
+		Deleted:     job.Deleted,
+		Revocations: job.Revocations,
+		StartedAt:   job.StartedAt,
+		UpdatedAt:   job.UpdatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if job.ClientID != "" {
+		response.ClientId = &job.ClientID
+	}
+	if job.Error != "" {
+		response.Error = &job.Error
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// checkLoginAllowed refuses logins with the provider while an emergency revocation covers them.
+func checkLoginAllowed(w http.ResponseWriter, r *http.Request, provider, clientID string) bool {
+	suspended, err := services.IsLoginSuspended(r.Context(), provider, clientID)
+	if err != nil {
+		slog.Error(r.Context(), "Failed to check login suspension", err, map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to check login suspension")
+		return false
+	}
+	if suspended {
+		slog.Info(r.Context(), "Login suspended during mass revocation", map[string]interface{}{
+			"provider":  provider,
+			"client_id": clientID,
+		})
+		writeProblem(w, r, http.StatusServiceUnavailable, problemLoginSuspended, "Logins with this provider are temporarily suspended")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/services"
+	"encoding/json"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+)
+
+// GetUsersUserIdTokensProvider returns an access token from the user's offline grant, so backend jobs
+// can call the provider for a user without a browser session. It is only available to internal callers.
+func (s *Server) GetUsersUserIdTokensProvider(w http.ResponseWriter, r *http.Request, userId string, provider string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Getting offline token", map[string]interface{}{
+		"internal_user_id": userId,
+		"provider":         provider,
+	})
+
+	if !authorizeInternalCaller(w, r) {
+		return
+	}
+
+	if !config.IsSupportedProvider(provider) {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	token, refreshStatus, err := services.GetOfflineToken(ctx, userId, provider)
+	if err != nil {
+		status, code, detail := classifyTokenError(err)
+		if status != http.StatusUnauthorized {
+			slog.Error(ctx, detail, err, map[string]interface{}{
+				"internal_user_id": userId,
+				"provider":         provider,
+			})
+		}
+		writeProblem(w, r, status, code, detail)
+		return
+	}
+
+	slog.Info(ctx, "Successfully retrieved offline token", map[string]interface{}{
+		"internal_user_id": userId,
+		"provider":         provider,
+		"user_id":          token.UserID,
+		"refresh_status":   refreshStatus,
+	})
+
+	// The grant's refresh token stays with the service, which refreshes it for every caller; handing it
+	// out would let a job rotate it from under the user's sessions.
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providerTokenResponse(provider, token, refreshStatus, false))
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/generated"
+	"auth-service/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"strings"
+)
+
+// PutAuthProviderPrimary makes one of the session's accounts its primary account for the provider,
+// the account used when token requests omit user_id.
+func (s *Server) PutAuthProviderPrimary(w http.ResponseWriter, r *http.Request, provider string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Setting primary account", map[string]interface{}{
+		"provider": provider,
+	})
+
+	if !config.IsSupportedProvider(provider) {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil || sessionCookie.Value == "" {
+		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
+		return
+	}
+
+	var body generated.PutAuthProviderPrimaryJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		slog.Error(ctx, "Invalid primary account request body", err, map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(body.UserId) == "" {
+		slog.Error(ctx, "User ID is required", fmt.Errorf("missing user ID"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "User ID is required")
+		return
+	}
+
+	err = services.SetPrimaryAccount(ctx, sessionCookie.Value, provider, body.UserId)
+	if errors.Is(err, services.ErrTokenNotFound) {
+		writeProblem(w, r, http.StatusNotFound, problemTokenNotFound, "Token not found")
+		return
+	}
+	if err != nil {
+		slog.Error(ctx, "Failed to set primary account", err, map[string]interface{}{
+			"session_id": sessionCookie.Value,
+			"provider":   provider,
+			"user_id":    body.UserId,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to set primary account")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
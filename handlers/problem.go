@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"auth-service/generated"
+	"encoding/json"
+	"net/http"
+)
+
+// Stable problem codes returned in the "code" member of every error response. Clients branch on
+// these, so existing codes must not change.
+const (
+	problemInvalidRequest       = "invalid_request"
+	problemUnsupportedProvider  = "unsupported_provider"
+	problemUnknownClient        = "unknown_client"
+	problemProviderNotPermitted = "provider_not_permitted"
+	problemInvalidRedirectURI   = "invalid_redirect_uri"
+	problemInvalidState         = "invalid_state"
+	problemLoginExpired         = "login_expired"
+	problemSessionMissing       = "session_missing"
+	problemTokenNotFound        = "token_not_found"
+	problemReauthRequired       = "reauth_required"
+	problemInvalidCredentials   = "invalid_credentials"
+	problemProviderError        = "provider_error"
+	problemUnauthorized         = "unauthorized"
+	problemInternalAPIDisabled  = "internal_api_disabled"
+	problemDeviceFlowDisabled   = "device_flow_disabled"
+	problemInvalidUserCode      = "invalid_user_code"
+	problemRateLimited          = "rate_limited"
+	problemNotRefreshable       = "token_not_refreshable"
+	problemUserNotFound         = "user_not_found"
+	problemIdentityLinked       = "identity_linked"
+	problemIdentityNotFound     = "identity_not_found"
+	problemLastIdentity         = "last_identity"
+	problemCrossOriginRequest   = "cross_origin_request"
+	problemRevocationNotFound   = "revocation_not_found"
+	problemRevocationInProgress = "revocation_in_progress"
+	problemRevocationCompleted  = "revocation_completed"
+	problemLoginSuspended       = "login_suspended"
+	problemSessionLimitReached  = "session_limit_reached"
+	problemLoginDenied          = "login_denied"
+	problemLinkSessionMismatch  = "link_session_mismatch"
+	problemInternalError        = "internal_error"
+)
+
+// writeProblem writes an RFC 7807 application/problem+json error response. The detail is shown to
+// callers, so internal errors belong in the logs rather than here.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	problem := newProblem(r, status, code, detail)
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// newProblem builds the problem for a request, for responses that embed problems rather than being one.
+func newProblem(r *http.Request, status int, code, detail string) generated.Problem {
+	instance := r.URL.Path
+	problem := generated.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Code:     code,
+		Instance: &instance,
+	}
+	if detail != "" {
+		problem.Detail = &detail
+	}
+	return problem
+}
+
+// RequestErrorHandler reports request binding errors from the generated router, such as a missing
+// required query parameter, as problems.
+func RequestErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, err.Error())
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/services"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/monzo/slog"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// providerUserIDHeader selects the account to proxy for instead of the session's primary account.
+const providerUserIDHeader = "X-Provider-User-Id"
+
+// maxProxyRequestBody bounds request bodies, which are buffered so they can be replayed after a refresh.
+const maxProxyRequestBody = 10 << 20
+
+// errProxyReauthRequired is returned by the proxy transport when the provider rejected the token and it
+// could not be refreshed.
+var errProxyReauthRequired = errors.New("provider rejected the token and it could not be refreshed")
+
+// proxyStrippedRequestHeaders are caller headers that must not reach the provider. Hop-by-hop headers
+// are removed by httputil.ReverseProxy.
+var proxyStrippedRequestHeaders = []string{"Authorization", "Cookie", "Origin", "Referer", internalAPIKeyHeader, providerUserIDHeader}
+
+// ProxyProviderAPI forwards /proxy/{provider}/* to the provider's web API with the session's access
+// token injected, so browsers never hold provider tokens. A 401 from the provider triggers one refresh
+// and retry. Retry-After responses hold back further calls to the provider until they expire.
+func (s *Server) ProxyProviderAPI(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	provider := chi.URLParam(r, "provider")
+
+	baseURL, err := config.GetProviderAPIBaseURL(provider)
+	if err != nil || !config.IsSupportedProvider(provider) {
+		slog.Error(ctx, "Unsupported proxy provider", fmt.Errorf("provider API not supported"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+	upstream, err := url.Parse(baseURL)
+	if err != nil {
+		slog.Error(ctx, "Invalid provider API base URL", err, map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Provider API is misconfigured")
+		return
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil || sessionCookie.Value == "" {
+		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
+		writeProblem(w, r, http.StatusUnauthorized, problemSessionMissing, "Session ID is required")
+		return
+	}
+	sessionID := sessionCookie.Value
+
+	if wait, err := services.GetProviderBackoff(ctx, provider); err != nil {
+		slog.Error(ctx, "Failed to check provider backoff", err, map[string]interface{}{
+			"provider": provider,
+		})
+	} else if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeProblem(w, r, http.StatusTooManyRequests, problemRateLimited, "The provider is rate limiting requests, retry later")
+		return
+	}
+
+	// Without an explicit account, the session's primary account is used.
+	var userID *string
+	if header := r.Header.Get(providerUserIDHeader); header != "" {
+		userID = &header
+	}
+	token, _, ok := getValidToken(w, r, provider, userID)
+	if !ok {
+		return
+	}
+
+	// Buffer the body so the request can be replayed after a refresh.
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProxyRequestBody))
+	if err != nil {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, problemInvalidRequest, "Request body is too large")
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = upstream.Scheme
+			pr.Out.URL.Host = upstream.Host
+			pr.Out.URL.Path = proxyUpstreamPath(upstream.Path, chi.URLParam(r, "*"))
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = upstream.Host
+			for _, header := range proxyStrippedRequestHeaders {
+				pr.Out.Header.Del(header)
+			}
+		},
+		Transport: &refreshingTransport{
+			base:      http.DefaultTransport,
+			sessionID: sessionID,
+			provider:  provider,
+			token:     token,
+		},
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+				if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+					if err := services.SetProviderBackoff(resp.Request.Context(), provider, retryAfter); err != nil {
+						slog.Error(resp.Request.Context(), "Failed to store provider backoff", err, map[string]interface{}{
+							"provider": provider,
+						})
+					}
+				}
+			}
+			// The provider's cookies, auth challenges and CORS policy are not ours to pass on.
+			resp.Header.Del("Set-Cookie")
+			resp.Header.Del("WWW-Authenticate")
+			for header := range resp.Header {
+				if strings.HasPrefix(header, "Access-Control-") {
+					resp.Header.Del(header)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, errProxyReauthRequired) {
+				writeProblem(w, r, http.StatusUnauthorized, problemReauthRequired, "The token could not be refreshed, the user must log in again")
+				return
+			}
+			slog.Error(r.Context(), "Failed to proxy provider request", err, map[string]interface{}{
+				"provider": provider,
+			})
+			writeProblem(w, r, http.StatusBadGateway, problemProviderError, "Failed to reach the provider")
+		},
+	}
+
+	slog.Info(ctx, "Proxying provider request", map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    token.UserID,
+		"method":     r.Method,
+		"path":       chi.URLParam(r, "*"),
+	})
+	proxy.ServeHTTP(w, r)
+}
+
+// proxyUpstreamPath joins the proxied path onto the API base path. Cleaning the path first keeps
+// dot segments from escaping the base path.
+func proxyUpstreamPath(basePath, proxied string) string {
+	cleaned := path.Clean("/" + proxied)
+	joined := strings.TrimSuffix(basePath, "/") + cleaned
+	if strings.HasSuffix(proxied, "/") && cleaned != "/" {
+		joined += "/"
+	}
+	return joined
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// refreshingTransport injects the access token and, when the provider answers 401, refreshes the
+// token once and retries the request.
+type refreshingTransport struct {
+	base      http.RoundTripper
+	sessionID string
+	provider  string
+	token     *services.AuthData
+}
+
+func (t *refreshingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(t.authorize(req, req.Body))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	ctx := req.Context()
+	if t.token.Token.RefreshToken == "" {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if err = services.RefreshAuthToken(ctx, t.sessionID, t.provider, t.token); err != nil {
+		if errors.Is(err, services.ErrReauthRequired) {
+			return nil, errProxyReauthRequired
+		}
+		return nil, err
+	}
+
+	var body io.ReadCloser
+	if req.Body != nil && req.GetBody != nil {
+		if body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(t.authorize(req, body))
+}
+
+func (t *refreshingTransport) authorize(req *http.Request, body io.ReadCloser) *http.Request {
+	out := req.Clone(req.Context())
+	out.Body = body
+	out.Header.Set("Authorization", config.GetProviderAuthScheme(t.provider)+" "+t.token.Token.AccessToken)
+	return out
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"net/http"
+)
+
+// setSessionCookie sets the session ID as a secure cookie.
+func setSessionCookie(w http.ResponseWriter, sessionID string) {
+	setClientSessionCookie(w, nil, sessionID)
+}
+
+// setClientSessionCookie sets the session ID as a secure cookie using the client's cookie settings, if any.
+func setClientSessionCookie(w http.ResponseWriter, client *config.ClientConfig, sessionID string) {
+	cookie := &http.Cookie{
+		Name:     "session_id",
+		Value:    sessionID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if client != nil {
+		cookie.Domain = client.Cookie.Domain
+		cookie.SameSite = client.CookieSameSite()
+	}
+	http.SetCookie(w, cookie)
+}
+
+// clearSessionCookie expires the session cookie, using the client's cookie settings, if any, so it
+// matches the cookie that was set.
+func clearSessionCookie(w http.ResponseWriter, client *config.ClientConfig) {
+	cookie := &http.Cookie{
+		Name:     "session_id",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if client != nil {
+		cookie.Domain = client.Cookie.Domain
+		cookie.SameSite = client.CookieSameSite()
+	}
+	http.SetCookie(w, cookie)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/generated"
+	"auth-service/services"
+	"encoding/json"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+)
+
+// DeleteAuthProviderAccountsUserIdSessions signs a provider account out of every session holding its
+// tokens and revokes them, for example after the account was compromised. It is only available to
+// internal callers.
+func (s *Server) DeleteAuthProviderAccountsUserIdSessions(w http.ResponseWriter, r *http.Request, provider string, userId string) {
+	ctx := r.Context()
+	slog.Info(ctx, "Signing out account from all sessions", map[string]interface{}{
+		"provider": provider,
+		"user_id":  userId,
+	})
+
+	if !authorizeInternalCaller(w, r) {
+		return
+	}
+
+	if !config.IsSupportedProvider(provider) {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+
+	result, err := services.SignOutAccount(ctx, provider, userId)
+	if err != nil {
+		slog.Error(ctx, "Failed to sign out account", err, map[string]interface{}{
+			"provider": provider,
+			"user_id":  userId,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to sign out account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(generated.AccountSignOut{
+		Sessions:   result.Sessions,
+		Revocation: result.Revocation,
+	})
+}
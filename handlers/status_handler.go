@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"auth-service/generated"
 	"auth-service/services"
 	"encoding/json"
 	"fmt"
 	"github.com/monzo/slog"
 	"net/http"
+	"sort"
 )
 
+// GetAuthStatus lists the accounts linked to the session with their token health.
 func (s *Server) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	slog.Info(ctx, "Getting auth status", nil)
@@ -16,7 +19,7 @@ func (s *Server) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 	sessionCookie, err := r.Cookie("session_id")
 	if err != nil || sessionCookie.Value == "" {
 		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
-		http.Error(w, "Session ID is required", http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, problemSessionMissing, "Session ID is required")
 		return
 	}
 
@@ -25,7 +28,7 @@ func (s *Server) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 		slog.Error(ctx, "Unable to get logged in providers", err, map[string]interface{}{
 			"session_id": sessionCookie.Value,
 		})
-		http.Error(w, "Unable to get logged in providers", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Unable to get logged in providers")
 		return
 	}
 
@@ -35,5 +38,50 @@ func (s *Server) GetAuthStatus(w http.ResponseWriter, r *http.Request) {
 	})
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(connectedProviders)
+	json.NewEncoder(w).Encode(linkedAccountsResponse(connectedProviders))
+}
+
+// linkedAccountsResponse converts the session's accounts to the status response, sorted by provider and
+// user ID.
+func linkedAccountsResponse(connectedProviders []services.LoggedInProvider) []generated.LinkedAccount {
+	sort.Slice(connectedProviders, func(i, j int) bool {
+		if connectedProviders[i].Provider != connectedProviders[j].Provider {
+			return connectedProviders[i].Provider < connectedProviders[j].Provider
+		}
+		return connectedProviders[i].UserID < connectedProviders[j].UserID
+	})
+
+	accounts := make([]generated.LinkedAccount, 0, len(connectedProviders))
+	for _, account := range connectedProviders {
+		linkedAccount := generated.LinkedAccount{
+			Provider:        account.Provider,
+			UserId:          account.UserID,
+			DisplayName:     account.DisplayName,
+			Email:           account.Email,
+			LoggedIn:        account.LoggedIn,
+			LinkedAt:        account.LinkedAt,
+			LastRefreshedAt: account.LastRefreshedAt,
+			ExpiresAt:       account.ExpiresAt,
+			Health:          account.Health,
+			Primary:         account.Primary,
+		}
+		if len(account.Scopes) > 0 {
+			scopes := account.Scopes
+			linkedAccount.Scopes = &scopes
+		}
+		if account.Profile != nil {
+			profile := generated.ProfileValidation{Valid: account.Profile.Valid}
+			if len(account.Profile.Missing) > 0 {
+				missing := account.Profile.Missing
+				profile.Missing = &missing
+			}
+			if len(account.Profile.Fallbacks) > 0 {
+				fallbacks := account.Profile.Fallbacks
+				profile.Fallbacks = &fallbacks
+			}
+			linkedAccount.Profile = &profile
+		}
+		accounts = append(accounts, linkedAccount)
+	}
+	return accounts
 }
@@ -3,16 +3,19 @@ package handlers
 import (
 	"auth-service/config"
 	"auth-service/generated"
-	"auth-service/models"
 	"auth-service/services"
-	"auth-service/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/monzo/slog"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 )
 
+// GetAuthProviderToken is the deprecated v1 token endpoint. Its expires_in is the absolute expiry as a
+// Unix timestamp, which is kept for existing callers; new callers use GetV2AuthProviderToken.
 func (s *Server) GetAuthProviderToken(w http.ResponseWriter, r *http.Request, provider string, params generated.GetAuthProviderTokenParams) {
 	ctx := r.Context()
 	slog.Info(ctx, "Getting auth provider token", map[string]interface{}{
@@ -20,107 +23,298 @@ func (s *Server) GetAuthProviderToken(w http.ResponseWriter, r *http.Request, pr
 		"user_id":  params.UserId,
 	})
 
-	_, exists := config.Providers[provider]
-	if !exists {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", fmt.Sprintf("</v2/auth/%s/token>; rel=\"successor-version\"", provider))
+
+	token, _, ok := getValidToken(w, r, provider, params.UserId)
+	if !ok {
+		return
+	}
+
+	// Return the token
+	response := map[string]interface{}{
+		"access_token": token.Token.AccessToken,
+		"expires_in":   token.Token.Expiry.Unix(),
+	}
+	if canReceiveRefreshToken(r, token) {
+		response["refresh_token"] = token.Token.RefreshToken
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetV2AuthProviderToken returns the token with its real expiry, token type, granted scopes and refresh status.
+func (s *Server) GetV2AuthProviderToken(w http.ResponseWriter, r *http.Request, provider string, params generated.GetV2AuthProviderTokenParams) {
+	ctx := r.Context()
+	slog.Info(ctx, "Getting auth provider token", map[string]interface{}{
+		"provider": provider,
+		"user_id":  params.UserId,
+		"version":  2,
+	})
+
+	token, refreshStatus, ok := getValidToken(w, r, provider, params.UserId)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(providerTokenResponse(provider, token, refreshStatus, canReceiveRefreshToken(r, token)))
+}
+
+// PostAuthProviderRefresh refreshes the token immediately, for callers whose token the provider
+// rejected before its expiry, and returns the new token.
+func (s *Server) PostAuthProviderRefresh(w http.ResponseWriter, r *http.Request, provider string, params generated.PostAuthProviderRefreshParams) {
+	ctx := r.Context()
+	slog.Info(ctx, "Forcing token refresh", map[string]interface{}{
+		"provider": provider,
+		"user_id":  params.UserId,
+	})
+
+	sessionID, userID, ok := validateTokenRequest(w, r, provider, params.UserId)
+	if !ok {
+		return
+	}
+
+	token, err := services.ForceRefreshAuthToken(ctx, sessionID, provider, userID)
+	if err != nil {
+		writeTokenError(w, r, err, sessionID, provider, userID)
+		return
+	}
+
+	slog.Info(ctx, "Successfully forced token refresh", map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    userID,
+		"expires_at": token.Token.Expiry,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(providerTokenResponse(provider, token, services.RefreshStatusRefreshed, canReceiveRefreshToken(r, token)))
+}
+
+// GetAuthTokens returns valid tokens for every account linked to the session, refreshing expired
+// tokens concurrently. Accounts whose token cannot be returned carry a problem instead of failing the
+// whole response.
+func (s *Server) GetAuthTokens(w http.ResponseWriter, r *http.Request, params generated.GetAuthTokensParams) {
+	ctx := r.Context()
+	slog.Info(ctx, "Getting tokens for all linked accounts", map[string]interface{}{
+		"provider": params.Provider,
+	})
+
+	if params.Provider != nil && !config.IsSupportedProvider(*params.Provider) {
 		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
-			"provider": provider,
+			"provider": *params.Provider,
 		})
-		http.Error(w, "Unsupported provider", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
 		return
 	}
 
 	sessionCookie, err := r.Cookie("session_id")
 	if err != nil || sessionCookie.Value == "" {
 		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
-		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusUnauthorized, problemSessionMissing, "Session ID is required")
 		return
 	}
+	sessionID := sessionCookie.Value
 
-	if params.UserId == "" {
-		slog.Error(ctx, "User ID is required", fmt.Errorf("missing user ID"), nil)
-		http.Error(w, "User ID is required", http.StatusBadRequest)
+	linkedAccounts, err := services.GetLoggedInProviders(sessionID)
+	if err != nil {
+		slog.Error(ctx, "Unable to get logged in providers", err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Unable to get logged in providers")
 		return
 	}
-	userID := params.UserId
 
-	// Retrieve the token
-	token, found := services.GetAuthToken(sessionCookie.Value, provider, userID)
-	if !found {
-		slog.Error(ctx, "Token not found", fmt.Errorf("token not found"), map[string]interface{}{
-			"session_id": sessionCookie.Value,
-			"provider":   provider,
-			"user_id":    userID,
-		})
-		http.Error(w, "Token not found", http.StatusNotFound)
-		return
+	accounts := []generated.AccountToken{}
+	for _, account := range linkedAccounts {
+		if params.Provider == nil || account.Provider == *params.Provider {
+			accounts = append(accounts, generated.AccountToken{Provider: account.Provider, ProviderUserId: account.UserID})
+		}
 	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if accounts[i].Provider != accounts[j].Provider {
+			return accounts[i].Provider < accounts[j].Provider
+		}
+		return accounts[i].ProviderUserId < accounts[j].ProviderUserId
+	})
 
-	// Check if the token is expired
-	if token.Token.Expiry.Before(time.Now()) {
-		slog.Info(ctx, "Token expired, refreshing", map[string]interface{}{
-			"session_id": sessionCookie.Value,
-			"provider":   provider,
-			"user_id":    userID,
-			"expired_at": token.Token.Expiry,
-		})
+	// Each goroutine only writes its own entry.
+	var wg sync.WaitGroup
+	for i := range accounts {
+		wg.Add(1)
+		go func(account *generated.AccountToken) {
+			defer wg.Done()
+			token, refreshStatus, err := services.GetValidAuthToken(ctx, sessionID, account.Provider, account.ProviderUserId)
+			if err != nil {
+				status, code, detail := classifyTokenError(err)
+				slog.Error(ctx, "Failed to get token for linked account", err, map[string]interface{}{
+					"session_id": sessionID,
+					"provider":   account.Provider,
+					"user_id":    account.ProviderUserId,
+				})
+				problem := newProblem(r, status, code, detail)
+				account.Error = &problem
+				return
+			}
+			response := providerTokenResponse(account.Provider, token, refreshStatus, canReceiveRefreshToken(r, token))
+			account.Token = &response
+		}(&accounts[i])
+	}
+	wg.Wait()
 
-		// Refresh the token
-		oauthConfig := config.Providers[provider]
-		newToken, err := utils.RefreshAccessTokenFunc(oauthConfig, token.Token.RefreshToken)
-		if err != nil {
-			slog.Error(ctx, "Failed to refresh token", err, map[string]interface{}{
-				"session_id": sessionCookie.Value,
-				"provider":   provider,
-				"user_id":    userID,
-			})
-			http.Error(w, "Failed to refresh token: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+	slog.Info(ctx, "Successfully retrieved tokens for linked accounts", map[string]interface{}{
+		"session_id": sessionID,
+		"accounts":   len(accounts),
+	})
 
-		// Update the token in storage
-		// Extract user info from `token`
-		userInfo := &services.UserInfo{
-			ID:          token.UserID,
-			DisplayName: token.DisplayName,
-			Email:       token.Email,
-		}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(generated.AccountTokens{Accounts: accounts})
+}
 
-		// Update the token in storage
-		err = services.StoreAuthToken(sessionCookie.Value, provider, (*models.UserInfo)(userInfo), newToken)
-		if err != nil {
-			slog.Error(ctx, "Failed to store refreshed token", err, map[string]interface{}{
-				"session_id": sessionCookie.Value,
-				"provider":   provider,
-				"user_id":    userID,
-			})
-			http.Error(w, "Failed to store refreshed token", http.StatusInternalServerError)
-			return
+// canReceiveRefreshToken reports whether the caller is trusted with the token's refresh token. Only
+// internal callers and sessions of backend clients are; browser callers, recognised by their Origin
+// header, never are, so refreshing stays inside the service.
+func canReceiveRefreshToken(r *http.Request, token *services.AuthData) bool {
+	if hasInternalAPIKey(r) {
+		return true
+	}
+	if r.Header.Get("Origin") != "" || token.ClientID == "" {
+		return false
+	}
+	client, exists := config.GetClient(token.ClientID)
+	return exists && client.ReceivesRefreshTokens()
+}
+
+// providerTokenResponse converts a stored token to the v2 token response. The refresh token is only
+// included when includeRefreshToken is set.
+func providerTokenResponse(provider string, token *services.AuthData, refreshStatus string, includeRefreshToken bool) generated.ProviderToken {
+	response := generated.ProviderToken{
+		AccessToken:    token.Token.AccessToken,
+		TokenType:      token.Token.Type(),
+		Provider:       provider,
+		ProviderUserId: token.UserID,
+		RefreshStatus:  refreshStatus,
+	}
+	if !token.Token.Expiry.IsZero() {
+		expiresAt := token.Token.Expiry.UTC().Truncate(time.Second)
+		expiresIn := int(time.Until(token.Token.Expiry).Seconds())
+		if expiresIn < 0 {
+			expiresIn = 0
 		}
+		response.ExpiresAt = &expiresAt
+		response.ExpiresIn = &expiresIn
+	}
+	if len(token.Scopes) > 0 {
+		scopes := token.Scopes
+		response.Scope = &scopes
+	}
+	if includeRefreshToken && token.Token.RefreshToken != "" {
+		refreshToken := token.Token.RefreshToken
+		response.RefreshToken = &refreshToken
+	}
+	return response
+}
 
-		slog.Info(ctx, "Successfully refreshed token", map[string]interface{}{
-			"session_id": sessionCookie.Value,
-			"provider":   provider,
-			"user_id":    userID,
-			"expires_at": newToken.Expiry,
-		})
+// getValidToken validates the request and returns the caller's token, refreshing it if it has expired.
+// Without a user ID, the session's primary account for the provider is used. It writes the error
+// response and returns false when the token cannot be returned.
+func getValidToken(w http.ResponseWriter, r *http.Request, provider string, userID *string) (*services.AuthData, string, bool) {
+	ctx := r.Context()
 
-		token.Token = newToken
+	sessionID, resolvedUserID, ok := validateTokenRequest(w, r, provider, userID)
+	if !ok {
+		return nil, "", false
 	}
 
-	// Return the token
-	response := map[string]interface{}{
-		"access_token":  token.Token.AccessToken,
-		"refresh_token": token.Token.RefreshToken,
-		"expires_in":    token.Token.Expiry.Unix(),
+	token, refreshStatus, err := services.GetValidAuthToken(ctx, sessionID, provider, resolvedUserID)
+	if err != nil {
+		writeTokenError(w, r, err, sessionID, provider, resolvedUserID)
+		return nil, "", false
 	}
 
 	slog.Info(ctx, "Successfully retrieved token", map[string]interface{}{
-		"session_id": sessionCookie.Value,
-		"provider":   provider,
-		"user_id":    userID,
-		"expires_at": token.Token.Expiry,
+		"session_id":     sessionID,
+		"provider":       provider,
+		"user_id":        resolvedUserID,
+		"expires_at":     token.Token.Expiry,
+		"refresh_status": refreshStatus,
 	})
+	return token, refreshStatus, true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+// validateTokenRequest checks the provider and session cookie of a token request and returns the
+// session ID and the user ID, defaulting to the session's primary account for the provider. It writes
+// the error response and returns false when the request is invalid.
+func validateTokenRequest(w http.ResponseWriter, r *http.Request, provider string, userID *string) (string, string, bool) {
+	ctx := r.Context()
+
+	if !config.IsSupportedProvider(provider) {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return "", "", false
+	}
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil || sessionCookie.Value == "" {
+		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
+		return "", "", false
+	}
+
+	if userID != nil && *userID != "" {
+		return sessionCookie.Value, *userID, true
+	}
+	primaryUserID, err := services.GetPrimaryAccount(sessionCookie.Value, provider)
+	if errors.Is(err, services.ErrTokenNotFound) {
+		writeTokenError(w, r, err, sessionCookie.Value, provider, "")
+		return "", "", false
+	}
+	if err != nil {
+		slog.Error(ctx, "Unable to get primary account", err, map[string]interface{}{
+			"session_id": sessionCookie.Value,
+			"provider":   provider,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Unable to get primary account")
+		return "", "", false
+	}
+	return sessionCookie.Value, primaryUserID, true
+}
+
+// writeTokenError writes the problem for an error from retrieving or refreshing a stored token.
+func writeTokenError(w http.ResponseWriter, r *http.Request, err error, sessionID, provider, userID string) {
+	status, code, detail := classifyTokenError(err)
+	if status != http.StatusUnauthorized {
+		slog.Error(r.Context(), detail, err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+	}
+	writeProblem(w, r, status, code, detail)
+}
+
+// classifyTokenError maps an error from retrieving or refreshing a stored token to its problem status,
+// code and detail.
+func classifyTokenError(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, services.ErrTokenNotFound):
+		return http.StatusNotFound, problemTokenNotFound, "Token not found"
+	case errors.Is(err, services.ErrReauthRequired):
+		return http.StatusUnauthorized, problemReauthRequired, "The token could not be refreshed, the user must log in again"
+	case errors.Is(err, services.ErrNotRefreshable):
+		return http.StatusConflict, problemNotRefreshable, "The token has no refresh token"
+	case errors.Is(err, services.ErrRefreshFailed):
+		return http.StatusBadGateway, problemProviderError, "The provider failed to refresh the token, try again later"
+	case errors.Is(err, services.ErrRefreshTimeout):
+		return http.StatusServiceUnavailable, problemProviderError, "Timed out waiting for the token to be refreshed, try again later"
+	default:
+		return http.StatusInternalServerError, problemInternalError, "Failed to get token"
+	}
 }
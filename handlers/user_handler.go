@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"auth-service/config"
+	"auth-service/generated"
+	"auth-service/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"strings"
+)
+
+// GetUsersMe returns the internal user the session belongs to, with its linked identities.
+func (s *Server) GetUsersMe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil || sessionCookie.Value == "" {
+		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
+		return
+	}
+
+	user, err := services.GetSessionUser(ctx, sessionCookie.Value)
+	if errors.Is(err, services.ErrUserNotFound) {
+		writeProblem(w, r, http.StatusNotFound, problemUserNotFound, "The session does not belong to a user")
+		return
+	}
+	if err != nil {
+		slog.Error(ctx, "Failed to get session user", err, map[string]interface{}{
+			"session_id": sessionCookie.Value,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to get user")
+		return
+	}
+
+	writeUser(w, user)
+}
+
+// PostUsersMeIdentities links an account logged in to the session to the session's user.
+func (s *Server) PostUsersMeIdentities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil || sessionCookie.Value == "" {
+		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
+		return
+	}
+
+	var body generated.PostUsersMeIdentitiesJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		slog.Error(ctx, "Invalid link identity request body", err, nil)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "Invalid request body")
+		return
+	}
+	if !config.IsSupportedProvider(body.Provider) {
+		slog.Error(ctx, "Unsupported provider", fmt.Errorf("provider not found"), map[string]interface{}{
+			"provider": body.Provider,
+		})
+		writeProblem(w, r, http.StatusBadRequest, problemUnsupportedProvider, "Unsupported provider")
+		return
+	}
+	if strings.TrimSpace(body.UserId) == "" {
+		slog.Error(ctx, "User ID is required", fmt.Errorf("missing user ID"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemInvalidRequest, "User ID is required")
+		return
+	}
+
+	user, err := services.LinkIdentity(ctx, sessionCookie.Value, body.Provider, body.UserId)
+	switch {
+	case errors.Is(err, services.ErrTokenNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemTokenNotFound, "Token not found")
+		return
+	case errors.Is(err, services.ErrIdentityLinked):
+		writeProblem(w, r, http.StatusConflict, problemIdentityLinked, "The account is linked to another user")
+		return
+	case err != nil:
+		slog.Error(ctx, "Failed to link identity", err, map[string]interface{}{
+			"session_id": sessionCookie.Value,
+			"provider":   body.Provider,
+			"user_id":    body.UserId,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to link identity")
+		return
+	}
+
+	writeUser(w, user)
+}
+
+// DeleteUsersMeIdentitiesProviderUserId unlinks an identity from the session's user.
+func (s *Server) DeleteUsersMeIdentitiesProviderUserId(w http.ResponseWriter, r *http.Request, provider string, userId string) {
+	ctx := r.Context()
+
+	sessionCookie, err := r.Cookie("session_id")
+	if err != nil || sessionCookie.Value == "" {
+		slog.Error(ctx, "Session ID is required", fmt.Errorf("missing session cookie"), nil)
+		writeProblem(w, r, http.StatusBadRequest, problemSessionMissing, "Session ID is required")
+		return
+	}
+
+	user, err := services.UnlinkIdentity(ctx, sessionCookie.Value, provider, userId)
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemUserNotFound, "The session does not belong to a user")
+		return
+	case errors.Is(err, services.ErrIdentityNotFound):
+		writeProblem(w, r, http.StatusNotFound, problemIdentityNotFound, "The account is not linked to the user")
+		return
+	case errors.Is(err, services.ErrLastIdentity):
+		writeProblem(w, r, http.StatusConflict, problemLastIdentity, "The user's only identity cannot be unlinked")
+		return
+	case err != nil:
+		slog.Error(ctx, "Failed to unlink identity", err, map[string]interface{}{
+			"session_id": sessionCookie.Value,
+			"provider":   provider,
+			"user_id":    userId,
+		})
+		writeProblem(w, r, http.StatusInternalServerError, problemInternalError, "Failed to unlink identity")
+		return
+	}
+
+	writeUser(w, user)
+}
+
+func writeUser(w http.ResponseWriter, user *services.User) {
+	identities := make([]generated.UserIdentity, 0, len(user.Identities))
+	for _, identity := range user.Identities {
+		identities = append(identities, generated.UserIdentity{
+			DisplayName: identity.DisplayName,
+			Email:       identity.Email,
+			LinkedAt:    identity.LinkedAt,
+			Provider:    identity.Provider,
+			UserId:      identity.UserID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(generated.User{
+		CreatedAt:  user.CreatedAt,
+		Id:         user.ID,
+		Identities: identities,
+	})
+}
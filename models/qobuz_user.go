@@ -0,0 +1,36 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// QobuzLoginResponse models the JSON returned by Qobuz's user/login endpoint.
+type QobuzLoginResponse struct {
+	UserAuthToken string `json:"user_auth_token"`
+	User          struct {
+		ID          int64  `json:"id"`
+		Login       string `json:"login"`
+		DisplayName string `json:"display_name"`
+		Email       string `json:"email"`
+	} `json:"user"`
+}
+
+// ToUserInfo converts the QobuzLoginResponse to a unified UserInfo. A missing display name or email is
+// left for profile validation to report.
+func (q *QobuzLoginResponse) ToUserInfo() (*UserInfo, error) {
+	user := q.User
+	if user.ID == 0 {
+		return nil, errors.New("user ID is missing in Qobuz response")
+	}
+	displayName := strings.TrimSpace(user.DisplayName)
+	if displayName == "" {
+		displayName = strings.TrimSpace(user.Login)
+	}
+	return &UserInfo{
+		ID:          strconv.FormatInt(user.ID, 10),
+		DisplayName: displayName,
+		Email:       normalizeEmail(user.Email),
+	}, nil
+}
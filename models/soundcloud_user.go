@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// SoundCloudUserResponse models the JSON returned by SoundCloud's /me endpoint.
+// SoundCloud identifies users by a numeric ID and never exposes an email address.
+type SoundCloudUserResponse struct {
+	ID       int64  `json:"id"`
+	URN      string `json:"urn"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
+
+// ToUserInfo converts the SoundCloudUserResponse to a unified UserInfo.
+func (s *SoundCloudUserResponse) ToUserInfo() (*UserInfo, error) {
+	if s.ID == 0 {
+		return nil, errors.New("user ID is missing in SoundCloud response")
+	}
+	if strings.TrimSpace(s.Username) == "" {
+		return nil, errors.New("username is missing in SoundCloud response")
+	}
+	return &UserInfo{
+		ID:          strconv.FormatInt(s.ID, 10),
+		DisplayName: s.Username,
+	}, nil
+}
@@ -10,22 +10,19 @@ type SpotifyUserResponse struct {
 	ID          string `json:"id"`
 	DisplayName string `json:"display_name"`
 	Email       string `json:"email"`
+	Country     string `json:"country"`
 }
 
-// ToUserInfo converts the SpotifyUserResponse to a unified UserInfo.
+// ToUserInfo converts the SpotifyUserResponse to a unified UserInfo. Spotify omits the display name of
+// some accounts, and the email unless the user-read-email scope was granted.
 func (s *SpotifyUserResponse) ToUserInfo() (*UserInfo, error) {
 	if strings.TrimSpace(s.ID) == "" {
 		return nil, errors.New("user ID is missing in Spotify response")
 	}
-	if strings.TrimSpace(s.DisplayName) == "" {
-		return nil, errors.New("display name is missing in Spotify response")
-	}
-	if strings.TrimSpace(s.Email) == "" || !validateEmail(s.Email) {
-		return nil, errors.New("invalid or missing email in Spotify response")
-	}
 	return &UserInfo{
 		ID:          s.ID,
-		DisplayName: s.DisplayName,
-		Email:       s.Email,
+		DisplayName: strings.TrimSpace(s.DisplayName),
+		Email:       normalizeEmail(s.Email),
+		Country:     s.Country,
 	}, nil
 }
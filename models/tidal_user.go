@@ -18,21 +18,19 @@ type TidalUserResponse struct {
 	} `json:"data"`
 }
 
-// ToUserInfo converts the TidalUserResponse to a unified UserInfo.
+// ToUserInfo converts the TidalUserResponse to a unified UserInfo. Missing profile fields are left
+// empty for profile validation to report.
 func (t *TidalUserResponse) ToUserInfo() (*UserInfo, error) {
 	data := t.Data
 	if strings.TrimSpace(data.ID) == "" {
 		return nil, errors.New("user ID is missing in Tidal response")
 	}
-	if strings.TrimSpace(data.Attributes.Username) == "" {
-		return nil, errors.New("username is missing in Tidal response")
-	}
-	if strings.TrimSpace(data.Attributes.Email) == "" || !validateEmail(data.Attributes.Email) {
-		return nil, errors.New("invalid or missing email in Tidal response")
-	}
+	emailVerified := data.Attributes.EmailVerified
 	return &UserInfo{
-		ID:          data.ID,
-		DisplayName: data.Attributes.Username,
-		Email:       data.Attributes.Email,
+		ID:            data.ID,
+		DisplayName:   strings.TrimSpace(data.Attributes.Username),
+		Email:         normalizeEmail(data.Attributes.Email),
+		Country:       data.Attributes.Country,
+		EmailVerified: &emailVerified,
 	}, nil
 }
@@ -2,12 +2,29 @@ package models
 
 import (
 	"net/mail"
+	"strings"
 )
 
 type UserInfo struct {
 	ID          string
 	DisplayName string
 	Email       string
+	// Country is the account's ISO 3166-1 alpha-2 country, if the provider reports it.
+	Country string
+	// EmailVerified is nil when the provider does not report whether the email is verified.
+	EmailVerified *bool
+	// Validation reports how the profile met the provider's profile requirements, if it was validated.
+	Validation *ProfileValidation
+}
+
+// ProfileValidation is the outcome of checking a profile against the provider's required fields.
+type ProfileValidation struct {
+	// Valid is set when the profile has every required field.
+	Valid bool `json:"valid"`
+	// Missing lists the required fields the provider did not return.
+	Missing []string `json:"missing,omitempty"`
+	// Fallbacks lists the fields filled in from other fields, such as the display name from the user ID.
+	Fallbacks []string `json:"fallbacks,omitempty"`
 }
 
 // ProviderResponse defines the behavior required to convert a provider's response to a UserInfo.
@@ -20,3 +37,12 @@ func validateEmail(email string) bool {
 	_, err := mail.ParseAddress(email)
 	return err == nil
 }
+
+// normalizeEmail returns the trimmed email, or an empty string if it is not a valid address.
+func normalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if email == "" || !validateEmail(email) {
+		return ""
+	}
+	return email
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"auth-service/config"
+	"net/http"
+
+	"github.com/go-chi/cors"
+)
+
+// corsHandler applies the CORS policy from config. Origins are looked up on every request, so
+// changes to the client registry take effect without a restart. Only explicitly listed origins
+// may make credentialed requests; origins matched by a wildcard pattern are served without credentials.
+func corsHandler() func(http.Handler) http.Handler {
+	policy := config.GetCORSConfig()
+	options := func(allowOrigin func(origin string) bool, allowCredentials bool) cors.Options {
+		return cors.Options{
+			AllowOriginFunc: func(r *http.Request, origin string) bool {
+				return allowOrigin(origin)
+			},
+			AllowedMethods:   policy.AllowedMethods,
+			AllowedHeaders:   policy.AllowedHeaders,
+			AllowCredentials: allowCredentials,
+			MaxAge:           policy.MaxAge,
+		}
+	}
+	credentialed := cors.New(options(func(origin string) bool {
+		return config.GetCORSConfig().IsCredentialedOrigin(origin)
+	}, true))
+	anonymous := cors.New(options(func(origin string) bool {
+		return config.GetCORSConfig().MatchesOriginPattern(origin)
+	}, false))
+
+	return func(next http.Handler) http.Handler {
+		credentialedNext := credentialed.Handler(next)
+		anonymousNext := anonymous.Handler(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.GetCORSConfig().IsCredentialedOrigin(r.Header.Get("Origin")) {
+				credentialedNext.ServeHTTP(w, r)
+				return
+			}
+			anonymousNext.ServeHTTP(w, r)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"auth-service/generated"
 	"auth-service/handlers"
 	"auth-service/redisclient"
+	"auth-service/services"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -13,9 +14,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
@@ -46,25 +47,27 @@ func InitializeServer() http.Handler {
 	redisclient.InitializeRedis(redisAddr)
 
 	config.InitConfig()
+	go config.WatchClientRegistry(clientRegistryReloadInterval())
+	go services.WatchRevocationRetries(revocationRetryInterval)
 
 	// Setup Router
 	r := chi.NewRouter()
 
 	// CORS Middleware
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	r.Use(corsHandler())
 
 	// Swagger setup
 	setupSwagger(r)
 
 	// Register Handlers
 	server := &handlers.Server{}
-	r.Mount("/", generated.HandlerFromMux(server, r))
+	// The proxy is mounted outside the generated router, so it is wrapped in the CSRF check itself.
+	r.Handle("/proxy/{provider}/*", handlers.CSRFProtection(http.HandlerFunc(server.ProxyProviderAPI)))
+	r.Mount("/", generated.HandlerWithOptions(server, generated.ChiServerOptions{
+		BaseRouter:       r,
+		Middlewares:      []generated.MiddlewareFunc{handlers.CSRFProtection},
+		ErrorHandlerFunc: handlers.RequestErrorHandler,
+	}))
 
 	log.Println("Server started successfully")
 	slog.Info(context.Background(), "Server started successfully", nil)
@@ -72,6 +75,18 @@ func InitializeServer() http.Handler {
 	return r
 }
 
+// revocationRetryInterval is how often queued provider token revocations are checked for retries.
+const revocationRetryInterval = 10 * time.Second
+
+// clientRegistryReloadInterval is how often the client registry file is checked for changes.
+func clientRegistryReloadInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("CLIENT_REGISTRY_RELOAD_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 30 * time.Second
+	}
+	return interval
+}
+
 func setupSwagger(r *chi.Mux) {
 	r.Get("/swagger/doc.json", func(w http.ResponseWriter, r *http.Request) {
 		swagger, err := generated.GetSwagger()
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"auth-service/config"
+	"time"
+)
+
+// Account health values reported by the status endpoint.
+const (
+	// AccountHealthOK means the token is usable and will be refreshed as needed.
+	AccountHealthOK = "ok"
+	// AccountHealthExpiring means the access token expires within accountExpiringWindow, or has expired
+	// and will be refreshed on next use.
+	AccountHealthExpiring = "expiring"
+	// AccountHealthNeedsReauth means the token can no longer be used or refreshed; the user must log in again.
+	AccountHealthNeedsReauth = "needs_reauth"
+	// AccountHealthNeedsUpgrade means the provider granted fewer scopes than we now request; the user must
+	// log in again to grant them.
+	AccountHealthNeedsUpgrade = "needs_upgrade"
+)
+
+// accountExpiringWindow is how long before expiry an account is reported as expiring.
+const accountExpiringWindow = 5 * time.Minute
+
+// Health returns the account health of the stored token, one of the AccountHealth values.
+func (a *AuthData) Health(provider string) string {
+	expired := !a.Token.Expiry.IsZero() && a.Token.Expiry.Before(time.Now())
+	switch {
+	case a.ReauthRequired || (expired && a.Token.RefreshToken == ""):
+		return AccountHealthNeedsReauth
+	case len(a.missingScopes(provider)) > 0:
+		return AccountHealthNeedsUpgrade
+	case !a.Token.Expiry.IsZero() && time.Until(a.Token.Expiry) < accountExpiringWindow:
+		return AccountHealthExpiring
+	default:
+		return AccountHealthOK
+	}
+}
+
+// missingScopes returns the scopes we request from the provider, for the account's client if it has
+// one, that the provider did not grant. Providers that do not report granted scopes never miss any.
+func (a *AuthData) missingScopes(provider string) []string {
+	oauthConfig, exists := config.Providers[provider]
+	if !exists || len(a.Scopes) == 0 {
+		return nil
+	}
+	requested := oauthConfig.Scopes
+	if client, exists := config.GetClient(a.ClientID); exists {
+		requested = client.ScopesFor(provider, requested)
+	}
+
+	granted := make(map[string]bool, len(a.Scopes))
+	for _, scope := range a.Scopes {
+		granted[scope] = true
+	}
+	var missing []string
+	for _, scope := range requested {
+		if !granted[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"github.com/monzo/slog"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// constructAccountSessionsKey holds the IDs of the sessions the provider account is logged in to, scored
+// by when it logged in to each. It may still list sessions whose token has since expired.
+func constructAccountSessionsKey(provider, providerUserID string) string {
+	return "account_sessions:" + provider + ":" + providerUserID
+}
+
+// SignOutResult reports what a global sign-out of a provider account removed.
+type SignOutResult struct {
+	// Sessions is how many sessions the account was logged out of.
+	Sessions int
+	// Revocation is the least complete revocation of the account's tokens, one of the Revocation values.
+	Revocation string
+}
+
+// SignOutAccount logs the provider account out of every session holding its token, for example after
+// the account was compromised. Linked users keep the identity but lose its stored token and offline
+// grant, so the account must log in again, and every token found is revoked at the provider.
+func SignOutAccount(ctx context.Context, provider, providerUserID string) (*SignOutResult, error) {
+	logParams := map[string]interface{}{
+		"provider": provider,
+		"user_id":  providerUserID,
+	}
+	sessionIDs, err := redisclient.Client.ZRange(ctx, constructAccountSessionsKey(provider, providerUserID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SignOutResult{Revocation: revocationWithoutToken(provider)}
+	var tokens []*oauth2.Token
+	for _, sessionID := range sessionIDs {
+		authData, found := GetAuthToken(sessionID, provider, providerUserID)
+		if !found {
+			continue // The token has expired since it was indexed.
+		}
+		if err = DeleteAuthToken(sessionID, provider, providerUserID); err != nil {
+			return nil, err
+		}
+		if _, err = EndEmptySession(ctx, sessionID); err != nil {
+			slog.Error(ctx, "Failed to end empty session", err, map[string]interface{}{
+				"session_id": sessionID,
+			})
+		}
+		tokens = append(tokens, authData.Token)
+		result.Sessions++
+	}
+
+	// The identity token and offline grant would otherwise restore the account into the user's sessions.
+	ownerID, err := getIdentityOwner(ctx, provider, providerUserID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != "" {
+		identityToken, found, err := getIdentityToken(ctx, provider, providerUserID)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			tokens = append(tokens, identityToken.Token)
+		}
+		if err = revokeOfflineGrant(ctx, ownerID, provider, providerUserID); err != nil {
+			return nil, err
+		}
+		if err = redisclient.Client.Del(ctx, constructIdentityTokenKey(provider, providerUserID)).Err(); err != nil {
+			return nil, err
+		}
+	}
+	if err = redisclient.Client.Del(ctx, constructAccountSessionsKey(provider, providerUserID)).Err(); err != nil {
+		return nil, err
+	}
+
+	// Sessions share tokens through the identity token, so each distinct token is revoked once.
+	revoked := map[string]bool{}
+	for _, token := range tokens {
+		value := tokenValue(token)
+		if revoked[value] {
+			continue
+		}
+		revoked[value] = true
+		result.Revocation = leastCompleteRevocation(result.Revocation, RevokeToken(ctx, provider, token))
+	}
+
+	logParams["sessions"] = result.Sessions
+	logParams["revocation"] = result.Revocation
+	slog.Info(ctx, "Signed out account from all sessions", logParams)
+	return result, nil
+}
+
+// indexAccountSession records that the account is logged in to the session. The index is kept as long
+// as the longest-lived token that can be indexed in it.
+func indexAccountSession(ctx context.Context, sessionID, provider string, authData *AuthData) error {
+	key := constructAccountSessionsKey(provider, authData.UserID)
+	_, err := redisclient.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(authData.LinkedAt.UnixMilli()), Member: sessionID})
+		pipe.Expire(ctx, key, refreshableAuthDataTTL)
+		return nil
+	})
+	return err
+}
+
+func unindexAccountSession(ctx context.Context, sessionID, provider, providerUserID string) error {
+	return redisclient.Client.ZRem(ctx, constructAccountSessionsKey(provider, providerUserID), sessionID).Err()
+}
+
+// activeAccountSessions returns the sessions the account is logged in to, oldest login first, pruning
+// sessions whose token has expired from the index.
+func activeAccountSessions(ctx context.Context, provider, providerUserID string) ([]string, error) {
+	sessionIDs, err := redisclient.Client.ZRange(ctx, constructAccountSessionsKey(provider, providerUserID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	var active []string
+	for _, sessionID := range sessionIDs {
+		exists, err := redisclient.Client.Exists(ctx, constructRedisKey(sessionID, provider, providerUserID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			if err = unindexAccountSession(ctx, sessionID, provider, providerUserID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		active = append(active, sessionID)
+	}
+	return active, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/redisclient"
+	"auth-service/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// appTokenExpiryMargin is how long before expiry a cached app token stops being handed out.
+	appTokenExpiryMargin = time.Minute
+	// appTokenLockTTL bounds how long one replica may spend fetching a token for everyone else.
+	appTokenLockTTL = 10 * time.Second
+	// appTokenPollInterval is how often waiting replicas check for the token being fetched.
+	appTokenPollInterval = 100 * time.Millisecond
+)
+
+func constructAppTokenKey(provider string) string {
+	return "app_token:" + provider
+}
+
+func constructAppTokenLockKey(provider string) string {
+	return "app_token_lock:" + provider
+}
+
+// GetAppToken returns a client-credentials app token for the provider. Tokens are cached in Redis until
+// shortly before they expire, and concurrent fetches across replicas are de-duplicated with a Redis lock.
+func GetAppToken(ctx context.Context, provider string) (*oauth2.Token, error) {
+	oauthConfig, exists := config.Providers[provider]
+	if !exists {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	deadline := time.Now().Add(appTokenLockTTL)
+	for {
+		token, err := getCachedAppToken(ctx, provider)
+		if err != nil {
+			return nil, err
+		}
+		if token != nil {
+			return token, nil
+		}
+
+		release, acquired, err := acquireLock(ctx, constructAppTokenLockKey(provider), appTokenLockTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire app token lock: %w", err)
+		}
+		if acquired {
+			defer release()
+			return fetchAndCacheAppToken(ctx, provider, oauthConfig)
+		}
+
+		// Another replica is fetching the token; wait for it to land in the cache.
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for app token")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(appTokenPollInterval):
+		}
+	}
+}
+
+// invalidateAppToken drops the provider's cached app token, so the next caller fetches a new one with the
+// current client credentials.
+func invalidateAppToken(ctx context.Context, provider string) error {
+	return redisclient.Client.Del(ctx, constructAppTokenKey(provider)).Err()
+}
+
+func getCachedAppToken(ctx context.Context, provider string) (*oauth2.Token, error) {
+	tokenJSON, err := redisclient.Client.Get(ctx, constructAppTokenKey(provider)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve app token from Redis: %w", err)
+	}
+
+	var token oauth2.Token
+	if err = json.Unmarshal([]byte(tokenJSON), &token); err != nil {
+		return nil, fmt.Errorf("failed to deserialize app token: %w", err)
+	}
+	return &token, nil
+}
+
+func fetchAndCacheAppToken(ctx context.Context, provider string, oauthConfig *oauth2.Config) (*oauth2.Token, error) {
+	// The token may have been cached while we were acquiring the lock.
+	if token, err := getCachedAppToken(ctx, provider); err != nil || token != nil {
+		return token, err
+	}
+
+	token, err := utils.FetchClientCredentialsTokenFunc(oauthConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Until(token.Expiry) - appTokenExpiryMargin
+	if token.Expiry.IsZero() || ttl <= 0 {
+		// Nothing worth caching; hand the token straight back.
+		return token, nil
+	}
+
+	tokenJSON, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize app token: %w", err)
+	}
+	if err = redisclient.Client.Set(ctx, constructAppTokenKey(provider), tokenJSON, ttl).Err(); err != nil {
+		// The token is still usable; the next caller will simply fetch a new one.
+		log.Printf("Failed to cache app token in Redis: %v", err)
+		slog.Error(ctx, "Failed to cache app token in Redis", err, map[string]interface{}{
+			"provider": provider,
+		})
+		return token, nil
+	}
+
+	slog.Info(ctx, "Cached app token in Redis", map[string]interface{}{
+		"provider":   provider,
+		"expires_at": token.Expiry,
+	})
+	return token, nil
+}
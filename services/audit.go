@@ -0,0 +1,76 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"encoding/json"
+	"github.com/monzo/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Audit event types.
+const (
+	// AuditSessionEvicted records an account logged out of its oldest session by the session limit.
+	AuditSessionEvicted = "session_evicted"
+	// AuditLoginDenied records a login the login policy denied.
+	AuditLoginDenied = "login_denied"
+)
+
+const (
+	// auditLogKey is a Redis stream of audit events, newest last.
+	auditLogKey = "audit_log"
+	// auditLogMaxLen bounds the audit stream; older events are trimmed.
+	auditLogMaxLen = 100000
+)
+
+// AuditEvent is a security-relevant change recorded in the audit trail.
+type AuditEvent struct {
+	Type      string            `json:"type"`
+	Provider  string            `json:"provider,omitempty"`
+	UserID    string            `json:"user_id,omitempty"`
+	SessionID string            `json:"session_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	At        time.Time         `json:"at"`
+}
+
+// RecordAuditEvent appends the event to the audit trail and logs it.
+func RecordAuditEvent(ctx context.Context, event AuditEvent) error {
+	event.At = time.Now().UTC()
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	slog.Info(ctx, "Audit event", map[string]interface{}{
+		"type":       event.Type,
+		"provider":   event.Provider,
+		"user_id":    event.UserID,
+		"session_id": event.SessionID,
+		"details":    event.Details,
+	})
+	return redisclient.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: auditLogKey,
+		MaxLen: auditLogMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"event": eventJSON},
+	}).Err()
+}
+
+// GetAuditEvents returns up to count of the most recent audit events, newest first.
+func GetAuditEvents(ctx context.Context, count int64) ([]AuditEvent, error) {
+	messages, err := redisclient.Client.XRevRangeN(ctx, auditLogKey, "+", "-", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]AuditEvent, 0, len(messages))
+	for _, message := range messages {
+		eventJSON, _ := message.Values["event"].(string)
+		var event AuditEvent
+		if err = json.Unmarshal([]byte(eventJSON), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
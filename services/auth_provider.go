@@ -7,13 +7,14 @@ import (
 
 	"auth-service/config"
 	"auth-service/models"
+	"auth-service/utils"
 
 	"github.com/go-resty/resty/v2"
 	"golang.org/x/oauth2"
 )
 
 func getClient() *resty.Client {
-	return resty.New()
+	return resty.New().SetTimeout(utils.ProviderRequestTimeout)
 }
 
 // makeAuthenticatedRequest sends an authenticated GET request using the provided OAuth token.
@@ -21,13 +22,13 @@ func getClient() *resty.Client {
 func makeAuthenticatedRequest[T any, P interface {
 	*T
 	models.ProviderResponse
-}](ctx context.Context, url string, token *oauth2.Token) (P, error) {
+}](ctx context.Context, url, authScheme string, token *oauth2.Token) (P, error) {
 	var result T
 	client := getClient()
 
 	resp, err := client.R().
 		SetContext(ctx).
-		SetHeader("Authorization", "Bearer "+token.AccessToken).
+		SetHeader("Authorization", authScheme+" "+token.AccessToken).
 		SetResult(&result).
 		Get(url)
 	if err != nil {
@@ -40,35 +41,49 @@ func makeAuthenticatedRequest[T any, P interface {
 }
 
 // GetUserInfo retrieves the user info from the given provider by delegating to
-// the appropriate models conversion logic.
+// the appropriate models conversion logic, and validates it against the provider's profile requirements.
 func GetUserInfo(ctx context.Context, provider string, token *oauth2.Token) (*models.UserInfo, error) {
 	url, err := config.GetProviderUserInfoURL(provider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider user info URL: %w", err)
 	}
 
+	authScheme := config.GetProviderAuthScheme(provider)
+
 	var response models.ProviderResponse
 
 	switch provider {
 	case "spotify":
-		spotifyUser, err := makeAuthenticatedRequest[models.SpotifyUserResponse, *models.SpotifyUserResponse](ctx, url, token)
+		spotifyUser, err := makeAuthenticatedRequest[models.SpotifyUserResponse, *models.SpotifyUserResponse](ctx, url, authScheme, token)
 		if err != nil {
 			return nil, err
 		}
 		response = spotifyUser
 
 	case "tidal":
-		tidalUser, err := makeAuthenticatedRequest[models.TidalUserResponse, *models.TidalUserResponse](ctx, url, token)
+		tidalUser, err := makeAuthenticatedRequest[models.TidalUserResponse, *models.TidalUserResponse](ctx, url, authScheme, token)
 		if err != nil {
 			return nil, err
 		}
 		response = tidalUser
 
+	case "soundcloud":
+		soundCloudUser, err := makeAuthenticatedRequest[models.SoundCloudUserResponse, *models.SoundCloudUserResponse](ctx, url, authScheme, token)
+		if err != nil {
+			return nil, err
+		}
+		response = soundCloudUser
+
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 
-	return response.ToUserInfo()
+	user, err := response.ToUserInfo()
+	if err != nil {
+		return nil, err
+	}
+	ValidateProfile(provider, user, token)
+	return user, nil
 }
 
 // UserInfo is our normalized user info structure
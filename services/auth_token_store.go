@@ -19,24 +19,53 @@ func constructRedisKey(sessionID, provider, userID string) string {
 	return fmt.Sprintf("session:%s_%s_%s", sessionID, provider, userID)
 }
 
+// refreshableAuthDataTTL is how long a refreshable token is kept after it was last stored. Such tokens
+// outlive their access token, which is refreshed on demand, but abandoned sessions still expire.
+const refreshableAuthDataTTL = 30 * 24 * time.Hour
+
 // AuthData represents stored auth info in Redis
 type AuthData struct {
 	Token       *oauth2.Token `json:"token"`
 	UserID      string        `json:"user_id"`
 	DisplayName string        `json:"display_name"`
 	Email       string        `json:"email"`
+	// Scopes are the scopes the provider granted, from the token response's scope field.
+	Scopes []string `json:"scopes,omitempty"`
+	// ClientID is the registered client the user logged in through, if any.
+	ClientID string `json:"client_id,omitempty"`
+	// LinkedAt is when the user logged in with the account.
+	LinkedAt time.Time `json:"linked_at"`
+	// LastRefreshedAt is when the token was last refreshed, if ever.
+	LastRefreshedAt *time.Time `json:"last_refreshed_at,omitempty"`
+	// ReauthRequired is set once the provider has rejected the refresh token.
+	ReauthRequired bool `json:"reauth_required,omitempty"`
+	// Profile is the outcome of validating the user's profile at login, if it was validated.
+	Profile *models.ProfileValidation `json:"profile,omitempty"`
 }
 
 // StoreAuthToken stores OAuth token and user info in Redis
 func StoreAuthToken(sessionID, provider string, userInfo *models.UserInfo, token *oauth2.Token) error {
-	key := constructRedisKey(sessionID, provider, userInfo.ID)
+	return StoreClientAuthToken(sessionID, provider, "", userInfo, token)
+}
 
-	authData := AuthData{
+// StoreClientAuthToken stores OAuth token and user info in Redis, recording the client the user
+// logged in through so its token policy applies to later requests.
+func StoreClientAuthToken(sessionID, provider, clientID string, userInfo *models.UserInfo, token *oauth2.Token) error {
+	return saveAuthData(sessionID, provider, &AuthData{
 		Token:       token,
 		UserID:      userInfo.ID,
 		DisplayName: userInfo.DisplayName,
 		Email:       userInfo.Email,
-	}
+		Scopes:      GrantedScopes(token),
+		ClientID:    clientID,
+		LinkedAt:    time.Now().UTC(),
+		Profile:     userInfo.Validation,
+	})
+}
+
+// saveAuthData stores the auth data in Redis, replacing any existing data for the account.
+func saveAuthData(sessionID, provider string, authData *AuthData) error {
+	key := constructRedisKey(sessionID, provider, authData.UserID)
 
 	// Serialize auth data into JSON
 	authDataJSON, err := json.Marshal(authData)
@@ -45,32 +74,65 @@ func StoreAuthToken(sessionID, provider string, userInfo *models.UserInfo, token
 		slog.Error(context.Background(), "Failed to serialize auth data", err, map[string]interface{}{
 			"session_id": sessionID,
 			"provider":   provider,
-			"user_id":    userInfo.ID,
+			"user_id":    authData.UserID,
 		})
 		return err
 	}
 
-	// Store in Redis with expiration based on token expiry
-	err = redisclient.Client.Set(context.Background(), key, authDataJSON, time.Until(token.Expiry)).Err()
+	err = redisclient.Client.Set(context.Background(), key, authDataJSON, authDataTTL(authData.Token)).Err()
 	if err != nil {
 		log.Printf("Failed to store auth data in Redis: %v", err)
 		slog.Error(context.Background(), "Failed to store auth data in Redis", err, map[string]interface{}{
 			"session_id": sessionID,
 			"provider":   provider,
-			"user_id":    userInfo.ID,
+			"user_id":    authData.UserID,
 		})
 		return err
 	}
 
-	log.Printf("Stored auth data in Redis for session %s, provider %s, user %s", sessionID, provider, userInfo.ID)
+	// The account's sessions are indexed so it can be signed out of all of them at once.
+	if err = indexAccountSession(context.Background(), sessionID, provider, authData); err != nil {
+		slog.Error(context.Background(), "Failed to index account session", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    authData.UserID,
+		})
+	}
+
+	// Linked accounts keep a durable copy of their token, to restore into the user's later sessions.
+	if err = syncIdentityToken(context.Background(), provider, authData); err != nil {
+		slog.Error(context.Background(), "Failed to store identity token", err, map[string]interface{}{
+			"provider": provider,
+			"user_id":  authData.UserID,
+		})
+	}
+
+	log.Printf("Stored auth data in Redis for session %s, provider %s, user %s", sessionID, provider, authData.UserID)
 	slog.Info(context.Background(), "Stored auth data in Redis", map[string]interface{}{
 		"session_id": sessionID,
 		"provider":   provider,
-		"user_id":    userInfo.ID,
+		"user_id":    authData.UserID,
 	})
 	return nil
 }
 
+// authDataTTL returns how long to keep a token in Redis. Refreshable tokens are kept past their access
+// token's expiry so they can be refreshed, and tokens that never expire, such as credential provider
+// tokens, are kept as long so abandoned sessions still expire. Others expire with the access token, or
+// are kept as long as refreshable tokens once expired, so they are reported as needing a new login.
+func authDataTTL(token *oauth2.Token) time.Duration {
+	if ttl := time.Until(token.Expiry); token.RefreshToken == "" && !token.Expiry.IsZero() && ttl > 0 {
+		return ttl
+	}
+	return refreshableAuthDataTTL
+}
+
+// GrantedScopes returns the scopes from the token response's space-separated scope field.
+func GrantedScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.Fields(scope)
+}
+
 // GetAuthToken retrieves OAuth token and user info from Redis
 func GetAuthToken(sessionID, provider, userID string) (*AuthData, bool) {
 	key := constructRedisKey(sessionID, provider, userID)
@@ -103,15 +165,50 @@ func GetAuthToken(sessionID, provider, userID string) (*AuthData, bool) {
 	return &authData, true
 }
 
+// GetAccountClientID returns the registered client the provider's account logged in through, or "" when
+// it logged in without one. Without a user ID, the first of the provider's accounts in the session is used.
+func GetAccountClientID(sessionID, provider, userID string) string {
+	if userID != "" {
+		if authData, exists := GetAuthToken(sessionID, provider, userID); exists {
+			return authData.ClientID
+		}
+		return ""
+	}
+	pattern := fmt.Sprintf("session:%s_%s_*", sessionID, provider)
+	keys, err := redisclient.Client.Keys(context.Background(), pattern).Result()
+	if err != nil {
+		slog.Error(context.Background(), "Failed to fetch keys from Redis", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+		})
+		return ""
+	}
+	keyPrefix := fmt.Sprintf("session:%s_%s_", sessionID, provider)
+	for _, key := range keys {
+		if authData, exists := GetAuthToken(sessionID, provider, strings.TrimPrefix(key, keyPrefix)); exists && authData.ClientID != "" {
+			return authData.ClientID
+		}
+	}
+	return ""
+}
+
 type LoggedInProvider struct {
-	Provider    string `json:"provider"`
-	UserID      string `json:"user_id"`
-	DisplayName string `json:"display_name"`
-	Email       string `json:"email"`
-	LoggedIn    bool   `json:"logged_in"`
+	Provider        string     `json:"provider"`
+	UserID          string     `json:"user_id"`
+	DisplayName     string     `json:"display_name"`
+	Email           string     `json:"email"`
+	LoggedIn        bool       `json:"logged_in"`
+	LinkedAt        *time.Time `json:"linked_at,omitempty"`
+	LastRefreshedAt *time.Time `json:"last_refreshed_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	Scopes          []string   `json:"scopes,omitempty"`
+	Health          string     `json:"health,omitempty"`
+	Primary         bool       `json:"primary"`
+	// Profile is the outcome of validating the account's profile at login, if it was validated.
+	Profile *models.ProfileValidation `json:"profile,omitempty"`
 }
 
-// GetLoggedInProviders returns all logged-in providers with user details
+// GetLoggedInProviders returns all logged-in providers with user details, marking each provider's primary account
 func GetLoggedInProviders(sessionID string) ([]LoggedInProvider, error) {
 	// Pattern to search for all providers under the session
 	pattern := fmt.Sprintf("session:%s_*", sessionID)
@@ -144,16 +241,34 @@ func GetLoggedInProviders(sessionID string) ([]LoggedInProvider, error) {
 		var authData AuthData
 		if err := json.Unmarshal([]byte(authDataJSON), &authData); err == nil {
 			// Append user info to the list
-			loggedInProviders = append(loggedInProviders, LoggedInProvider{
-				Provider:    provider,
-				UserID:      authData.UserID,
-				DisplayName: authData.DisplayName,
-				Email:       authData.Email,
-				LoggedIn:    true,
-			})
+			loggedInProvider := LoggedInProvider{
+				Provider:        provider,
+				UserID:          authData.UserID,
+				DisplayName:     authData.DisplayName,
+				Email:           authData.Email,
+				LoggedIn:        true,
+				LastRefreshedAt: authData.LastRefreshedAt,
+				Scopes:          authData.Scopes,
+				Health:          authData.Health(provider),
+				Profile:         authData.Profile,
+			}
+			if !authData.LinkedAt.IsZero() {
+				loggedInProvider.LinkedAt = &authData.LinkedAt
+			}
+			if !authData.Token.Expiry.IsZero() {
+				loggedInProvider.ExpiresAt = &authData.Token.Expiry
+			}
+			loggedInProviders = append(loggedInProviders, loggedInProvider)
 		}
 	}
 
+	if err = markPrimaryAccounts(context.Background(), sessionID, loggedInProviders); err != nil {
+		log.Printf("Failed to fetch primary accounts from Redis: %v", err)
+		slog.Error(context.Background(), "Failed to fetch primary accounts from Redis", err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+		return nil, err
+	}
 	return loggedInProviders, nil
 }
 
@@ -172,6 +287,22 @@ func DeleteAuthToken(sessionID, provider, userID string) error {
 		return err
 	}
 
+	if err = unindexAccountSession(context.Background(), sessionID, provider, userID); err != nil {
+		slog.Error(context.Background(), "Failed to unindex account session", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+			"user_id":    userID,
+		})
+	}
+
+	if err = reassignPrimaryAccount(context.Background(), sessionID, provider, userID); err != nil {
+		// The earliest remaining account is used as primary until the reassignment succeeds.
+		slog.Error(context.Background(), "Failed to reassign primary account", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+		})
+	}
+
 	log.Printf("Token deleted from Redis for session %s, provider %s, user %s", sessionID, provider, userID)
 	slog.Info(context.Background(), "Token deleted from Redis", map[string]interface{}{
 		"session_id": sessionID,
@@ -207,6 +338,25 @@ func DeleteAllAuthTokensForProvider(sessionID, provider string) error {
 		return err
 	}
 
+	keyPrefix := fmt.Sprintf("session:%s_%s_", sessionID, provider)
+	for _, key := range keys {
+		userID := strings.TrimPrefix(key, keyPrefix)
+		if err = unindexAccountSession(context.Background(), sessionID, provider, userID); err != nil {
+			slog.Error(context.Background(), "Failed to unindex account session", err, map[string]interface{}{
+				"session_id": sessionID,
+				"provider":   provider,
+				"user_id":    userID,
+			})
+		}
+	}
+
+	if err = redisclient.Client.HDel(context.Background(), constructPrimaryAccountKey(sessionID), provider).Err(); err != nil {
+		slog.Error(context.Background(), "Failed to clear primary account", err, map[string]interface{}{
+			"session_id": sessionID,
+			"provider":   provider,
+		})
+	}
+
 	log.Printf("Successfully deleted all tokens for provider %s", provider)
 	slog.Info(context.Background(), "Successfully deleted all tokens for provider", map[string]interface{}{
 		"provider": provider,
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"auth-service/config"
+	"auth-service/models"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidCredentials is returned when a credential provider rejects the username or password.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ExchangeCredentials logs in to a credential provider and returns the normalized user info and
+// the provider's user auth token. The password is only forwarded to the provider and never stored.
+func ExchangeCredentials(ctx context.Context, provider, username, password string) (*models.UserInfo, *oauth2.Token, error) {
+	providerConfig, exists := config.CredentialProviders[provider]
+	if !exists {
+		return nil, nil, fmt.Errorf("unsupported credential provider: %s", provider)
+	}
+
+	switch provider {
+	case "qobuz":
+		return exchangeQobuzCredentials(ctx, providerConfig, username, password)
+	default:
+		return nil, nil, fmt.Errorf("unsupported credential provider: %s", provider)
+	}
+}
+
+// exchangeQobuzCredentials calls Qobuz's user/login endpoint, which expects the MD5 hash of the password.
+func exchangeQobuzCredentials(ctx context.Context, providerConfig *config.CredentialProviderConfig, username, password string) (*models.UserInfo, *oauth2.Token, error) {
+	passwordHash := md5.Sum([]byte(password))
+
+	var result models.QobuzLoginResponse
+	resp, err := getClient().R().
+		SetContext(ctx).
+		SetHeader("X-App-Id", providerConfig.AppID).
+		SetFormData(map[string]string{
+			"username": username,
+			"password": hex.EncodeToString(passwordHash[:]),
+			"app_id":   providerConfig.AppID,
+		}).
+		SetResult(&result).
+		Post(providerConfig.LoginURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	switch resp.StatusCode() {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusBadRequest:
+		return nil, nil, ErrInvalidCredentials
+	default:
+		return nil, nil, fmt.Errorf("provider returned non-OK status: %d", resp.StatusCode())
+	}
+
+	if strings.TrimSpace(result.UserAuthToken) == "" {
+		return nil, nil, errors.New("user auth token is missing in Qobuz response")
+	}
+	userInfo, err := result.ToUserInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Qobuz user auth tokens do not expire and cannot be refreshed, so the token has no expiry.
+	token := &oauth2.Token{
+		AccessToken: result.UserAuthToken,
+		TokenType:   "X-User-Auth-Token",
+	}
+	ValidateProfile("qobuz", userInfo, token)
+	return userInfo, token, nil
+}
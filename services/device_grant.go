@@ -0,0 +1,446 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DeviceGrantLifetime is how long the user has to approve a device before its codes expire.
+	DeviceGrantLifetime = 10 * time.Minute
+	// DeviceGrantPollInterval is the minimum number of seconds a device must wait between polls.
+	DeviceGrantPollInterval = 5
+	// deviceGrantSlowDownStep is added to the poll interval every time a device polls too quickly.
+	deviceGrantSlowDownStep = 5
+
+	// userCodeAlphabet avoids vowels and look-alike characters so codes are easy to type and never spell words.
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+
+	// DeviceVerificationWindow is how long verification attempts are counted for.
+	DeviceVerificationWindow = 10 * time.Minute
+	// deviceVerificationIPLimit caps the user codes one address can try in the window, so codes cannot
+	// be guessed by walking the code space.
+	deviceVerificationIPLimit = 20
+	// deviceVerificationCodeLimit caps how often one user code can be opened, so a code that leaks from
+	// the device's screen cannot be used to start logins over and over.
+	deviceVerificationCodeLimit = 5
+)
+
+// Device grant statuses.
+const (
+	DeviceGrantPending  = "pending"
+	DeviceGrantApproved = "approved"
+	DeviceGrantDenied   = "denied"
+)
+
+// Device token polling errors, named after the RFC 8628 error codes.
+var (
+	ErrAuthorizationPending = errors.New("authorization_pending")
+	ErrSlowDown             = errors.New("slow_down")
+	ErrAccessDenied         = errors.New("access_denied")
+	ErrExpiredToken         = errors.New("expired_token")
+)
+
+// ErrDeviceGrantSettled is returned when approving or denying a grant that is no longer pending.
+var ErrDeviceGrantSettled = errors.New("device grant is no longer pending")
+
+// ErrTooManyVerificationAttempts is returned when an address or user code has been tried too often.
+var ErrTooManyVerificationAttempts = errors.New("too many device verification attempts")
+
+// DeviceGrant represents a pending device authorization in Redis.
+type DeviceGrant struct {
+	DeviceCode string `json:"device_code"`
+	UserCode   string `json:"user_code"`
+	Provider   string `json:"provider"`
+	Status     string `json:"status"`
+	// Interval is the poll interval the device starts with; slowing down is tracked in devicePollState.
+	Interval  int       `json:"interval"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SessionID string    `json:"session_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	// DeviceIP and DeviceUserAgent describe the device that requested the grant, so the user can check
+	// it is theirs before logging in.
+	DeviceIP        string `json:"device_ip,omitempty"`
+	DeviceUserAgent string `json:"device_user_agent,omitempty"`
+}
+
+// devicePollState is the device's polling bookkeeping. It is kept apart from the grant so a poll never
+// writes the grant, and cannot undo an approval or denial that lands while it runs.
+type devicePollState struct {
+	Interval     int       `json:"interval"`
+	LastPolledAt time.Time `json:"last_polled_at"`
+}
+
+func constructDeviceCodeKey(deviceCode string) string {
+	return "device_code:" + deviceCode
+}
+
+func constructDevicePollKey(deviceCode string) string {
+	return "device_poll:" + deviceCode
+}
+
+func constructUserCodeKey(userCode string) string {
+	return "device_user_code:" + userCode
+}
+
+func constructVerificationIPAttemptsKey(ip string) string {
+	return "device_verify_ip:" + ip
+}
+
+func constructVerificationCodeAttemptsKey(userCode string) string {
+	return "device_verify_code:" + userCode
+}
+
+// CreateDeviceGrant starts a device authorization for the provider and returns the new grant. The
+// device's address and user agent are shown to the user when they verify the code.
+func CreateDeviceGrant(provider, deviceIP, deviceUserAgent string) (*DeviceGrant, error) {
+	deviceCode, err := generateDeviceCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user code: %w", err)
+	}
+
+	grant := &DeviceGrant{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		Provider:   provider,
+		Status:     DeviceGrantPending,
+		Interval:   DeviceGrantPollInterval,
+		ExpiresAt:  time.Now().Add(DeviceGrantLifetime),
+
+		DeviceIP:        deviceIP,
+		DeviceUserAgent: deviceUserAgent,
+	}
+	if err = saveDeviceGrant(grant, DeviceGrantLifetime); err != nil {
+		return nil, err
+	}
+
+	// Only claim the user code if no other pending grant is using it.
+	claimed, err := redisclient.Client.SetNX(context.Background(), constructUserCodeKey(userCode), deviceCode, DeviceGrantLifetime).Result()
+	if err != nil {
+		log.Printf("Failed to store device user code in Redis: %v", err)
+		slog.Error(context.Background(), "Failed to store device user code in Redis", err, map[string]interface{}{
+			"provider": provider,
+		})
+		return nil, err
+	}
+	if !claimed {
+		_ = redisclient.Client.Del(context.Background(), constructDeviceCodeKey(deviceCode)).Err()
+		return nil, errors.New("user code collision, please retry")
+	}
+
+	slog.Info(context.Background(), "Created device grant", map[string]interface{}{
+		"provider":  provider,
+		"user_code": userCode,
+	})
+	return grant, nil
+}
+
+// CheckVerificationAttempts returns ErrTooManyVerificationAttempts when the address or the user code has
+// used up its attempts, without counting an attempt. It is checked before every lookup, so a blocked
+// address cannot tell valid codes from invalid ones.
+func CheckVerificationAttempts(ip, userCode string) error {
+	ctx := context.Background()
+	attempts, err := redisclient.Client.MGet(ctx, constructVerificationIPAttemptsKey(ip), constructVerificationCodeAttemptsKey(userCode)).Result()
+	if err != nil {
+		log.Printf("Failed to read device verification attempts from Redis: %v", err)
+		slog.Error(ctx, "Failed to read device verification attempts from Redis", err, map[string]interface{}{
+			"user_code": userCode,
+		})
+		return err
+	}
+	if attemptCount(attempts[0]) >= deviceVerificationIPLimit || attemptCount(attempts[1]) >= deviceVerificationCodeLimit {
+		return ErrTooManyVerificationAttempts
+	}
+	return nil
+}
+
+// RecordVerificationAttempt counts an attempt against the address and the user code. Only confirmations
+// and lookups of unknown codes are counted, so viewing the confirmation page cannot use up the code.
+// It returns ErrTooManyVerificationAttempts once either has been tried too often.
+func RecordVerificationAttempt(ip, userCode string) error {
+	ctx := context.Background()
+	pipe := redisclient.Client.TxPipeline()
+	ipAttempts := pipe.Incr(ctx, constructVerificationIPAttemptsKey(ip))
+	pipe.ExpireNX(ctx, constructVerificationIPAttemptsKey(ip), DeviceVerificationWindow)
+	codeAttempts := pipe.Incr(ctx, constructVerificationCodeAttemptsKey(userCode))
+	pipe.ExpireNX(ctx, constructVerificationCodeAttemptsKey(userCode), DeviceVerificationWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Failed to record device verification attempt in Redis: %v", err)
+		slog.Error(ctx, "Failed to record device verification attempt in Redis", err, map[string]interface{}{
+			"user_code": userCode,
+		})
+		return err
+	}
+	if ipAttempts.Val() > deviceVerificationIPLimit || codeAttempts.Val() > deviceVerificationCodeLimit {
+		slog.Warn(ctx, "Too many device verification attempts", map[string]interface{}{
+			"ip":            ip,
+			"user_code":     userCode,
+			"ip_attempts":   ipAttempts.Val(),
+			"code_attempts": codeAttempts.Val(),
+		})
+		return ErrTooManyVerificationAttempts
+	}
+	return nil
+}
+
+// attemptCount parses an attempt counter read with MGET, where a missing counter is nil.
+func attemptCount(value interface{}) int {
+	count, _ := strconv.Atoi(fmt.Sprint(value))
+	return count
+}
+
+// GetDeviceGrantByUserCode looks up a pending device grant by the code the user typed in.
+func GetDeviceGrantByUserCode(userCode string) (*DeviceGrant, error) {
+	deviceCode, err := redisclient.Client.Get(context.Background(), constructUserCodeKey(userCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return getDeviceGrant(deviceCode)
+}
+
+// ApproveDeviceGrant records the session created by the user's login so the device can collect it.
+func ApproveDeviceGrant(deviceCode, sessionID, userID string) error {
+	return settleDeviceGrant(deviceCode, func(grant *DeviceGrant) {
+		grant.Status = DeviceGrantApproved
+		grant.SessionID = sessionID
+		grant.UserID = userID
+	})
+}
+
+// DenyDeviceGrant records that the user declined the login at the provider.
+func DenyDeviceGrant(deviceCode string) error {
+	return settleDeviceGrant(deviceCode, func(grant *DeviceGrant) {
+		grant.Status = DeviceGrantDenied
+	})
+}
+
+// settleDeviceGrant applies the approval or denial to a grant that is still pending, returning
+// ErrDeviceGrantSettled otherwise. The grant is watched while it is changed, so a concurrent approval
+// or denial cannot be overwritten.
+func settleDeviceGrant(deviceCode string, settle func(*DeviceGrant)) error {
+	ctx := context.Background()
+	key := constructDeviceCodeKey(deviceCode)
+	var userCode string
+	err := redisclient.Client.Watch(ctx, func(tx *redis.Tx) error {
+		grantJSON, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return ErrExpiredToken
+		}
+		if err != nil {
+			return err
+		}
+		var grant DeviceGrant
+		if err = json.Unmarshal([]byte(grantJSON), &grant); err != nil {
+			return err
+		}
+		if grant.Status != DeviceGrantPending {
+			return ErrDeviceGrantSettled
+		}
+		settle(&grant)
+		settledJSON, err := json.Marshal(grant)
+		if err != nil {
+			return err
+		}
+		userCode = grant.UserCode
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, settledJSON, redis.KeepTTL)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		// The grant changed while it was being settled, so it is no longer the pending grant we read.
+		return ErrDeviceGrantSettled
+	}
+	if err != nil {
+		if !errors.Is(err, ErrExpiredToken) && !errors.Is(err, ErrDeviceGrantSettled) {
+			log.Printf("Failed to settle device grant in Redis: %v", err)
+			slog.Error(ctx, "Failed to settle device grant in Redis", err, nil)
+		}
+		return err
+	}
+	_ = redisclient.Client.Del(ctx, constructUserCodeKey(userCode)).Err()
+	return nil
+}
+
+// PollDeviceGrant is called by the device while it waits for the user. It returns the approved grant
+// once, or one of ErrAuthorizationPending, ErrSlowDown, ErrAccessDenied or ErrExpiredToken.
+func PollDeviceGrant(deviceCode string) (*DeviceGrant, error) {
+	grant, err := getDeviceGrant(deviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	switch grant.Status {
+	case DeviceGrantApproved:
+		// The device collects the session exactly once. The grant is read and deleted in one GETDEL, so
+		// of two concurrent polls only one gets it and the other sees the code as expired.
+		return takeDeviceGrant(deviceCode)
+	case DeviceGrantDenied:
+		_ = redisclient.Client.Del(context.Background(), constructDeviceCodeKey(deviceCode), constructDevicePollKey(deviceCode)).Err()
+		return nil, ErrAccessDenied
+	}
+
+	state, err := getDevicePollState(deviceCode, grant.Interval)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tooFast := !state.LastPolledAt.IsZero() && now.Sub(state.LastPolledAt) < time.Duration(state.Interval)*time.Second
+	if tooFast {
+		state.Interval += deviceGrantSlowDownStep
+	}
+	state.LastPolledAt = now
+	if err = saveDevicePollState(deviceCode, state, time.Until(grant.ExpiresAt)); err != nil {
+		return nil, err
+	}
+	if tooFast {
+		return nil, ErrSlowDown
+	}
+	return nil, ErrAuthorizationPending
+}
+
+// getDevicePollState returns the device's polling bookkeeping, starting from the grant's interval
+// before its first poll.
+func getDevicePollState(deviceCode string, interval int) (*devicePollState, error) {
+	stateJSON, err := redisclient.Client.Get(context.Background(), constructDevicePollKey(deviceCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return &devicePollState{Interval: interval}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state devicePollState
+	if err = json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func saveDevicePollState(deviceCode string, state *devicePollState, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrExpiredToken
+	}
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return redisclient.Client.Set(context.Background(), constructDevicePollKey(deviceCode), stateJSON, ttl).Err()
+}
+
+func getDeviceGrant(deviceCode string) (*DeviceGrant, error) {
+	grantJSON, err := redisclient.Client.Get(context.Background(), constructDeviceCodeKey(deviceCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		log.Printf("Failed to retrieve device grant from Redis: %v", err)
+		slog.Error(context.Background(), "Failed to retrieve device grant from Redis", err, nil)
+		return nil, err
+	}
+
+	var grant DeviceGrant
+	if err = json.Unmarshal([]byte(grantJSON), &grant); err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// takeDeviceGrant atomically reads and deletes an approved grant.
+func takeDeviceGrant(deviceCode string) (*DeviceGrant, error) {
+	grantJSON, err := redisclient.Client.GetDel(context.Background(), constructDeviceCodeKey(deviceCode)).Result()
+	_ = redisclient.Client.Del(context.Background(), constructDevicePollKey(deviceCode)).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrExpiredToken
+	}
+	if err != nil {
+		log.Printf("Failed to collect device grant from Redis: %v", err)
+		slog.Error(context.Background(), "Failed to collect device grant from Redis", err, nil)
+		return nil, err
+	}
+
+	var grant DeviceGrant
+	if err = json.Unmarshal([]byte(grantJSON), &grant); err != nil {
+		return nil, err
+	}
+	if grant.Status != DeviceGrantApproved {
+		return nil, ErrExpiredToken
+	}
+	return &grant, nil
+}
+
+func saveDeviceGrant(grant *DeviceGrant, ttl time.Duration) error {
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return err
+	}
+	err = redisclient.Client.Set(context.Background(), constructDeviceCodeKey(grant.DeviceCode), grantJSON, ttl).Err()
+	if err != nil {
+		log.Printf("Failed to store device grant in Redis: %v", err)
+		slog.Error(context.Background(), "Failed to store device grant in Redis", err, map[string]interface{}{
+			"provider": grant.Provider,
+		})
+		return err
+	}
+	return nil
+}
+
+func generateDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateUserCode returns a code in the form XXXX-XXXX.
+func generateUserCode() (string, error) {
+	code := make([]byte, 0, userCodeLength+1)
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code = append(code, userCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode upper-cases a user code typed by the user and restores its separator, so that
+// "bcdf ghjk" and "BCDF-GHJK" refer to the same grant.
+func NormalizeUserCode(userCode string) string {
+	code := make([]rune, 0, userCodeLength+1)
+	for _, r := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			code = append(code, r)
+		}
+	}
+	if len(code) != userCodeLength {
+		return string(code)
+	}
+	return string(code[:userCodeLength/2]) + "-" + string(code[userCodeLength/2:])
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// releaseLockScript deletes the lock only if it is still held by the caller.
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// extendLockScript resets the lock's expiry only if it is still held by the caller.
+var extendLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// acquireLock tries to take a Redis lock shared by all replicas. The lock expires after ttl so a
+// crashed holder cannot block others forever. The returned release function is safe to call once
+// the lock has expired or been taken over.
+func acquireLock(ctx context.Context, key string, ttl time.Duration) (release func(), acquired bool, err error) {
+	owner := uuid.New().String()
+	acquired, err = redisclient.Client.SetNX(ctx, key, owner, ttl).Result()
+	if err != nil || !acquired {
+		return func() {}, false, err
+	}
+	return func() {
+		releaseLockScript.Run(context.Background(), redisclient.Client, []string{key}, owner)
+	}, true, nil
+}
+
+// acquireExtendableLock is acquireLock for work that may outlive ttl. The returned extend function
+// resets the lock's expiry to ttl and reports whether the caller still holds it.
+func acquireExtendableLock(ctx context.Context, key string, ttl time.Duration) (release func(), extend func(context.Context) (bool, error), acquired bool, err error) {
+	owner := uuid.New().String()
+	acquired, err = redisclient.Client.SetNX(ctx, key, owner, ttl).Result()
+	if err != nil || !acquired {
+		return func() {}, nil, false, err
+	}
+	release = func() {
+		releaseLockScript.Run(context.Background(), redisclient.Client, []string{key}, owner)
+	}
+	extend = func(ctx context.Context) (bool, error) {
+		extended, err := extendLockScript.Run(ctx, redisclient.Client, []string{key}, owner, ttl.Milliseconds()).Int()
+		return extended == 1, err
+	}
+	return release, extend, true, nil
+}
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// Mass revocation statuses.
+const (
+	MassRevocationRunning   = "running"
+	MassRevocationFailed    = "failed"
+	MassRevocationCompleted = "completed"
+)
+
+const (
+	// massRevocationBatch is how many keys each batch scans.
+	massRevocationBatch = 100
+	// massRevocationLockTTL bounds how long a batch may hold the job's lock.
+	massRevocationLockTTL = time.Minute
+	// massRevocationStaleAfter is how long a running job may go without progress before it is considered
+	// abandoned, e.g. by a replica that was shut down, and may be resumed.
+	massRevocationStaleAfter = 2 * massRevocationLockTTL
+	// completedMassRevocationTTL is how long a completed job's report is kept.
+	completedMassRevocationTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrMassRevocationNotFound is returned when there is no mass revocation with the ID.
+	ErrMassRevocationNotFound = errors.New("mass revocation not found")
+	// ErrMassRevocationInProgress is returned when a mass revocation for the same tokens is already running.
+	ErrMassRevocationInProgress = errors.New("mass revocation already in progress")
+	// ErrMassRevocationCompleted is returned when resuming a mass revocation that has completed.
+	ErrMassRevocationCompleted = errors.New("mass revocation already completed")
+)
+
+// MassRevocation is an emergency revocation of every stored token of a provider, or of those issued
+// through one registered client. It runs in batches, recording its progress so it can be resumed.
+type MassRevocation struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	// ClientID limits the revocation to tokens issued through the registered client, if set.
+	ClientID string `json:"client_id,omitempty"`
+	Status   string `json:"status"`
+	// Phase indexes the key patterns being scanned, and Cursor is the scan's position within it.
+	Phase  int    `json:"phase"`
+	Cursor uint64 `json:"cursor"`
+	// Scanned counts the keys looked at, and Deleted the tokens deleted.
+	Scanned int `json:"scanned"`
+	Deleted int `json:"deleted"`
+	// Revocations counts the provider revocation outcomes by Revocation value.
+	Revocations map[string]int `json:"revocations"`
+	Error       string         `json:"error,omitempty"`
+	StartedAt   time.Time      `json:"started_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+func constructMassRevocationKey(id string) string {
+	return "mass_revocation:" + id
+}
+
+func constructMassRevocationLockKey(id string) string {
+	return "mass_revocation_lock:" + id
+}
+
+// constructActiveMassRevocationKey holds the ID of the running mass revocation of the provider's tokens,
+// or of those of one client. Logins it covers are suspended while it exists.
+func constructActiveMassRevocationKey(provider, clientID string) string {
+	if clientID == "" {
+		clientID = "*"
+	}
+	return "mass_revocation_active:" + provider + ":" + clientID
+}
+
+// massRevocationPatterns are the keys holding the provider's tokens: session tokens, the identity tokens
+// of linked accounts, and users' offline grants.
+func massRevocationPatterns(provider string) []string {
+	return []string{
+		"session:*_" + provider + "_*",
+		constructIdentityTokenKey(provider, "*"),
+		constructOfflineGrantKey("*", provider),
+	}
+}
+
+// StartMassRevocation starts revoking the provider's tokens, or those issued through the client, in
+// the background. Logins the revocation covers are suspended until it completes.
+func StartMassRevocation(ctx context.Context, provider, clientID string) (*MassRevocation, error) {
+	job := &MassRevocation{
+		ID:          uuid.New().String(),
+		Provider:    provider,
+		ClientID:    clientID,
+		Status:      MassRevocationRunning,
+		Revocations: map[string]int{},
+		StartedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+	started, err := redisclient.Client.SetNX(ctx, constructActiveMassRevocationKey(provider, clientID), job.ID, 0).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, ErrMassRevocationInProgress
+	}
+	if err = saveMassRevocation(ctx, job); err != nil {
+		return nil, err
+	}
+	dropAppToken(ctx, job)
+
+	slog.Info(ctx, "Started mass revocation", map[string]interface{}{
+		"mass_revocation_id": job.ID,
+		"provider":           provider,
+		"client_id":          clientID,
+	})
+	go runMassRevocation(job.ID)
+	return job, nil
+}
+
+// ResumeMassRevocation continues a mass revocation that failed, or that stopped making progress, from
+// where it left off.
+func ResumeMassRevocation(ctx context.Context, id string) (*MassRevocation, error) {
+	job, err := GetMassRevocation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case job.Status == MassRevocationCompleted:
+		return nil, ErrMassRevocationCompleted
+	case job.Status == MassRevocationRunning && time.Since(job.UpdatedAt) < massRevocationStaleAfter:
+		return nil, ErrMassRevocationInProgress
+	}
+
+	job.Status = MassRevocationRunning
+	job.Error = ""
+	job.UpdatedAt = time.Now().UTC()
+	if err = saveMassRevocation(ctx, job); err != nil {
+		return nil, err
+	}
+
+	slog.Info(ctx, "Resumed mass revocation", map[string]interface{}{
+		"mass_revocation_id": job.ID,
+		"phase":              job.Phase,
+		"cursor":             job.Cursor,
+	})
+	go runMassRevocation(job.ID)
+	return job, nil
+}
+
+// GetMassRevocation returns the mass revocation and its progress.
+func GetMassRevocation(ctx context.Context, id string) (*MassRevocation, error) {
+	jobJSON, err := redisclient.Client.Get(ctx, constructMassRevocationKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMassRevocationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job MassRevocation
+	if err = json.Unmarshal([]byte(jobJSON), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// IsLoginSuspended reports whether a running mass revocation covers new logins with the provider
+// through the client.
+func IsLoginSuspended(ctx context.Context, provider, clientID string) (bool, error) {
+	keys := []string{constructActiveMassRevocationKey(provider, "")}
+	if clientID != "" {
+		keys = append(keys, constructActiveMassRevocationKey(provider, clientID))
+	}
+	active, err := redisclient.Client.Exists(ctx, keys...).Result()
+	return active > 0, err
+}
+
+// runMassRevocation processes the job's batches until it completes or fails.
+func runMassRevocation(id string) {
+	ctx := context.Background()
+	for {
+		done, err := processMassRevocationBatch(ctx, id)
+		if err == nil && !done {
+			continue
+		}
+		if err != nil {
+			slog.Error(ctx, "Mass revocation failed", err, map[string]interface{}{
+				"mass_revocation_id": id,
+			})
+			failMassRevocation(ctx, id, err)
+		}
+		return
+	}
+}
+
+// processMassRevocationBatch scans and revokes the next batch of keys under the job's lock, so resumed
+// workers never process a batch twice. It reports whether the job has stopped running.
+func processMassRevocationBatch(ctx context.Context, id string) (bool, error) {
+	release, extend, acquired, err := acquireExtendableLock(ctx, constructMassRevocationLockKey(id), massRevocationLockTTL)
+	if err != nil {
+		return false, err
+	}
+	if !acquired {
+		// Another worker is processing a batch of the job.
+		time.Sleep(refreshPollInterval)
+		return false, nil
+	}
+	defer release()
+
+	job, err := GetMassRevocation(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if job.Status != MassRevocationRunning {
+		return true, nil
+	}
+
+	patterns := massRevocationPatterns(job.Provider)
+	keys, cursor, err := redisclient.Client.Scan(ctx, job.Cursor, patterns[job.Phase], massRevocationBatch).Result()
+	if err != nil {
+		return false, err
+	}
+	revoked := map[string]bool{}
+	for _, key := range keys {
+		// Each revocation can take up to the provider request timeout, so the lock is extended per key
+		// rather than sized for the whole batch. A worker that lost the lock stops without saving, and the
+		// worker that took over rescans from the last saved cursor.
+		held, err := extend(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !held {
+			slog.Warn(ctx, "Lost mass revocation lock, stopping batch", map[string]interface{}{
+				"mass_revocation_id": id,
+			})
+			return false, nil
+		}
+
+		job.Scanned++
+		token, deleted, err := deleteMassRevocationToken(ctx, job, key)
+		if err != nil {
+			return false, err
+		}
+		if !deleted {
+			continue
+		}
+		job.Deleted++
+
+		// Linked accounts share a token between their sessions, identity token and offline grant.
+		value := tokenValue(token)
+		if revoked[value] {
+			continue
+		}
+		revoked[value] = true
+		job.Revocations[RevokeToken(ctx, job.Provider, token)]++
+	}
+
+	job.Cursor = cursor
+	if cursor == 0 {
+		job.Phase++
+	}
+	job.UpdatedAt = time.Now().UTC()
+	if job.Phase < len(patterns) {
+		return false, saveMassRevocation(ctx, job)
+	}
+
+	job.Status = MassRevocationCompleted
+	job.CompletedAt = &job.UpdatedAt
+	if err = saveMassRevocation(ctx, job); err != nil {
+		return false, err
+	}
+	if err = redisclient.Client.Del(ctx, constructActiveMassRevocationKey(job.Provider, job.ClientID)).Err(); err != nil {
+		return false, err
+	}
+	// The app token may have been fetched again with the old credentials while the job ran.
+	dropAppToken(ctx, job)
+	slog.Info(ctx, "Completed mass revocation", map[string]interface{}{
+		"mass_revocation_id": job.ID,
+		"provider":           job.Provider,
+		"client_id":          job.ClientID,
+		"scanned":            job.Scanned,
+		"deleted":            job.Deleted,
+		"revocations":        job.Revocations,
+	})
+	return true, nil
+}
+
+// dropAppToken invalidates the provider's cached client-credentials token. It was issued with the same
+// client credentials as the revoked tokens, so it is dropped whether the job covers one client or all.
+func dropAppToken(ctx context.Context, job *MassRevocation) {
+	if err := invalidateAppToken(ctx, job.Provider); err != nil {
+		slog.Error(ctx, "Failed to invalidate app token", err, map[string]interface{}{
+			"mass_revocation_id": job.ID,
+			"provider":           job.Provider,
+		})
+	}
+}
+
+// deleteMassRevocationToken deletes the token stored under the key if the job covers it.
+func deleteMassRevocationToken(ctx context.Context, job *MassRevocation, key string) (*oauth2.Token, bool, error) {
+	authDataJSON, err := redisclient.Client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil // The token expired or was deleted since the scan.
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var authData AuthData
+	if err = json.Unmarshal([]byte(authDataJSON), &authData); err != nil || authData.Token == nil {
+		return nil, false, nil
+	}
+	if job.ClientID != "" && authData.ClientID != job.ClientID {
+		return nil, false, nil
+	}
+
+	// Session tokens are deleted through the token store, which keeps the session's indexes up to date.
+	if sessionKey, isSession := strings.CutPrefix(key, "session:"); isSession {
+		sessionID, userID, found := strings.Cut(sessionKey, "_"+job.Provider+"_")
+		if !found || userID != authData.UserID {
+			return nil, false, nil // Another provider's key that happened to match the pattern.
+		}
+		return authData.Token, true, DeleteAuthToken(sessionID, job.Provider, userID)
+	}
+	return authData.Token, true, redisclient.Client.Del(ctx, key).Err()
+}
+
+// failMassRevocation records the error, leaving logins suspended until the job is resumed and completes.
+func failMassRevocation(ctx context.Context, id string, cause error) {
+	job, err := GetMassRevocation(ctx, id)
+	if err != nil {
+		return
+	}
+	job.Status = MassRevocationFailed
+	job.Error = cause.Error()
+	job.UpdatedAt = time.Now().UTC()
+	if err = saveMassRevocation(ctx, job); err != nil {
+		slog.Error(ctx, "Failed to record mass revocation failure", err, map[string]interface{}{
+			"mass_revocation_id": id,
+		})
+	}
+}
+
+// saveMassRevocation stores the job. Running and failed jobs are kept until they complete.
+func saveMassRevocation(ctx context.Context, job *MassRevocation) error {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to serialize mass revocation: %w", err)
+	}
+	var ttl time.Duration
+	if job.Status == MassRevocationCompleted {
+		ttl = completedMassRevocationTTL
+	}
+	return redisclient.Client.Set(ctx, constructMassRevocationKey(job.ID), jobJSON, ttl).Err()
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/monzo/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// constructOfflineGrantKey holds the user's long-lived grant for the provider, which backend jobs use
+// without a browser session.
+func constructOfflineGrantKey(userID, provider string) string {
+	return "offline_grant:" + userID + ":" + provider
+}
+
+// GetOfflineToken returns the access token of the user's offline grant for the provider, refreshing it
+// first if it has expired. It returns ErrTokenNotFound when the user has no grant for the provider.
+func GetOfflineToken(ctx context.Context, userID, provider string) (*AuthData, string, error) {
+	grant, found, err := getOfflineGrant(ctx, userID, provider)
+	if err != nil {
+		return nil, "", err
+	}
+	if !found {
+		return nil, "", ErrTokenNotFound
+	}
+
+	if grant.Token.Expiry.IsZero() || grant.Token.RefreshToken == "" {
+		if !grant.Token.Expiry.IsZero() && grant.Token.Expiry.Before(time.Now()) {
+			return nil, "", ErrReauthRequired
+		}
+		return grant, RefreshStatusNotRefreshable, nil
+	}
+	if grant.Token.Expiry.After(time.Now()) {
+		return grant, RefreshStatusValid, nil
+	}
+	if grant.ReauthRequired {
+		return nil, "", ErrReauthRequired
+	}
+
+	if err = refreshOfflineGrant(ctx, userID, provider, grant); err != nil {
+		return nil, "", err
+	}
+	return grant, RefreshStatusRefreshed, nil
+}
+
+// refreshOfflineGrant refreshes the grant's token, updating grant in place. The new token is shared
+// with the user's sessions through the identity token, as the provider may have rotated the refresh token.
+func refreshOfflineGrant(ctx context.Context, userID, provider string, grant *AuthData) error {
+	staleAccessToken := grant.Token.AccessToken
+	return withRefreshLock(ctx, constructRefreshLockKey(provider, grant.UserID), func() error {
+		// The grant may have been refreshed while we were acquiring the lock, or one of the user's
+		// sessions may have spent the refresh token it shares with them.
+		if current, found, err := getOfflineGrant(ctx, userID, provider); err == nil && found && current.Token.AccessToken != staleAccessToken {
+			*grant = *current
+			return nil
+		}
+		if adopted, err := adoptIdentityToken(ctx, provider, grant, staleAccessToken); err != nil {
+			slog.Error(ctx, "Failed to check identity token", err, map[string]interface{}{
+				"internal_user_id": userID,
+				"provider":         provider,
+			})
+		} else if adopted {
+			return storeOfflineGrant(ctx, userID, provider, grant)
+		}
+
+		logParams := map[string]interface{}{
+			"internal_user_id": userID,
+			"provider":         provider,
+			"user_id":          grant.UserID,
+			"expired_at":       grant.Token.Expiry,
+		}
+		slog.Info(ctx, "Refreshing offline grant", logParams)
+		refreshed, err := refreshedAuthData(ctx, provider, grant, logParams)
+		if err != nil && !errors.Is(err, ErrReauthRequired) {
+			// Transient failures are retried by the next request, so a provider outage during a
+			// backend sync does not disable the grant.
+			return err
+		}
+		if err != nil {
+			grant.ReauthRequired = true
+			if err := storeOfflineGrant(ctx, userID, provider, grant); err != nil {
+				slog.Error(ctx, "Failed to mark offline grant as requiring reauthentication", err, logParams)
+			}
+			return err
+		}
+		if err = storeOfflineGrant(ctx, userID, provider, refreshed); err != nil {
+			return err
+		}
+		if err = storeIdentityToken(ctx, provider, refreshed); err != nil {
+			slog.Error(ctx, "Failed to store identity token", err, logParams)
+		}
+
+		*grant = *refreshed
+		return nil
+	})
+}
+
+// syncOfflineGrant keeps the user's offline grant for the provider up to date with the identity's
+// token. The first identity linked for a provider holds the grant until it is unlinked.
+func syncOfflineGrant(ctx context.Context, userID, provider string, authData *AuthData) error {
+	grant, found, err := getOfflineGrant(ctx, userID, provider)
+	if err != nil {
+		return err
+	}
+	if found && grant.UserID != authData.UserID {
+		return nil
+	}
+	return storeOfflineGrant(ctx, userID, provider, authData)
+}
+
+// revokeOfflineGrant removes the identity's offline grant, handing the grant to another of the user's
+// identities with the provider if there is one.
+func revokeOfflineGrant(ctx context.Context, userID, provider, providerUserID string) error {
+	grant, found, err := getOfflineGrant(ctx, userID, provider)
+	if err != nil || !found || grant.UserID != providerUserID {
+		return err
+	}
+	if err = redisclient.Client.Del(ctx, constructOfflineGrantKey(userID, provider)).Err(); err != nil {
+		return err
+	}
+	slog.Info(ctx, "Revoked offline grant", map[string]interface{}{
+		"internal_user_id": userID,
+		"provider":         provider,
+		"user_id":          providerUserID,
+	})
+
+	identities, err := getUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if identity.Provider != provider || identity.UserID == providerUserID {
+			continue
+		}
+		authData, found, err := getIdentityToken(ctx, provider, identity.UserID)
+		if err != nil || !found {
+			continue
+		}
+		return storeOfflineGrant(ctx, userID, provider, authData)
+	}
+	return nil
+}
+
+// storeOfflineGrant stores the grant. Refreshable grants, and those whose token never expires, are kept
+// until they are revoked; others expire with their access token.
+func storeOfflineGrant(ctx context.Context, userID, provider string, authData *AuthData) error {
+	authDataJSON, err := json.Marshal(authData)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if authData.Token.RefreshToken == "" && !authData.Token.Expiry.IsZero() {
+		ttl = authDataTTL(authData.Token)
+	}
+	return redisclient.Client.Set(ctx, constructOfflineGrantKey(userID, provider), authDataJSON, ttl).Err()
+}
+
+func getOfflineGrant(ctx context.Context, userID, provider string) (*AuthData, bool, error) {
+	authDataJSON, err := redisclient.Client.Get(ctx, constructOfflineGrantKey(userID, provider)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var authData AuthData
+	if err = json.Unmarshal([]byte(authDataJSON), &authData); err != nil {
+		return nil, false, err
+	}
+	return &authData, true, nil
+}
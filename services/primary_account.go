@@ -0,0 +1,163 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"errors"
+	"github.com/monzo/slog"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// constructPrimaryAccountKey is the Redis hash mapping each provider to the session's primary user ID.
+func constructPrimaryAccountKey(sessionID string) string {
+	return "session_primary:" + sessionID
+}
+
+// SetPrimaryAccount makes the account the session's primary account for the provider.
+func SetPrimaryAccount(ctx context.Context, sessionID, provider, userID string) error {
+	if _, found := GetAuthToken(sessionID, provider, userID); !found {
+		return ErrTokenNotFound
+	}
+
+	key := constructPrimaryAccountKey(sessionID)
+	_, err := redisclient.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, provider, userID)
+		pipe.Expire(ctx, key, refreshableAuthDataTTL)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info(ctx, "Set primary account", map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    userID,
+	})
+	return nil
+}
+
+// GetPrimaryAccount returns the user ID of the session's primary account for the provider, or
+// ErrTokenNotFound when the session has no account with the provider. It is called on every token
+// request without a user ID, so it reads the primary account's own key; the session's accounts are
+// only scanned when no primary is recorded yet, and the result is recorded for later requests.
+func GetPrimaryAccount(sessionID, provider string) (string, error) {
+	ctx := context.Background()
+	key := constructPrimaryAccountKey(sessionID)
+	chosen, err := redisclient.Client.HGet(ctx, key, provider).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	if chosen != "" {
+		exists, err := redisclient.Client.Exists(ctx, constructRedisKey(sessionID, provider, chosen)).Result()
+		if err != nil {
+			return "", err
+		}
+		if exists > 0 {
+			return chosen, nil
+		}
+	}
+
+	accounts, err := GetLoggedInProviders(sessionID)
+	if err != nil {
+		return "", err
+	}
+	for _, account := range accounts {
+		if account.Provider != provider || !account.Primary {
+			continue
+		}
+		_, err = redisclient.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, provider, account.UserID)
+			pipe.Expire(ctx, key, refreshableAuthDataTTL)
+			return nil
+		})
+		if err != nil {
+			slog.Error(ctx, "Failed to record primary account", err, map[string]interface{}{
+				"session_id": sessionID,
+				"provider":   provider,
+			})
+		}
+		return account.UserID, nil
+	}
+	return "", ErrTokenNotFound
+}
+
+// markPrimaryAccounts sets Primary on each provider's primary account: the one chosen with
+// SetPrimaryAccount if it is still linked, and otherwise the earliest linked account.
+func markPrimaryAccounts(ctx context.Context, sessionID string, accounts []LoggedInProvider) error {
+	chosen, err := redisclient.Client.HGetAll(ctx, constructPrimaryAccountKey(sessionID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	primary := map[string]int{}
+	for i, account := range accounts {
+		current, exists := primary[account.Provider]
+		switch {
+		case !exists:
+			primary[account.Provider] = i
+		case accounts[current].UserID == chosen[account.Provider]:
+		case account.UserID == chosen[account.Provider] || linkedBefore(account, accounts[current]):
+			primary[account.Provider] = i
+		}
+	}
+	for _, i := range primary {
+		accounts[i].Primary = true
+	}
+	return nil
+}
+
+// reassignPrimaryAccount promotes the earliest remaining account to primary once the primary account
+// has been logged out.
+func reassignPrimaryAccount(ctx context.Context, sessionID, provider, removedUserID string) error {
+	key := constructPrimaryAccountKey(sessionID)
+	chosen, err := redisclient.Client.HGet(ctx, key, provider).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && chosen != removedUserID) {
+		// Either nothing was chosen, so the earliest account already is primary, or the primary is unaffected.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	accounts, err := GetLoggedInProviders(sessionID)
+	if err != nil {
+		return err
+	}
+	var remaining []LoggedInProvider
+	for _, account := range accounts {
+		if account.Provider == provider {
+			remaining = append(remaining, account)
+		}
+	}
+	if len(remaining) == 0 {
+		return redisclient.Client.HDel(ctx, key, provider).Err()
+	}
+	sort.Slice(remaining, func(i, j int) bool { return linkedBefore(remaining[i], remaining[j]) })
+
+	slog.Info(ctx, "Reassigned primary account", map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    remaining[0].UserID,
+	})
+	return redisclient.Client.HSet(ctx, key, provider, remaining[0].UserID).Err()
+}
+
+// linkedBefore reports whether account a was linked before account b. Accounts linked before link
+// times were recorded count as the oldest, and ties are broken by user ID.
+func linkedBefore(a, b LoggedInProvider) bool {
+	switch {
+	case a.LinkedAt == nil && b.LinkedAt == nil:
+		return a.UserID < b.UserID
+	case a.LinkedAt == nil:
+		return true
+	case b.LinkedAt == nil:
+		return false
+	case a.LinkedAt.Equal(*b.LinkedAt):
+		return a.UserID < b.UserID
+	default:
+		return a.LinkedAt.Before(*b.LinkedAt)
+	}
+}
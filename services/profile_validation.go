@@ -0,0 +1,49 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+
+	"golang.org/x/oauth2"
+)
+
+// ValidateProfile checks the user's profile against the provider's required fields, filling in the
+// display name from the user ID when it is missing. The email is only required when its scope was
+// granted. Invalid profiles are still logged in; the outcome is recorded on the user.
+func ValidateProfile(provider string, user *models.UserInfo, token *oauth2.Token) *models.ProfileValidation {
+	requirements := config.GetProfileRequirements(provider)
+	validation := &models.ProfileValidation{}
+
+	// The user ID stands in for a missing display name either way, but it only counts as a fallback
+	// when the display name is not required.
+	if user.DisplayName == "" {
+		user.DisplayName = user.ID
+		if requirements.Requires(config.ProfileFieldDisplayName) {
+			validation.Missing = append(validation.Missing, config.ProfileFieldDisplayName)
+		} else {
+			validation.Fallbacks = append(validation.Fallbacks, config.ProfileFieldDisplayName)
+		}
+	}
+	if user.Email == "" && requirements.Requires(config.ProfileFieldEmail) && emailScopeGranted(requirements, token) {
+		validation.Missing = append(validation.Missing, config.ProfileFieldEmail)
+	}
+
+	validation.Valid = len(validation.Missing) == 0
+	user.Validation = validation
+	return validation
+}
+
+// emailScopeGranted reports whether the token grants access to the email. Providers that do not report
+// granted scopes are assumed to have granted it.
+func emailScopeGranted(requirements config.ProfileRequirements, token *oauth2.Token) bool {
+	granted := GrantedScopes(token)
+	if requirements.EmailScope == "" || len(granted) == 0 {
+		return true
+	}
+	for _, scope := range granted {
+		if scope == requirements.EmailScope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func constructProviderBackoffKey(provider string) string {
+	return "provider_backoff:" + provider
+}
+
+// SetProviderBackoff records that the provider asked us, through Retry-After, not to call its API
+// for the given duration. Provider rate limits apply to our whole app, so the backoff is shared by
+// all sessions and replicas.
+func SetProviderBackoff(ctx context.Context, provider string, retryAfter time.Duration) error {
+	return redisclient.Client.Set(ctx, constructProviderBackoffKey(provider), "1", retryAfter).Err()
+}
+
+// GetProviderBackoff returns how long calls to the provider's API must still be held back, or zero.
+func GetProviderBackoff(ctx context.Context, provider string) (time.Duration, error) {
+	ttl, err := redisclient.Client.PTTL(ctx, constructProviderBackoffKey(provider)).Result()
+	if errors.Is(err, redis.Nil) || ttl < 0 {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return ttl, nil
+}
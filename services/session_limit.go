@@ -0,0 +1,156 @@
+package services
+
+import (
+	"auth-service/config"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrSessionLimitReached is returned when the account is logged in to as many sessions as its provider
+// allows and the provider's policy rejects further logins.
+var ErrSessionLimitReached = errors.New("session limit reached")
+
+const (
+	// sessionLimitLockTTL bounds how long one login may hold the account's session limit.
+	sessionLimitLockTTL = 10 * time.Second
+	// sessionLimitPollInterval is how often waiting logins check whether the lock was released.
+	sessionLimitPollInterval = 100 * time.Millisecond
+)
+
+// constructSessionLimitLockKey serializes the account's logins, so concurrent logins cannot each see
+// room under the limit and exceed it together.
+func constructSessionLimitLockKey(provider, providerUserID string) string {
+	return "session_limit_lock:" + provider + ":" + providerUserID
+}
+
+// EnforceSessionLimit logs the account in to the session with store under the provider's session limit.
+// It first makes room by logging the account out of its oldest sessions and revoking their tokens or,
+// under the reject policy, returns ErrSessionLimitReached without calling store. Logging in again to a
+// session that already holds the account is always allowed. token is the token being stored; evicted
+// sessions sharing it, as restored linked accounts do, are not revoked at the provider.
+func EnforceSessionLimit(ctx context.Context, provider, providerUserID, sessionID string, token *oauth2.Token, store func() error) error {
+	limit := config.GetSessionLimit(provider)
+	if limit.Max == 0 {
+		return store()
+	}
+
+	var evicted []*AuthData
+	err := withSessionLimitLock(ctx, provider, providerUserID, func() error {
+		var err error
+		if evicted, err = evictExcessSessions(ctx, provider, providerUserID, sessionID, limit); err != nil {
+			return err
+		}
+		return store()
+	})
+
+	// The provider may be slow to answer, so evicted tokens are revoked once other logins may proceed.
+	for _, authData := range evicted {
+		if sharesToken(authData.Token, token) {
+			continue
+		}
+		slog.Info(ctx, "Revoked evicted session's token", map[string]interface{}{
+			"provider":   provider,
+			"user_id":    providerUserID,
+			"revocation": revokeAccountToken(ctx, provider, authData),
+		})
+	}
+	return err
+}
+
+// withSessionLimitLock runs fn while holding the account's session limit lock, waiting for any other
+// login of the account to finish first.
+func withSessionLimitLock(ctx context.Context, provider, providerUserID string, fn func() error) error {
+	deadline := time.Now().Add(sessionLimitLockTTL)
+	for {
+		release, acquired, err := acquireLock(ctx, constructSessionLimitLockKey(provider, providerUserID), sessionLimitLockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to acquire session limit lock: %w", err)
+		}
+		if acquired {
+			defer release()
+			return fn()
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for the account's other logins")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sessionLimitPollInterval):
+		}
+	}
+}
+
+// evictExcessSessions logs the account out of its oldest sessions until the session fits under the
+// limit, returning the evicted tokens, or returns ErrSessionLimitReached under the reject policy.
+func evictExcessSessions(ctx context.Context, provider, providerUserID, sessionID string, limit config.SessionLimit) ([]*AuthData, error) {
+	sessionIDs, err := activeAccountSessions(ctx, provider, providerUserID)
+	if err != nil {
+		return nil, err
+	}
+	var others []string
+	for _, id := range sessionIDs {
+		if id != sessionID {
+			others = append(others, id)
+		}
+	}
+	excess := len(others) - (limit.Max - 1)
+	if excess <= 0 {
+		return nil, nil
+	}
+	if limit.Policy == config.SessionLimitReject {
+		return nil, ErrSessionLimitReached
+	}
+
+	var evicted []*AuthData
+	for _, evictedSessionID := range others[:excess] {
+		authData, found := GetAuthToken(evictedSessionID, provider, providerUserID)
+		if err = DeleteAuthToken(evictedSessionID, provider, providerUserID); err != nil {
+			return evicted, err
+		}
+		if found {
+			evicted = append(evicted, authData)
+		}
+		if _, err = EndEmptySession(ctx, evictedSessionID); err != nil {
+			slog.Error(ctx, "Failed to end empty session", err, map[string]interface{}{
+				"session_id": evictedSessionID,
+			})
+		}
+		err = RecordAuditEvent(ctx, AuditEvent{
+			Type:      AuditSessionEvicted,
+			Provider:  provider,
+			UserID:    providerUserID,
+			SessionID: evictedSessionID,
+			Details: map[string]string{
+				"new_session_id": sessionID,
+				"max_sessions":   strconv.Itoa(limit.Max),
+			},
+		})
+		if err != nil {
+			slog.Error(ctx, "Failed to record session eviction", err, map[string]interface{}{
+				"session_id": evictedSessionID,
+				"provider":   provider,
+				"user_id":    providerUserID,
+			})
+		}
+	}
+	return evicted, nil
+}
+
+// sharesToken reports whether both tokens are copies of the same grant, so revoking one revokes the other.
+func sharesToken(a, b *oauth2.Token) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a.RefreshToken != "" {
+		return a.RefreshToken == b.RefreshToken
+	}
+	return a.AccessToken != "" && a.AccessToken == b.AccessToken
+}
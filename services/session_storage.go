@@ -4,22 +4,35 @@ import (
 	"auth-service/redisclient"
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/monzo/slog"
 	"log"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 type PKCEData struct {
 	CodeVerifier string `json:"code_verifier"`
+	// DeviceCode is set when the login completes a device authorization grant.
+	DeviceCode string `json:"device_code,omitempty"`
+	// ClientID is set when a registered client started the login.
+	ClientID string `json:"client_id,omitempty"`
+	// LinkSessionID is set when the login links another account to the user of an existing session.
+	LinkSessionID string `json:"link_session_id,omitempty"`
 }
 
 // StorePKCEData stores the PKCE data (including the code verifier) in Redis,
 // using the state token as the key.
 var StorePKCEData = func(stateToken, codeVerifier string) error {
-	key := "pkce:" + stateToken
-	data := PKCEData{
+	return SavePKCEData(stateToken, PKCEData{
 		CodeVerifier: codeVerifier,
-	}
+	})
+}
+
+// SavePKCEData stores the full PKCE data for a login in Redis, using the state token as the key.
+var SavePKCEData = func(stateToken string, data PKCEData) error {
+	key := "pkce:" + stateToken
 	b, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -37,20 +50,36 @@ var StorePKCEData = func(stateToken, codeVerifier string) error {
 
 // GetCodeVerifier retrieves the code verifier from Redis for the given state token.
 func GetCodeVerifier(stateToken string) (string, error) {
+	data, err := GetPKCEData(stateToken)
+	if err != nil {
+		return "", err
+	}
+	return data.CodeVerifier, nil
+}
+
+// ErrPKCEDataNotFound is returned when no login was started with the state token, or it has expired.
+var ErrPKCEDataNotFound = errors.New("PKCE data not found")
+
+// GetPKCEData retrieves the full PKCE data from Redis for the given state token. It returns
+// ErrPKCEDataNotFound when the login has expired or was never started.
+func GetPKCEData(stateToken string) (*PKCEData, error) {
 	key := "pkce:" + stateToken
 	result, err := redisclient.Client.Get(context.Background(), key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrPKCEDataNotFound
+	}
 	if err != nil {
 		log.Printf("Failed to retrieve PKCE data from Redis: %v", err)
 		slog.Error(context.Background(), "Failed to retrieve PKCE data from Redis", err, map[string]interface{}{
 			"state_token": stateToken,
 		})
-		return "", err
+		return nil, err
 	}
 	var data PKCEData
 	if err = json.Unmarshal([]byte(result), &data); err != nil {
-		return "", err
+		return nil, err
 	}
-	return data.CodeVerifier, nil
+	return &data, nil
 }
 
 // DeletePKCEData removes the PKCE data from Redis for the given state token.
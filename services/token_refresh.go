@@ -0,0 +1,239 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/utils"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrTokenNotFound is returned when no token is stored for the session, provider and user.
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrReauthRequired is returned when an expired token cannot be refreshed and the user must log in again.
+	ErrReauthRequired = errors.New("token could not be refreshed")
+	// ErrNotRefreshable is returned when a refresh is forced for a token that has no refresh token.
+	ErrNotRefreshable = errors.New("token cannot be refreshed")
+	// ErrRefreshFailed is returned when the provider failed to refresh the token without rejecting it,
+	// for example because it could not be reached. The token is kept and the next request retries.
+	ErrRefreshFailed = errors.New("provider failed to refresh token")
+	// ErrRefreshTimeout is returned when another caller's refresh of the token did not finish in time.
+	ErrRefreshTimeout = errors.New("timed out waiting for token refresh")
+)
+
+const (
+	// refreshLockTTL bounds how long one caller may spend refreshing a token for everyone else.
+	refreshLockTTL = 10 * time.Second
+	// refreshPollInterval is how often waiting callers check whether the refresh has finished.
+	refreshPollInterval = 100 * time.Millisecond
+)
+
+// constructRefreshLockKey locks refreshes of the account's token. The account's sessions and offline
+// grant share one refresh token, so they all refresh under the same lock.
+func constructRefreshLockKey(provider, userID string) string {
+	return "refresh_lock:" + provider + ":" + userID
+}
+
+// Refresh statuses reported by the token endpoints.
+const (
+	RefreshStatusValid          = "valid"
+	RefreshStatusRefreshed      = "refreshed"
+	RefreshStatusNotRefreshable = "not_refreshable"
+)
+
+// GetValidAuthToken returns the stored token, refreshing it first if it has expired. The returned
+// status is one of the RefreshStatus values.
+func GetValidAuthToken(ctx context.Context, sessionID, provider, userID string) (*AuthData, string, error) {
+	authData, found := GetAuthToken(sessionID, provider, userID)
+	if !found {
+		return nil, "", ErrTokenNotFound
+	}
+
+	// Tokens without an expiry, such as credential provider tokens, never expire and cannot be refreshed.
+	if authData.Token.Expiry.IsZero() || authData.Token.RefreshToken == "" {
+		if !authData.Token.Expiry.IsZero() && authData.Token.Expiry.Before(time.Now()) {
+			return nil, "", ErrReauthRequired
+		}
+		return authData, RefreshStatusNotRefreshable, nil
+	}
+	if authData.Token.Expiry.After(time.Now()) {
+		return authData, RefreshStatusValid, nil
+	}
+	// The provider already rejected this refresh token; don't spend another round trip on it.
+	if authData.ReauthRequired {
+		return nil, "", ErrReauthRequired
+	}
+
+	if err := RefreshAuthToken(ctx, sessionID, provider, authData); err != nil {
+		return nil, "", err
+	}
+	return authData, RefreshStatusRefreshed, nil
+}
+
+// ForceRefreshAuthToken refreshes the stored token regardless of its expiry, for tokens the provider
+// has rejected early. Tokens without a refresh token return ErrNotRefreshable.
+func ForceRefreshAuthToken(ctx context.Context, sessionID, provider, userID string) (*AuthData, error) {
+	authData, found := GetAuthToken(sessionID, provider, userID)
+	if !found {
+		return nil, ErrTokenNotFound
+	}
+	if _, exists := config.Providers[provider]; !exists || authData.Token.RefreshToken == "" {
+		return nil, ErrNotRefreshable
+	}
+	if err := RefreshAuthToken(ctx, sessionID, provider, authData); err != nil {
+		return nil, err
+	}
+	return authData, nil
+}
+
+// RefreshAuthToken refreshes the token with the provider and stores it, updating authData in place.
+// A refresh token the provider rejects returns ErrReauthRequired. Concurrent refreshes of the same token, across replicas,
+// are de-duplicated with a Redis lock: callers that lose the race pick up the winner's token instead
+// of spending the refresh token again.
+func RefreshAuthToken(ctx context.Context, sessionID, provider string, authData *AuthData) error {
+	staleAccessToken := authData.Token.AccessToken
+	return withRefreshLock(ctx, constructRefreshLockKey(provider, authData.UserID), func() error {
+		// The token may have been refreshed while we were acquiring the lock.
+		if current, found := GetAuthToken(sessionID, provider, authData.UserID); found && current.Token.AccessToken != staleAccessToken {
+			*authData = *current
+			return nil
+		}
+		// Linked accounts share their refresh token with the user's other sessions and offline grant,
+		// which may have refreshed it already.
+		if adopted, err := adoptIdentityToken(ctx, provider, authData, staleAccessToken); err != nil {
+			slog.Error(ctx, "Failed to check identity token", err, map[string]interface{}{
+				"session_id": sessionID,
+				"provider":   provider,
+				"user_id":    authData.UserID,
+			})
+		} else if adopted {
+			return saveAuthData(sessionID, provider, authData)
+		}
+		return refreshAndStoreAuthToken(ctx, sessionID, provider, authData)
+	})
+}
+
+// withRefreshLock runs refresh while holding the lock, waiting for any other holder to finish first.
+func withRefreshLock(ctx context.Context, key string, refresh func() error) error {
+	deadline := time.Now().Add(refreshLockTTL)
+	for {
+		release, acquired, err := acquireLock(ctx, key, refreshLockTTL)
+		if err != nil {
+			return fmt.Errorf("failed to acquire refresh lock: %w", err)
+		}
+		if acquired {
+			defer release()
+			return refresh()
+		}
+
+		// Another caller is refreshing the token; wait for it to finish.
+		if time.Now().After(deadline) {
+			return ErrRefreshTimeout
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(refreshPollInterval):
+		}
+	}
+}
+
+func refreshAndStoreAuthToken(ctx context.Context, sessionID, provider string, authData *AuthData) error {
+	logParams := map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    authData.UserID,
+		"expired_at": authData.Token.Expiry,
+	}
+	slog.Info(ctx, "Refreshing token", logParams)
+
+	refreshed, err := refreshedAuthData(ctx, provider, authData, logParams)
+	if errors.Is(err, ErrReauthRequired) {
+		// Remember the rejection so the account is reported as needing a new login.
+		authData.ReauthRequired = true
+		if err := saveAuthData(sessionID, provider, authData); err != nil {
+			slog.Error(ctx, "Failed to mark token as requiring reauthentication", err, logParams)
+		}
+		return err
+	}
+	if err != nil {
+		// Transient failures are retried by the next request.
+		return err
+	}
+	if err = saveAuthData(sessionID, provider, refreshed); err != nil {
+		return err
+	}
+
+	slog.Info(ctx, "Successfully refreshed token", map[string]interface{}{
+		"session_id": sessionID,
+		"provider":   provider,
+		"user_id":    authData.UserID,
+		"expires_at": refreshed.Token.Expiry,
+	})
+
+	*authData = *refreshed
+	return nil
+}
+
+// refreshedAuthData refreshes the token with the provider and returns a copy of authData holding the
+// new token. It returns ErrReauthRequired when the provider rejects the refresh token or a mass
+// revocation covers it, and ErrRefreshFailed when it fails to refresh it.
+func refreshedAuthData(ctx context.Context, provider string, authData *AuthData, logParams map[string]interface{}) (*AuthData, error) {
+	oauthConfig, exists := config.Providers[provider]
+	if !exists {
+		slog.Error(ctx, "Provider does not support refreshing tokens", ErrReauthRequired, logParams)
+		return nil, ErrReauthRequired
+	}
+	// A token being mass revoked must not be exchanged for a new one the revocation would miss.
+	suspended, err := IsLoginSuspended(ctx, provider, authData.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if suspended {
+		slog.Info(ctx, "Skipped refreshing token during mass revocation", logParams)
+		return nil, ErrReauthRequired
+	}
+	newToken, err := utils.RefreshAccessTokenFunc(oauthConfig, authData.Token.RefreshToken)
+	if err != nil {
+		slog.Error(ctx, "Failed to refresh token", err, logParams)
+		if isGrantRejected(err) {
+			return nil, ErrReauthRequired
+		}
+		return nil, fmt.Errorf("%w: %v", ErrRefreshFailed, err)
+	}
+
+	// Providers may omit the scope from a refresh response when it is unchanged.
+	if len(GrantedScopes(newToken)) == 0 && len(authData.Scopes) > 0 {
+		newToken = newToken.WithExtra(map[string]interface{}{
+			"scope": strings.Join(authData.Scopes, " "),
+		})
+	}
+
+	refreshed := *authData
+	refreshedAt := time.Now().UTC()
+	refreshed.Token = newToken
+	refreshed.Scopes = GrantedScopes(newToken)
+	refreshed.LastRefreshedAt = &refreshedAt
+	refreshed.ReauthRequired = false
+	return &refreshed, nil
+}
+
+// isGrantRejected reports whether the provider definitively rejected the refresh token, rather than
+// failing to answer.
+func isGrantRejected(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	if retrieveErr.ErrorCode != "" {
+		return retrieveErr.ErrorCode == "invalid_grant"
+	}
+	return retrieveErr.Response != nil && retrieveErr.Response.StatusCode == http.StatusBadRequest
+}
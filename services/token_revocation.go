@@ -0,0 +1,390 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/redisclient"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/monzo/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// Revocation outcomes reported by logout.
+const (
+	// RevocationRevoked means the provider revoked the token, or there was no token left to revoke.
+	RevocationRevoked = "revoked"
+	// RevocationPending means the provider could not be reached and the revocation is being retried.
+	RevocationPending = "pending"
+	// RevocationFailed means the provider refused to revoke the token.
+	RevocationFailed = "failed"
+	// RevocationRetained means the token was kept because the account is linked to a user, whose
+	// offline grant and other sessions share it, and RETAIN_LINKED_TOKENS_ON_LOGOUT is set. Unlinking
+	// the account revokes it.
+	RevocationRetained = "retained"
+	// RevocationLocalOnly means the provider has no revocation endpoint, so the token was only deleted
+	// here and stays valid at the provider until it expires.
+	RevocationLocalOnly = "local_only"
+)
+
+const (
+	// revocationQueueKey is a sorted set of revocations to retry, scored by when they are next due.
+	revocationQueueKey = "token_revocations"
+	// maxRevocationAttempts bounds how often a revocation is tried before it is given up.
+	maxRevocationAttempts = 6
+	// revocationRetryDelay is the delay before the first retry, doubled for each one after.
+	revocationRetryDelay = 30 * time.Second
+	// revocationRetryBatch bounds how many due revocations one pass retries.
+	revocationRetryBatch = 50
+)
+
+// errRevocationRejected is returned when the provider refuses a revocation in a way a retry won't fix.
+var errRevocationRejected = errors.New("provider rejected the revocation")
+
+// pendingRevocation is a revocation queued for retry.
+type pendingRevocation struct {
+	ID            string `json:"id"`
+	Provider      string `json:"provider"`
+	Token         string `json:"token"`
+	TokenTypeHint string `json:"token_type_hint"`
+	Attempts      int    `json:"attempts"`
+}
+
+// LogoutAccount removes the account from the session and revokes its token at the provider. The
+// returned status is one of the Revocation values.
+func LogoutAccount(ctx context.Context, sessionID, provider, userID string) (string, error) {
+	authData, found := GetAuthToken(sessionID, provider, userID)
+	if err := DeleteAuthToken(sessionID, provider, userID); err != nil {
+		return "", err
+	}
+	if !found {
+		return revocationWithoutToken(provider), nil
+	}
+	return revokeAccountToken(ctx, provider, authData), nil
+}
+
+// LogoutProvider removes all of the session's accounts with the provider and revokes their tokens. The
+// returned status is the least complete revocation among them.
+func LogoutProvider(ctx context.Context, sessionID, provider string) (string, error) {
+	accounts, err := GetLoggedInProviders(sessionID)
+	if err != nil {
+		return "", err
+	}
+	var tokens []*AuthData
+	for _, account := range accounts {
+		if account.Provider != provider {
+			continue
+		}
+		if authData, found := GetAuthToken(sessionID, provider, account.UserID); found {
+			tokens = append(tokens, authData)
+		}
+	}
+	if err = DeleteAllAuthTokensForProvider(sessionID, provider); err != nil {
+		return "", err
+	}
+
+	status := revocationWithoutToken(provider)
+	for _, authData := range tokens {
+		status = leastCompleteRevocation(status, revokeAccountToken(ctx, provider, authData))
+	}
+	return status, nil
+}
+
+// LogoutSession logs every account out of the session, revoking their tokens, and ends the session. The
+// returned status is the least complete revocation among them.
+func LogoutSession(ctx context.Context, sessionID string) (string, error) {
+	accounts, err := GetLoggedInProviders(sessionID)
+	if err != nil {
+		return "", err
+	}
+	status := RevocationRevoked
+	loggedOut := map[string]bool{}
+	for _, account := range accounts {
+		if loggedOut[account.Provider] {
+			continue
+		}
+		loggedOut[account.Provider] = true
+		revocation, err := LogoutProvider(ctx, sessionID, account.Provider)
+		if err != nil {
+			return "", err
+		}
+		status = leastCompleteRevocation(status, revocation)
+	}
+	if _, err = EndEmptySession(ctx, sessionID); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// EndEmptySession removes what is left of the session once no accounts are logged in to it, and reports
+// whether it did.
+func EndEmptySession(ctx context.Context, sessionID string) (bool, error) {
+	accounts, err := GetLoggedInProviders(sessionID)
+	if err != nil {
+		return false, err
+	}
+	if len(accounts) > 0 {
+		return false, nil
+	}
+	err = redisclient.Client.Del(ctx, constructPrimaryAccountKey(sessionID), constructSessionUserKey(sessionID)).Err()
+	if err != nil {
+		return false, err
+	}
+	slog.Info(ctx, "Ended session", map[string]interface{}{
+		"session_id": sessionID,
+	})
+	return true, nil
+}
+
+// revokeAccountToken revokes a logged-out account's token. A linked account's token is shared with the
+// user's identity token and offline grant, which are dropped with it, unless linked tokens are retained.
+func revokeAccountToken(ctx context.Context, provider string, authData *AuthData) string {
+	if _, supported := config.GetProviderRevocation(provider); !supported {
+		return RevocationLocalOnly
+	}
+	logParams := map[string]interface{}{
+		"provider": provider,
+		"user_id":  authData.UserID,
+	}
+	ownerID, err := getIdentityOwner(ctx, provider, authData.UserID)
+	if err != nil {
+		slog.Error(ctx, "Failed to check identity, keeping the token", err, logParams)
+		return RevocationRetained
+	}
+	if ownerID == "" {
+		return RevokeToken(ctx, provider, authData.Token)
+	}
+
+	shared, err := isSharedIdentityToken(ctx, ownerID, provider, authData)
+	if err != nil {
+		slog.Error(ctx, "Failed to check identity token, keeping the token", err, logParams)
+		return RevocationRetained
+	}
+	if shared && config.GetRetainLinkedTokens() {
+		return RevocationRetained
+	}
+	if shared {
+		// The revoked token would otherwise be restored into new sessions and used by backend jobs.
+		if err = revokeOfflineGrant(ctx, ownerID, provider, authData.UserID); err != nil {
+			slog.Error(ctx, "Failed to revoke offline grant", err, logParams)
+		}
+		if err = redisclient.Client.Del(ctx, constructIdentityTokenKey(provider, authData.UserID)).Err(); err != nil {
+			slog.Error(ctx, "Failed to delete identity token", err, logParams)
+		}
+	}
+	return RevokeToken(ctx, provider, authData.Token)
+}
+
+// isSharedIdentityToken reports whether the session's token is the one held by the linked account's
+// identity token or the user's offline grant.
+func isSharedIdentityToken(ctx context.Context, ownerID, provider string, authData *AuthData) (bool, error) {
+	value := tokenValue(authData.Token)
+	identityToken, found, err := getIdentityToken(ctx, provider, authData.UserID)
+	if err != nil {
+		return false, err
+	}
+	if found && tokenValue(identityToken.Token) == value {
+		return true, nil
+	}
+	grant, found, err := getOfflineGrant(ctx, ownerID, provider)
+	if err != nil {
+		return false, err
+	}
+	return found && grant.UserID == authData.UserID && tokenValue(grant.Token) == value, nil
+}
+
+// tokenValue returns the token's refresh token, or its access token if it has none, which identifies
+// the grant it belongs to.
+func tokenValue(token *oauth2.Token) string {
+	if token.RefreshToken != "" {
+		return token.RefreshToken
+	}
+	return token.AccessToken
+}
+
+func revocationWithoutToken(provider string) string {
+	if _, supported := config.GetProviderRevocation(provider); !supported {
+		return RevocationLocalOnly
+	}
+	return RevocationRevoked
+}
+
+// leastCompleteRevocation returns whichever status leaves more of the tokens valid at the provider.
+func leastCompleteRevocation(a, b string) string {
+	rank := map[string]int{
+		RevocationRevoked:   0,
+		RevocationLocalOnly: 1,
+		RevocationRetained:  2,
+		RevocationPending:   3,
+		RevocationFailed:    4,
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// RevokeToken revokes the token at the provider, preferring the refresh token, which providers revoke
+// together with the access tokens issued from it. A revocation that fails because the provider could
+// not be reached is queued and retried in the background.
+func RevokeToken(ctx context.Context, provider string, token *oauth2.Token) string {
+	revocation, supported := config.GetProviderRevocation(provider)
+	if !supported {
+		return RevocationLocalOnly
+	}
+	value, hint := token.RefreshToken, "refresh_token"
+	if value == "" {
+		value, hint = token.AccessToken, "access_token"
+	}
+	if value == "" {
+		return RevocationRevoked
+	}
+
+	err := revokeAtProvider(ctx, provider, revocation, value, hint)
+	if err == nil {
+		slog.Info(ctx, "Revoked token at provider", map[string]interface{}{
+			"provider": provider,
+		})
+		return RevocationRevoked
+	}
+	slog.Error(ctx, "Failed to revoke token at provider", err, map[string]interface{}{
+		"provider": provider,
+	})
+	if errors.Is(err, errRevocationRejected) {
+		return RevocationFailed
+	}
+
+	pending := pendingRevocation{
+		ID:            uuid.New().String(),
+		Provider:      provider,
+		Token:         value,
+		TokenTypeHint: hint,
+		Attempts:      1,
+	}
+	if err = queueRevocation(ctx, pending); err != nil {
+		slog.Error(ctx, "Failed to queue token revocation", err, map[string]interface{}{
+			"provider": provider,
+		})
+		return RevocationFailed
+	}
+	return RevocationPending
+}
+
+// revokeAtProvider posts the token to the provider's RFC 7009 revocation endpoint.
+func revokeAtProvider(ctx context.Context, provider string, revocation *config.RevocationConfig, token, tokenTypeHint string) error {
+	oauthConfig, exists := config.Providers[provider]
+	if !exists {
+		return fmt.Errorf("%w: unsupported provider %s", errRevocationRejected, provider)
+	}
+
+	form := map[string]string{
+		"token":           token,
+		"token_type_hint": tokenTypeHint,
+	}
+	request := getClient().R().SetContext(ctx)
+	if revocation.AuthStyle == oauth2.AuthStyleInHeader {
+		request.SetBasicAuth(url.QueryEscape(oauthConfig.ClientID), url.QueryEscape(oauthConfig.ClientSecret))
+	} else {
+		form["client_id"] = oauthConfig.ClientID
+		form["client_secret"] = oauthConfig.ClientSecret
+	}
+
+	resp, err := request.SetFormData(form).Post(revocation.URL)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	switch {
+	case resp.StatusCode() == http.StatusOK:
+		return nil
+	case resp.StatusCode() == http.StatusTooManyRequests || resp.StatusCode() >= http.StatusInternalServerError:
+		return fmt.Errorf("provider returned status: %d", resp.StatusCode())
+	default:
+		return fmt.Errorf("%w: provider returned status %d", errRevocationRejected, resp.StatusCode())
+	}
+}
+
+// queueRevocation schedules the revocation's next attempt, with the delay doubling after each attempt.
+func queueRevocation(ctx context.Context, pending pendingRevocation) error {
+	member, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	due := time.Now().Add(revocationRetryDelay << (pending.Attempts - 1))
+	return redisclient.Client.ZAdd(ctx, revocationQueueKey, redis.Z{
+		Score:  float64(due.Unix()),
+		Member: member,
+	}).Err()
+}
+
+// RetryRevocations retries the queued revocations that are due. Each is claimed by removing it from the
+// queue, so replicas never retry the same revocation twice.
+func RetryRevocations(ctx context.Context) error {
+	due, err := redisclient.Client.ZRangeByScore(ctx, revocationQueueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: revocationRetryBatch,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, member := range due {
+		claimed, err := redisclient.Client.ZRem(ctx, revocationQueueKey, member).Result()
+		if err != nil {
+			return err
+		}
+		if claimed == 0 {
+			continue // Another replica is retrying it.
+		}
+		var pending pendingRevocation
+		if err = json.Unmarshal([]byte(member), &pending); err != nil {
+			continue
+		}
+		retryRevocation(ctx, pending)
+	}
+	return nil
+}
+
+func retryRevocation(ctx context.Context, pending pendingRevocation) {
+	logParams := map[string]interface{}{
+		"provider": pending.Provider,
+		"attempts": pending.Attempts + 1,
+	}
+	revocation, supported := config.GetProviderRevocation(pending.Provider)
+	if !supported {
+		slog.Error(ctx, "Dropping revocation for provider without revocation support", errRevocationRejected, logParams)
+		return
+	}
+
+	err := revokeAtProvider(ctx, pending.Provider, revocation, pending.Token, pending.TokenTypeHint)
+	if err == nil {
+		slog.Info(ctx, "Revoked token at provider on retry", logParams)
+		return
+	}
+	pending.Attempts++
+	if errors.Is(err, errRevocationRejected) || pending.Attempts >= maxRevocationAttempts {
+		slog.Error(ctx, "Giving up revoking token at provider", err, logParams)
+		return
+	}
+	slog.Error(ctx, "Failed to revoke token at provider, will retry", err, logParams)
+	if err = queueRevocation(ctx, pending); err != nil {
+		slog.Error(ctx, "Failed to queue token revocation", err, logParams)
+	}
+}
+
+// WatchRevocationRetries retries queued revocations as they fall due.
+func WatchRevocationRetries(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := RetryRevocations(context.Background()); err != nil {
+			slog.Error(context.Background(), "Failed to retry token revocations", err, nil)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"auth-service/redisclient"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/monzo/slog"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrUserNotFound is returned when the session does not belong to an internal user.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrIdentityLinked is returned when a provider identity already belongs to another user.
+	ErrIdentityLinked = errors.New("identity is linked to another user")
+	// ErrIdentityNotFound is returned when the identity is not linked to the session's user.
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrLastIdentity is returned when unlinking would leave the user without any identity to log in with.
+	ErrLastIdentity = errors.New("cannot unlink the user's only identity")
+)
+
+// User is a durable internal user, which outlives sessions. Any of its identities logs in as the user.
+type User struct {
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Identities []Identity `json:"identities"`
+}
+
+// Identity is a provider account linked to a user, keyed by provider and provider user ID.
+type Identity struct {
+	Provider    string    `json:"provider"`
+	UserID      string    `json:"user_id"`
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	LinkedAt    time.Time `json:"linked_at"`
+}
+
+func constructUserKey(userID string) string {
+	return "user:" + userID
+}
+
+// constructUserIdentitiesKey is the Redis hash of the user's identities, keyed by provider and provider user ID.
+func constructUserIdentitiesKey(userID string) string {
+	return "user_identities:" + userID
+}
+
+// constructIdentityKey maps a provider identity to the user it is linked to.
+func constructIdentityKey(provider, providerUserID string) string {
+	return "identity:" + provider + ":" + providerUserID
+}
+
+// constructIdentityTokenKey holds the latest token of a linked identity, restored into the user's new sessions.
+func constructIdentityTokenKey(provider, providerUserID string) string {
+	return "identity_token:" + provider + ":" + providerUserID
+}
+
+func constructSessionUserKey(sessionID string) string {
+	return "session_user:" + sessionID
+}
+
+func identityField(provider, providerUserID string) string {
+	return provider + ":" + providerUserID
+}
+
+// ResolveSessionUser attaches the session to the internal user of an account that just logged in.
+// An identity seen for the first time creates a new user, unless link is set, in which case it is
+// linked to the session's user. An identity that is linked already resolves to its user. When the
+// session belongs to that user, all of the user's other linked accounts are restored into it.
+func ResolveSessionUser(ctx context.Context, sessionID, provider, providerUserID string, link bool) (*User, error) {
+	authData, found := GetAuthToken(sessionID, provider, providerUserID)
+	if !found {
+		return nil, ErrTokenNotFound
+	}
+	sessionUserID, err := getSessionUserID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ownerID, err := getIdentityOwner(ctx, provider, providerUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := ownerID
+	switch {
+	case link:
+		if sessionUserID == "" {
+			if sessionUserID, err = createUser(ctx); err != nil {
+				return nil, err
+			}
+		}
+		if ownerID != "" && ownerID != sessionUserID {
+			return nil, ErrIdentityLinked
+		}
+		userID = sessionUserID
+	case ownerID == "":
+		if userID, err = createUser(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if ownerID == "" {
+		if err = claimIdentity(ctx, userID, provider, authData); err != nil {
+			return nil, err
+		}
+	}
+	if sessionUserID == "" {
+		sessionUserID = userID
+	}
+	if err = setSessionUser(ctx, sessionID, sessionUserID); err != nil {
+		return nil, err
+	}
+	if sessionUserID == userID {
+		if err = restoreIdentities(ctx, sessionID, userID); err != nil {
+			return nil, err
+		}
+	}
+	return GetUser(ctx, userID)
+}
+
+// CheckIdentityLinkable returns ErrIdentityLinked when linking the identity to the session's user
+// would take it from another user.
+func CheckIdentityLinkable(ctx context.Context, sessionID, provider, providerUserID string) error {
+	ownerID, err := getIdentityOwner(ctx, provider, providerUserID)
+	if err != nil || ownerID == "" {
+		return err
+	}
+	sessionUserID, err := getSessionUserID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if ownerID != sessionUserID {
+		return ErrIdentityLinked
+	}
+	return nil
+}
+
+// LinkIdentity links an account in the session to the session's user, creating the user for sessions
+// that predate internal users.
+func LinkIdentity(ctx context.Context, sessionID, provider, providerUserID string) (*User, error) {
+	return ResolveSessionUser(ctx, sessionID, provider, providerUserID, true)
+}
+
+// UnlinkIdentity removes an identity from the session's user, revokes its offline grant and revokes its
+// tokens at the provider, logging the account out of the session. Later logins with it no longer
+// resolve to the user.
+func UnlinkIdentity(ctx context.Context, sessionID, provider, providerUserID string) (*User, error) {
+	userID, err := getSessionUserID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, ErrUserNotFound
+	}
+	ownerID, err := getIdentityOwner(ctx, provider, providerUserID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != userID {
+		return nil, ErrIdentityNotFound
+	}
+	count, err := redisclient.Client.HLen(ctx, constructUserIdentitiesKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if count <= 1 {
+		return nil, ErrLastIdentity
+	}
+
+	// The grant is revoked first, so a failure leaves the identity linked and the unlink can be retried.
+	if err = revokeOfflineGrant(ctx, userID, provider, providerUserID); err != nil {
+		return nil, err
+	}
+	identityToken, _, err := getIdentityToken(ctx, provider, providerUserID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = redisclient.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, constructIdentityKey(provider, providerUserID), constructIdentityTokenKey(provider, providerUserID))
+		pipe.HDel(ctx, constructUserIdentitiesKey(userID), identityField(provider, providerUserID))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info(ctx, "Unlinked identity", map[string]interface{}{
+		"internal_user_id": userID,
+		"provider":         provider,
+		"user_id":          providerUserID,
+	})
+
+	// Nothing the user keeps relies on the account's tokens any more, so they are revoked at the
+	// provider and the account is logged out of the session.
+	var tokens []*oauth2.Token
+	if identityToken != nil {
+		tokens = append(tokens, identityToken.Token)
+	}
+	if sessionToken, found := GetAuthToken(sessionID, provider, providerUserID); found &&
+		(identityToken == nil || sessionToken.Token.RefreshToken != identityToken.Token.RefreshToken) {
+		tokens = append(tokens, sessionToken.Token)
+	}
+	if err = DeleteAuthToken(sessionID, provider, providerUserID); err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		RevokeToken(ctx, provider, token)
+	}
+	return GetUser(ctx, userID)
+}
+
+// GetSessionUser returns the internal user the session belongs to, or ErrUserNotFound.
+func GetSessionUser(ctx context.Context, sessionID string) (*User, error) {
+	userID, err := getSessionUserID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, ErrUserNotFound
+	}
+	return GetUser(ctx, userID)
+}
+
+// GetUser returns the user with its identities in the order they were linked.
+func GetUser(ctx context.Context, userID string) (*User, error) {
+	userJSON, err := redisclient.Client.Get(ctx, constructUserKey(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var user User
+	if err = json.Unmarshal([]byte(userJSON), &user); err != nil {
+		return nil, err
+	}
+
+	user.Identities, err = getUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func getUserIdentities(ctx context.Context, userID string) ([]Identity, error) {
+	fields, err := redisclient.Client.HGetAll(ctx, constructUserIdentitiesKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	identities := []Identity{}
+	for _, identityJSON := range fields {
+		var identity Identity
+		if err := json.Unmarshal([]byte(identityJSON), &identity); err != nil {
+			continue
+		}
+		identities = append(identities, identity)
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		if !identities[i].LinkedAt.Equal(identities[j].LinkedAt) {
+			return identities[i].LinkedAt.Before(identities[j].LinkedAt)
+		}
+		return identityField(identities[i].Provider, identities[i].UserID) < identityField(identities[j].Provider, identities[j].UserID)
+	})
+	return identities, nil
+}
+
+func getSessionUserID(ctx context.Context, sessionID string) (string, error) {
+	userID, err := redisclient.Client.Get(ctx, constructSessionUserKey(sessionID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return userID, err
+}
+
+func setSessionUser(ctx context.Context, sessionID, userID string) error {
+	return redisclient.Client.Set(ctx, constructSessionUserKey(sessionID), userID, refreshableAuthDataTTL).Err()
+}
+
+func getIdentityOwner(ctx context.Context, provider, providerUserID string) (string, error) {
+	userID, err := redisclient.Client.Get(ctx, constructIdentityKey(provider, providerUserID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return userID, err
+}
+
+func createUser(ctx context.Context) (string, error) {
+	user := User{
+		ID:        uuid.New().String(),
+		CreatedAt: time.Now().UTC(),
+	}
+	userJSON, err := json.Marshal(user)
+	if err != nil {
+		return "", err
+	}
+	if err = redisclient.Client.Set(ctx, constructUserKey(user.ID), userJSON, 0).Err(); err != nil {
+		return "", err
+	}
+
+	slog.Info(ctx, "Created user", map[string]interface{}{
+		"internal_user_id": user.ID,
+	})
+	return user.ID, nil
+}
+
+// claimIdentity links the identity to the user. Identities are claimed atomically, so one racing
+// login cannot link an identity to two users.
+func claimIdentity(ctx context.Context, userID, provider string, authData *AuthData) error {
+	claimed, err := redisclient.Client.SetNX(ctx, constructIdentityKey(provider, authData.UserID), userID, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		ownerID, err := getIdentityOwner(ctx, provider, authData.UserID)
+		if err != nil {
+			return err
+		}
+		if ownerID != userID {
+			return ErrIdentityLinked
+		}
+	}
+
+	identityJSON, err := json.Marshal(Identity{
+		Provider:    provider,
+		UserID:      authData.UserID,
+		DisplayName: authData.DisplayName,
+		Email:       authData.Email,
+		LinkedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if err = redisclient.Client.HSet(ctx, constructUserIdentitiesKey(userID), identityField(provider, authData.UserID), identityJSON).Err(); err != nil {
+		return err
+	}
+	if err = storeIdentityToken(ctx, provider, authData); err != nil {
+		return err
+	}
+	if err = syncOfflineGrant(ctx, userID, provider, authData); err != nil {
+		return err
+	}
+
+	slog.Info(ctx, "Linked identity", map[string]interface{}{
+		"internal_user_id": userID,
+		"provider":         provider,
+		"user_id":          authData.UserID,
+	})
+	return nil
+}
+
+// restoreIdentities stores the latest token of each of the user's linked accounts missing from the
+// session, applying each account's session limit. Accounts the limit rejects are left out.
+func restoreIdentities(ctx context.Context, sessionID, userID string) error {
+	identities, err := getUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		exists, err := redisclient.Client.Exists(ctx, constructRedisKey(sessionID, identity.Provider, identity.UserID)).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			continue
+		}
+		authData, found, err := getIdentityToken(ctx, identity.Provider, identity.UserID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue // The account's token has expired; it must log in again.
+		}
+		// A running mass revocation may not have reached the identity token yet.
+		suspended, err := IsLoginSuspended(ctx, identity.Provider, authData.ClientID)
+		if err != nil {
+			return err
+		}
+		if suspended {
+			slog.Info(ctx, "Skipped restoring linked account during mass revocation", map[string]interface{}{
+				"session_id":       sessionID,
+				"internal_user_id": userID,
+				"provider":         identity.Provider,
+				"user_id":          identity.UserID,
+			})
+			continue
+		}
+		// Restored accounts count towards their session limit like accounts that log in.
+		err = EnforceSessionLimit(ctx, identity.Provider, identity.UserID, sessionID, authData.Token, func() error {
+			return saveAuthData(sessionID, identity.Provider, authData)
+		})
+		if errors.Is(err, ErrSessionLimitReached) {
+			slog.Info(ctx, "Skipped restoring linked account at its session limit", map[string]interface{}{
+				"session_id":       sessionID,
+				"internal_user_id": userID,
+				"provider":         identity.Provider,
+				"user_id":          identity.UserID,
+			})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		slog.Info(ctx, "Restored linked account into session", map[string]interface{}{
+			"session_id":       sessionID,
+			"internal_user_id": userID,
+			"provider":         identity.Provider,
+			"user_id":          identity.UserID,
+		})
+	}
+	return nil
+}
+
+// syncIdentityToken keeps the durable copies of a linked account's token, its identity token and
+// offline grant, up to date with the session's.
+func syncIdentityToken(ctx context.Context, provider string, authData *AuthData) error {
+	ownerID, err := getIdentityOwner(ctx, provider, authData.UserID)
+	if err != nil || ownerID == "" {
+		return err
+	}
+	if err = storeIdentityToken(ctx, provider, authData); err != nil {
+		return err
+	}
+	return syncOfflineGrant(ctx, ownerID, provider, authData)
+}
+
+func storeIdentityToken(ctx context.Context, provider string, authData *AuthData) error {
+	authDataJSON, err := json.Marshal(authData)
+	if err != nil {
+		return err
+	}
+	return redisclient.Client.Set(ctx, constructIdentityTokenKey(provider, authData.UserID), authDataJSON, authDataTTL(authData.Token)).Err()
+}
+
+func getIdentityToken(ctx context.Context, provider, providerUserID string) (*AuthData, bool, error) {
+	authDataJSON, err := redisclient.Client.Get(ctx, constructIdentityTokenKey(provider, providerUserID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var authData AuthData
+	if err = json.Unmarshal([]byte(authDataJSON), &authData); err != nil {
+		return nil, false, err
+	}
+	return &authData, true, nil
+}
+
+// adoptIdentityToken picks up a linked account's token that another of the user's sessions, or its
+// offline grant, has refreshed, since they share one refresh token. It reports whether the adopted
+// access token is still valid; otherwise authData is left holding the latest refresh token to refresh
+// with. The caller stores the adopted token.
+func adoptIdentityToken(ctx context.Context, provider string, authData *AuthData, staleAccessToken string) (bool, error) {
+	latest, found, err := getIdentityToken(ctx, provider, authData.UserID)
+	if err != nil || !found || latest.Token.AccessToken == staleAccessToken {
+		return false, err
+	}
+	authData.Token = latest.Token
+	authData.Scopes = latest.Scopes
+	authData.LastRefreshedAt = latest.LastRefreshedAt
+	if !latest.Token.Valid() {
+		return false, nil
+	}
+	authData.ReauthRequired = false
+	return true, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"auth-service/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useClientRegistry writes the registry to a temporary file and points CLIENT_REGISTRY_FILE at it.
+func useClientRegistry(t *testing.T, registry string) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	assert.NoError(t, os.WriteFile(path, []byte(registry), 0o600))
+	os.Setenv("CLIENT_REGISTRY_FILE", path)
+	t.Cleanup(func() { os.Unsetenv("CLIENT_REGISTRY_FILE") })
+}
+
+func Test_ReloadClients_ShouldDefaultToBrowserClients(t *testing.T) {
+	useClientRegistry(t, `[
+		{"id": "web", "redirect_uris": ["https://app.example.com/"]},
+		{"id": "server", "type": "backend", "redirect_uris": ["https://api.example.com/oauth"], "response_mode": "query"}
+	]`)
+	assert.NoError(t, config.ReloadClients())
+
+	web, exists := config.GetClient("web")
+	assert.True(t, exists)
+	assert.Equal(t, config.ClientTypeBrowser, web.Type)
+	assert.False(t, web.ReceivesRefreshTokens())
+
+	server, exists := config.GetClient("server")
+	assert.True(t, exists)
+	assert.True(t, server.ReceivesRefreshTokens())
+}
+
+func Test_ReloadClients_BackendClientWithCookieResponseMode_ShouldFail(t *testing.T) {
+	useClientRegistry(t, `[{"id": "server", "type": "backend", "redirect_uris": ["https://api.example.com/oauth"]}]`)
+
+	err := config.ReloadClients()
+	assert.ErrorContains(t, err, "must use response_mode query")
+}
+
+func Test_ReloadClients_UnknownClientType_ShouldFail(t *testing.T) {
+	useClientRegistry(t, `[{"id": "web", "type": "mobile", "redirect_uris": ["https://app.example.com/"]}]`)
+
+	err := config.ReloadClients()
+	assert.ErrorContains(t, err, "unsupported type")
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"auth-service/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func Test_MatchesOriginPattern_ShouldMatchSubdomainsOnly(t *testing.T) {
+	cors := &config.CORSConfig{OriginPatterns: []string{"https://*.example.com"}}
+
+	assert.True(t, cors.MatchesOriginPattern("https://app.example.com"))
+	assert.True(t, cors.MatchesOriginPattern("https://a.b.example.com"))
+	assert.True(t, cors.MatchesOriginPattern("HTTPS://APP.EXAMPLE.COM"))
+
+	assert.False(t, cors.MatchesOriginPattern("https://example.com"))
+	assert.False(t, cors.MatchesOriginPattern("https://evilexample.com"))
+	assert.False(t, cors.MatchesOriginPattern("http://app.example.com"))
+	assert.False(t, cors.MatchesOriginPattern("https://app.example.com:8443"))
+	assert.False(t, cors.MatchesOriginPattern("https://evil.com/.example.com"))
+}
+
+func Test_IsCredentialedOrigin_ShouldOnlyAllowListedOrigins(t *testing.T) {
+	cors := &config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		OriginPatterns: []string{"https://*.example.com"},
+	}
+
+	assert.True(t, cors.IsCredentialedOrigin("https://app.example.com"))
+	assert.False(t, cors.IsCredentialedOrigin("https://other.example.com"))
+}
+
+func Test_ReloadClients_ShouldLoadCORSConfigFromEnv(t *testing.T) {
+	os.Unsetenv("CLIENT_REGISTRY_FILE")
+	os.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com/, https://*.staging.example.com")
+	defer os.Unsetenv("CORS_ALLOWED_ORIGINS")
+	os.Setenv("CORS_ALLOWED_METHODS", "GET,POST,DELETE")
+	defer os.Unsetenv("CORS_ALLOWED_METHODS")
+	os.Setenv("CORS_MAX_AGE", "600")
+	defer os.Unsetenv("CORS_MAX_AGE")
+
+	assert.NoError(t, config.ReloadClients())
+
+	cors := config.GetCORSConfig()
+	assert.Equal(t, []string{"https://app.example.com"}, cors.AllowedOrigins)
+	assert.Equal(t, []string{"https://*.staging.example.com"}, cors.OriginPatterns)
+	assert.Equal(t, []string{"GET", "POST", "DELETE"}, cors.AllowedMethods)
+	assert.Equal(t, 600, cors.MaxAge)
+}
+
+func Test_ReloadClients_InvalidOrigin_ShouldKeepCurrentConfig(t *testing.T) {
+	os.Unsetenv("CLIENT_REGISTRY_FILE")
+	os.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	assert.NoError(t, config.ReloadClients())
+
+	os.Setenv("CORS_ALLOWED_ORIGINS", "https://app.*.example.com")
+	defer os.Unsetenv("CORS_ALLOWED_ORIGINS")
+
+	assert.Error(t, config.ReloadClients())
+	assert.Equal(t, []string{"https://app.example.com"}, config.GetCORSConfig().AllowedOrigins)
+}
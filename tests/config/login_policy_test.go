@@ -0,0 +1,82 @@
+package config
+
+import (
+	"auth-service/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useLoginPolicy writes the policy to a temporary file and points LOGIN_POLICY_FILE at it.
+func useLoginPolicy(t *testing.T, policy string) {
+	path := filepath.Join(t.TempDir(), "login_policy.json")
+	assert.NoError(t, os.WriteFile(path, []byte(policy), 0o600))
+	os.Setenv("LOGIN_POLICY_FILE", path)
+	t.Cleanup(func() {
+		os.Unsetenv("LOGIN_POLICY_FILE")
+		config.ReloadLoginPolicy()
+	})
+}
+
+func Test_LoginPolicy_WithoutFile_ShouldAllowEveryLogin(t *testing.T) {
+	assert.NoError(t, config.ReloadLoginPolicy())
+
+	decision := config.GetLoginPolicy().Evaluate(config.LoginAttributes{Provider: "spotify", UserID: "user1"})
+	assert.True(t, decision.Allowed)
+}
+
+func Test_LoginPolicy_Unless_ShouldExemptMatchingLogins(t *testing.T) {
+	useLoginPolicy(t, `{"rules": [
+		{"name": "staff-only", "effect": "deny", "reason": "staff_only", "providers": ["spotify"], "unless": {"email_domains": ["example.com"]}}
+	]}`)
+	assert.NoError(t, config.ReloadLoginPolicy())
+	policy := config.GetLoginPolicy()
+
+	denied := policy.Evaluate(config.LoginAttributes{Provider: "spotify", UserID: "user1", Email: "user@gmail.com"})
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, "staff-only", denied.Rule)
+	assert.Equal(t, "staff_only", denied.Reason)
+
+	assert.True(t, policy.Evaluate(config.LoginAttributes{Provider: "spotify", UserID: "user2", Email: "user@Example.com"}).Allowed)
+	assert.True(t, policy.Evaluate(config.LoginAttributes{Provider: "tidal", UserID: "user3", Email: "user@gmail.com"}).Allowed)
+}
+
+func Test_LoginPolicy_EmailVerified_ShouldNotMatchWhenUnreported(t *testing.T) {
+	useLoginPolicy(t, `{"rules": [{"effect": "deny", "reason": "email_unverified", "email_verified": false}]}`)
+	assert.NoError(t, config.ReloadLoginPolicy())
+	policy := config.GetLoginPolicy()
+	verified, unverified := true, false
+
+	assert.False(t, policy.Evaluate(config.LoginAttributes{Provider: "tidal", EmailVerified: &unverified}).Allowed)
+	assert.True(t, policy.Evaluate(config.LoginAttributes{Provider: "tidal", EmailVerified: &verified}).Allowed)
+	assert.True(t, policy.Evaluate(config.LoginAttributes{Provider: "spotify"}).Allowed)
+}
+
+func Test_LoginPolicy_FirstMatchingRule_ShouldDecide(t *testing.T) {
+	useLoginPolicy(t, `{"default": "deny", "rules": [
+		{"name": "allowlist", "effect": "allow", "user_ids": ["user1"]},
+		{"name": "blocked-country", "effect": "deny", "countries": ["XX"]}
+	]}`)
+	assert.NoError(t, config.ReloadLoginPolicy())
+	policy := config.GetLoginPolicy()
+
+	assert.True(t, policy.Evaluate(config.LoginAttributes{Provider: "spotify", UserID: "user1", Country: "XX"}).Allowed)
+
+	denied := policy.Evaluate(config.LoginAttributes{Provider: "spotify", UserID: "user2", Country: "xx"})
+	assert.Equal(t, config.LoginDecision{Rule: "blocked-country", Reason: "login_denied"}, denied)
+
+	assert.Equal(t, config.LoginDecision{Reason: "login_denied"}, policy.Evaluate(config.LoginAttributes{Provider: "spotify", UserID: "user3"}))
+}
+
+func Test_ReloadLoginPolicy_InvalidRule_ShouldKeepCurrentPolicy(t *testing.T) {
+	useLoginPolicy(t, `{"default": "deny"}`)
+	assert.NoError(t, config.ReloadLoginPolicy())
+
+	useLoginPolicy(t, `{"rules": [{"name": "bad", "effect": "block"}]}`)
+	assert.ErrorContains(t, config.ReloadLoginPolicy(), `invalid effect "block"`)
+	assert.False(t, config.GetLoginPolicy().Evaluate(config.LoginAttributes{Provider: "spotify"}).Allowed)
+
+	useLoginPolicy(t, `{"rules": [{"effect": "deny", "reason": "Not Allowed"}]}`)
+	assert.ErrorContains(t, config.ReloadLoginPolicy(), "invalid reason")
+}
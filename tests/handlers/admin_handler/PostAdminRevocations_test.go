@@ -0,0 +1,148 @@
+package admin_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/redisclient"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// adminRequest sends a request to the admin API as an internal caller.
+func adminRequest(t *testing.T, method, url string, body interface{}) *http.Response {
+	var payload bytes.Buffer
+	if body != nil {
+		assert.NoError(t, json.NewEncoder(&payload).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Api-Key", os.Getenv("INTERNAL_API_KEY"))
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+// waitForRevocation polls the revocation until it stops running.
+func waitForRevocation(t *testing.T, serverURL, id string) generated.MassRevocation {
+	var job generated.MassRevocation
+	assert.Eventually(t, func() bool {
+		resp := adminRequest(t, "GET", serverURL+"/admin/revocations/"+id, nil)
+		defer resp.Body.Close()
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		return job.Status != services.MassRevocationRunning
+	}, 10*time.Second, 100*time.Millisecond)
+	return job
+}
+
+func Test_PostAdminRevocations_WithoutInternalKey_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp, err := http.Post(setup.Server.URL+"/admin/revocations", "application/json", bytes.NewBufferString(`{"provider":"spotify"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func Test_PostAdminRevocations_ShouldDeleteEveryTokenOfTheProvider(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	firstSession, secondSession := uuid.New().String(), uuid.New().String()
+	spotifyUser := mocks.NewMockUser("spotify", "spotify-user", "Spotify User", "")
+	otherUser := mocks.NewMockUser("spotify", "other-user", "Other User", "")
+	tidalUser := mocks.NewMockUser("tidal", "tidal-user", "Tidal User", "")
+	assert.NoError(t, services.StoreAuthToken(firstSession, "spotify", spotifyUser, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(firstSession, "tidal", tidalUser, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(secondSession, "spotify", otherUser, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+
+	resp := adminRequest(t, "POST", setup.Server.URL+"/admin/revocations", generated.MassRevocationRequest{Provider: "spotify"})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var started generated.MassRevocation
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&started))
+
+	job := waitForRevocation(t, setup.Server.URL, started.Id)
+	assert.Equal(t, services.MassRevocationCompleted, job.Status)
+	assert.Equal(t, 2, job.Deleted)
+	assert.NotNil(t, job.CompletedAt)
+
+	_, found := services.GetAuthToken(firstSession, "spotify", "spotify-user")
+	assert.False(t, found)
+	_, found = services.GetAuthToken(secondSession, "spotify", "other-user")
+	assert.False(t, found)
+	_, found = services.GetAuthToken(firstSession, "tidal", "tidal-user")
+	assert.True(t, found, "Other providers' tokens should be kept")
+
+	// Logins are allowed again once the revocation has completed.
+	suspended, err := services.IsLoginSuspended(context.Background(), "spotify", "")
+	assert.NoError(t, err)
+	assert.False(t, suspended)
+}
+
+func Test_PostAdminRevocations_ShouldDropCachedAppToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// The app token was fetched with the provider credentials the revoked tokens were issued with.
+	ctx := context.Background()
+	assert.NoError(t, redisclient.Client.Set(ctx, "app_token:spotify", `{"access_token":"leaked-app-token"}`, time.Hour).Err())
+
+	resp := adminRequest(t, "POST", setup.Server.URL+"/admin/revocations", generated.MassRevocationRequest{Provider: "spotify"})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var started generated.MassRevocation
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&started))
+	waitForRevocation(t, setup.Server.URL, started.Id)
+
+	cached, err := redisclient.Client.Exists(ctx, "app_token:spotify").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, cached, "The cached app token should be dropped")
+}
+
+func Test_PostAdminRevocations_InProgress_ShouldSuspendLogins(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// A revocation of Spotify's tokens is still running.
+	assert.NoError(t, redisclient.Client.Set(context.Background(), "mass_revocation_active:spotify:*", "running-revocation", 0).Err())
+
+	resp := adminRequest(t, "POST", setup.Server.URL+"/admin/revocations", generated.MassRevocationRequest{Provider: "spotify"})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "revocation_in_progress", tests.DecodeProblem(t, resp).Code)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	login, err := client.Get(setup.Server.URL + "/auth/spotify/login?redirect_uri=http://localhost:3000/callback")
+	assert.NoError(t, err)
+	defer login.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, login.StatusCode)
+	assert.Equal(t, "login_suspended", tests.DecodeProblem(t, login).Code)
+}
+
+func Test_GetAdminRevocationsRevocationId_Unknown_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := adminRequest(t, "GET", setup.Server.URL+"/admin/revocations/unknown", nil)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "revocation_not_found", tests.DecodeProblem(t, resp).Code)
+}
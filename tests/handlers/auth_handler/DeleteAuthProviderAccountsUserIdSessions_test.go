@@ -0,0 +1,79 @@
+package auth_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+// signOutAccount calls the global sign-out endpoint, as an internal caller when internal is set.
+func signOutAccount(t *testing.T, serverURL, provider, userID string, internal bool) *http.Response {
+	req, err := http.NewRequest("DELETE", serverURL+"/auth/"+provider+"/accounts/"+userID+"/sessions", nil)
+	assert.NoError(t, err)
+	if internal {
+		req.Header.Set("X-Internal-Api-Key", os.Getenv("INTERNAL_API_KEY"))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func Test_DeleteAuthProviderAccountsUserIdSessions_WithoutInternalKey_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := signOutAccount(t, setup.Server.URL, "tidal", "tidal-user", false)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "unauthorized", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_DeleteAuthProviderAccountsUserIdSessions_ShouldSignOutEverySessionAndRevoke(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	ctx := context.Background()
+	var revoked []string
+	defer stubRevocation(t, "tidal", http.StatusOK, &revoked)()
+
+	// The account is linked to a user and logged in to two sessions, one of which has another account.
+	tidalUser := mocks.NewMockUser("tidal", "tidal-user", "Tidal User", "")
+	spotifyUser := mocks.NewMockUser("spotify", "spotify-user", "Spotify User", "")
+	firstSession, secondSession := uuid.New().String(), uuid.New().String()
+	assert.NoError(t, services.StoreAuthToken(firstSession, "tidal", tidalUser, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(firstSession, "spotify", spotifyUser, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(secondSession, "tidal", tidalUser, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+	user, err := services.ResolveSessionUser(ctx, firstSession, "tidal", "tidal-user", false)
+	assert.NoError(t, err)
+
+	resp := signOutAccount(t, setup.Server.URL, "tidal", "tidal-user", true)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result generated.AccountSignOut
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 2, result.Sessions)
+	assert.Equal(t, services.RevocationRevoked, result.Revocation)
+	// The sessions share one refresh token, which is revoked once.
+	assert.Equal(t, []string{"mock-refresh-token-tidal"}, revoked)
+
+	_, found := services.GetAuthToken(firstSession, "tidal", "tidal-user")
+	assert.False(t, found)
+	_, found = services.GetAuthToken(secondSession, "tidal", "tidal-user")
+	assert.False(t, found)
+	_, found = services.GetAuthToken(firstSession, "spotify", "spotify-user")
+	assert.True(t, found, "Other accounts should stay logged in")
+
+	_, _, err = services.GetOfflineToken(ctx, user.ID, "tidal")
+	assert.ErrorIs(t, err, services.ErrTokenNotFound)
+}
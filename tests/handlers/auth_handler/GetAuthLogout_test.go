@@ -0,0 +1,102 @@
+package auth_handler
+
+import (
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// sessionCookieFrom returns the session cookie the response set, if any.
+func sessionCookieFrom(resp *http.Response) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func Test_GetAuthLogout_ShouldLogOutSessionClearCookieAndRedirect(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sessionID := uuid.New().String()
+	spotifyUser := mocks.NewMockUser("spotify", "spotify-user", "Spotify User", "")
+	tidalUser := mocks.NewMockUser("tidal", "tidal-user", "Tidal User", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", spotifyUser, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", tidalUser, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+
+	reqURL, err := buildRequestURL(setup.Server.URL+"/auth/logout", "http://localhost:3000/signed-out")
+	assert.NoError(t, err)
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(createSessionRequest(t, "GET", reqURL.String(), sessionID))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "http://localhost:3000/signed-out", resp.Header.Get("Location"))
+	cookie := sessionCookieFrom(resp)
+	if assert.NotNil(t, cookie) {
+		assert.True(t, cookie.MaxAge < 0, "Session cookie should be expired")
+	}
+
+	accounts, err := services.GetLoggedInProviders(sessionID)
+	assert.NoError(t, err)
+	assert.Empty(t, accounts)
+}
+
+func Test_PostAuthLogout_InvalidRedirectURI_ShouldKeepSession(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sessionID := uuid.New().String()
+	user := mocks.NewMockUser("spotify", "spotify-user", "Spotify User", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", user, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+
+	reqURL, err := buildRequestURL(setup.Server.URL+"/auth/logout", "https://evil.com/phish")
+	assert.NoError(t, err)
+	resp, err := http.DefaultClient.Do(createSessionRequest(t, "POST", reqURL.String(), sessionID))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_redirect_uri", tests.DecodeProblem(t, resp).Code)
+	_, found := services.GetAuthToken(sessionID, "spotify", "spotify-user")
+	assert.True(t, found, "Session should not be logged out")
+}
+
+func Test_PostAuthProviderLogout_LastAccount_ShouldClearCookie(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sessionID := uuid.New().String()
+	spotifyUser := mocks.NewMockUser("spotify", "spotify-user", "Spotify User", "")
+	tidalUser := mocks.NewMockUser("tidal", "tidal-user", "Tidal User", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", spotifyUser, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", tidalUser, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+
+	// Another account remains, so the session and its cookie are kept.
+	resp, err := http.DefaultClient.Do(createSessionRequest(t, "POST", setup.Server.URL+"/auth/spotify/logout", sessionID))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, sessionCookieFrom(resp))
+
+	resp, err = http.DefaultClient.Do(createSessionRequest(t, "POST", setup.Server.URL+"/auth/tidal/logout", sessionID))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	cookie := sessionCookieFrom(resp)
+	if assert.NotNil(t, cookie) {
+		assert.True(t, cookie.MaxAge < 0, "Session cookie should be expired")
+	}
+}
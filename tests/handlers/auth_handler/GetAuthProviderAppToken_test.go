@@ -0,0 +1,109 @@
+package auth_handler
+
+import (
+	"auth-service/tests"
+	"auth-service/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func createAppTokenRequest(t *testing.T, url, apiKey string) *http.Request {
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	if apiKey != "" {
+		req.Header.Set("X-Internal-Api-Key", apiKey)
+	}
+	return req
+}
+
+func Test_GetAuthProviderAppToken_MissingAPIKey_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	req := createAppTokenRequest(t, setup.Server.URL+"/auth/spotify/app-token", "")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Internal API key is required")
+}
+
+func Test_GetAuthProviderAppToken_InvalidProvider_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	req := createAppTokenRequest(t, setup.Server.URL+"/auth/qobuz/app-token", os.Getenv("INTERNAL_API_KEY"))
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Unsupported provider")
+}
+
+func Test_GetAuthProviderAppToken_ConcurrentRequests_ShouldFetchOnceAndCache(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// Mock the client-credentials grant and count how often it is performed.
+	var fetches int32
+	originalFetch := utils.FetchClientCredentialsTokenFunc
+	utils.FetchClientCredentialsTokenFunc = func(oauthConfig *oauth2.Config) (*oauth2.Token, error) {
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(200 * time.Millisecond)
+		return &oauth2.Token{
+			AccessToken: "mock-app-access-token",
+			TokenType:   "Bearer",
+			Expiry:      time.Now().Add(time.Hour),
+		}, nil
+	}
+	defer func() { utils.FetchClientCredentialsTokenFunc = originalFetch }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req := createAppTokenRequest(t, setup.Server.URL+"/auth/spotify/app-token", os.Getenv("INTERNAL_API_KEY"))
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var response map[string]interface{}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			assert.Equal(t, "mock-app-access-token", response["access_token"])
+			assert.Equal(t, "Bearer", response["token_type"])
+			assert.InDelta(t, 3600, response["expires_in"], 5)
+		}()
+	}
+	wg.Wait()
+
+	// A later request is served from the cache as well.
+	req := createAppTokenRequest(t, setup.Server.URL+"/auth/spotify/app-token", os.Getenv("INTERNAL_API_KEY"))
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
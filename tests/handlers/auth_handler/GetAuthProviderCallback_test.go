@@ -4,15 +4,20 @@ import (
 	"auth-service/config"
 	"auth-service/services"
 	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/oauth2"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 // Helper to build callback URL with query params
@@ -28,6 +33,63 @@ func buildCallbackURL(baseURL, code, state string) (*url.URL, error) {
 	return reqURL, nil
 }
 
+// loginThroughCallback logs the Tidal account in through the OAuth callback, as a browser would, with
+// the provider issuing the refresh token. It returns the new session's ID.
+func loginThroughCallback(t *testing.T, setup *tests.TestSetup, userID, refreshToken string) string {
+	mockRedirectURI := setup.Server.URL + "/mock-callback"
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	t.Cleanup(func() { os.Unsetenv("ALLOWED_REDIRECT_DOMAINS") })
+
+	prefix := "/mock-oauth/" + uuid.New().String()
+	originalConfig := config.Providers["tidal"]
+	mockConfig := *originalConfig
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:  setup.Server.URL + prefix + "/authorize",
+		TokenURL: setup.Server.URL + prefix + "/token",
+	}
+	config.Providers["tidal"] = &mockConfig
+	originalGetProviderUserInfoURL := config.GetProviderUserInfoURL
+	config.GetProviderUserInfoURL = func(provider string) (string, error) {
+		return setup.Server.URL + prefix + "/me", nil
+	}
+	t.Cleanup(func() {
+		config.Providers["tidal"] = originalConfig
+		config.GetProviderUserInfoURL = originalGetProviderUserInfoURL
+	})
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post(prefix+"/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "access-%s", "refresh_token": %q, "expires_in": 3600, "token_type": "Bearer"}`, refreshToken, refreshToken)
+	})
+	router.Get(prefix+"/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data": {"id": %q, "attributes": {"username": "Tidal User", "email": "tidal@example.com"}}}`, userID)
+	})
+
+	stateToken := uuid.New().String()
+	assert.NoError(t, services.StorePKCEData(stateToken, "mock-code-verifier"))
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/tidal/callback", "mock-auth-code", stateToken+"|"+mockRedirectURI)
+	assert.NoError(t, err)
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	for _, c := range resp.Cookies() {
+		if c.Name == "session_id" {
+			return c.Value
+		}
+	}
+	t.Fatal("the callback did not set a session cookie")
+	return ""
+}
+
 // Test: Invalid Provider
 func Test_Callback_InvalidProvider_ShouldReturn400(t *testing.T) {
 	setup := tests.InitializeTestEnvironment(t)
@@ -129,6 +191,25 @@ func Test_Callback_MissingAuthorizationCode_ShouldReturn400(t *testing.T) {
 }
 
 // Test: Successful Callback Flow
+func Test_Callback_ExpiredLogin_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	defer os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	// No login was started with the state token, as when its PKCE data has expired.
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/spotify/callback", "mock-code", "expired-state|"+setup.Server.URL+"/mock-callback")
+	assert.NoError(t, err)
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "login_expired", problem.Code)
+}
+
 func Test_Callback_Spotify_SuccessfulFlow_ShouldRedirectAndStoreToken(t *testing.T) {
 	setup := tests.InitializeTestEnvironment(t)
 	defer setup.Cleanup()
@@ -342,3 +423,524 @@ func Test_Callback_Tidal_SuccessfulFlow_ShouldRedirectAndStoreToken(t *testing.T
 	assert.True(t, found)
 	assert.Equal(t, "mocked-access-token", token.Token.AccessToken)
 }
+
+func Test_Callback_SoundCloud_SuccessfulFlow_ShouldRedirectAndStoreToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// Define mock URLs.
+	mockAuthURL := setup.Server.URL + "/mock-oauth/authorize"
+	mockTokenURL := setup.Server.URL + "/mock-oauth/token"
+	mockRedirectURI := setup.Server.URL + "/mock-callback"
+	mockUserInfoURL := setup.Server.URL + "/mock-oauth/soundcloud-me"
+
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	defer os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	// Mock OAuth Config for SoundCloud, keeping its basic-auth client credentials style.
+	originalConfig := config.Providers["soundcloud"]
+	mockConfig := *originalConfig
+	mockConfig.RedirectURL = mockRedirectURI
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:   mockAuthURL,
+		TokenURL:  mockTokenURL,
+		AuthStyle: oauth2.AuthStyleInHeader,
+	}
+	config.Providers["soundcloud"] = &mockConfig
+
+	// Mock GetProviderUserInfoURL for soundcloud.
+	originalGetProviderUserInfoURL := config.GetProviderUserInfoURL
+	config.GetProviderUserInfoURL = func(provider string) (string, error) {
+		if provider == "soundcloud" {
+			return mockUserInfoURL, nil
+		}
+		return "", fmt.Errorf("provider not supported")
+	}
+	defer func() { config.GetProviderUserInfoURL = originalGetProviderUserInfoURL }()
+
+	// Store PKCE data using a state token.
+	stateToken := "mock-state"
+	codeVerifier := "mock-code-verifier"
+	err := services.StorePKCEData(stateToken, codeVerifier)
+	assert.NoError(t, err)
+
+	// Set up mock endpoints.
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	// Mock token exchange endpoint, which only accepts client credentials via basic auth.
+	router.Post("/mock-oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"access_token": "mocked-access-token",
+			"refresh_token": "mocked-refresh-token",
+			"expires_in": 3599,
+			"token_type": "Bearer"
+		}`))
+	})
+	// Mock soundcloud user info endpoint, which expects the OAuth authorization scheme.
+	router.Get("/mock-oauth/soundcloud-me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "OAuth mocked-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"id": 123456,
+			"urn": "soundcloud:users:123456",
+			"username": "SoundCloudUser",
+			"full_name": "SoundCloud User"
+		}`))
+	})
+
+	// Build callback URL with valid state and auth code.
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/soundcloud/callback", "mock-auth-code", stateToken+"|"+mockRedirectURI)
+	assert.NoError(t, err)
+
+	// Use a custom HTTP client that prevents following redirects.
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// Act: Call the callback endpoint.
+	resp, err := client.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Assert: Verify a temporary redirect to the frontend.
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	redirectLocation, err := resp.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, mockRedirectURI, redirectLocation.String())
+
+	// Verify that a session cookie was set.
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "session_id" {
+			cookie = c
+			break
+		}
+	}
+	assert.NotNil(t, cookie)
+
+	// Validate token storage in Redis for the soundcloud provider, keyed by the numeric user ID.
+	token, found := services.GetAuthToken(cookie.Value, "soundcloud", "123456")
+	assert.True(t, found)
+	assert.Equal(t, "mocked-access-token", token.Token.AccessToken)
+	assert.Equal(t, "SoundCloudUser", token.DisplayName)
+	assert.Empty(t, token.Email)
+}
+
+func Test_Callback_RedirectURINotRegisteredForClient_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// The login was started by the partner widget, so the global allowlist no longer applies.
+	stateToken := "mock-client-state"
+	err := services.SavePKCEData(stateToken, services.PKCEData{
+		CodeVerifier: "mock-code-verifier",
+		ClientID:     "partner-widget",
+	})
+	assert.NoError(t, err)
+
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/soundcloud/callback", "mock-auth-code", stateToken+"|http://localhost:3000/callback")
+	assert.NoError(t, err)
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), "invalid redirect URI")
+}
+
+func Test_Callback_ClientWithFragmentResponseMode_ShouldReturnSessionInFragment(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	mockTokenURL := setup.Server.URL + "/mock-oauth/token"
+	mockRedirectURI := setup.Server.URL + "/mock-callback"
+	mockUserInfoURL := setup.Server.URL + "/mock-oauth/me"
+
+	originalConfig := config.Providers["spotify"]
+	mockConfig := *originalConfig
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:  setup.Server.URL + "/mock-oauth/authorize",
+		TokenURL: mockTokenURL,
+	}
+	config.Providers["spotify"] = &mockConfig
+
+	originalGetProviderUserInfoURL := config.GetProviderUserInfoURL
+	config.GetProviderUserInfoURL = func(provider string) (string, error) {
+		return mockUserInfoURL, nil
+	}
+	defer func() { config.GetProviderUserInfoURL = originalGetProviderUserInfoURL }()
+
+	// The mobile app is registered with the fragment response mode.
+	stateToken := "mock-mobile-state"
+	err := services.SavePKCEData(stateToken, services.PKCEData{
+		CodeVerifier: "mock-code-verifier",
+		ClientID:     "mobile-app",
+	})
+	assert.NoError(t, err)
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post("/mock-oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"access_token": "mocked-access-token",
+			"refresh_token": "mocked-refresh-token",
+			"expires_in": 3600,
+			"token_type": "Bearer"
+		}`))
+	})
+	router.Get("/mock-oauth/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"id": "mock-user-id",
+			"display_name": "Mock User",
+			"email": "mockuser@googlemail.com"
+		}`))
+	})
+
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/spotify/callback", "mock-auth-code", stateToken+"|"+mockRedirectURI)
+	assert.NoError(t, err)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Assert: The session is handed over in the fragment instead of a cookie.
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Empty(t, resp.Cookies())
+
+	redirectLocation, err := resp.Location()
+	assert.NoError(t, err)
+	fragment, err := url.ParseQuery(redirectLocation.Fragment)
+	assert.NoError(t, err)
+	sessionID := fragment.Get("session_id")
+	assert.NotEmpty(t, sessionID)
+
+	token, found := services.GetAuthToken(sessionID, "spotify", "mock-user-id")
+	assert.True(t, found)
+	assert.Equal(t, "mocked-access-token", token.Token.AccessToken)
+}
+
+func Test_Callback_TokenExchangeFails_ShouldNotLeakProviderError(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	mockTokenURL := setup.Server.URL + "/mock-oauth/failing-token"
+
+	originalConfig := config.Providers["tidal"]
+	mockConfig := *originalConfig
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:   setup.Server.URL + "/mock-oauth/authorize",
+		TokenURL:  mockTokenURL,
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	config.Providers["tidal"] = &mockConfig
+
+	stateToken := "mock-failing-exchange-state"
+	err := services.StorePKCEData(stateToken, "mock-code-verifier")
+	assert.NoError(t, err)
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post("/mock-oauth/failing-token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant", "error_description": "internal provider detail"}`))
+	})
+
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/tidal/callback", "mock-auth-code", stateToken+"|http://localhost:3000/callback")
+	assert.NoError(t, err)
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusBadGateway, problem.Status)
+	assert.Equal(t, "provider_error", problem.Code)
+	assert.NotContains(t, *problem.Detail, "internal provider detail")
+}
+
+// startLinkingLogin signs a browser in with a tidal account and starts linking a Spotify account to it. It
+// returns the linking session and the user the session belongs to, and the callback URL the provider
+// sends the browser back to.
+func startLinkingLogin(t *testing.T, setup *tests.TestSetup) (string, *services.User, string) {
+	mockRedirectURI := setup.Server.URL + "/mock-callback"
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	t.Cleanup(func() { os.Unsetenv("ALLOWED_REDIRECT_DOMAINS") })
+
+	originalConfig := config.Providers["spotify"]
+	mockConfig := *originalConfig
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:  setup.Server.URL + "/mock-oauth/authorize",
+		TokenURL: setup.Server.URL + "/mock-oauth/link-token",
+	}
+	config.Providers["spotify"] = &mockConfig
+	t.Cleanup(func() { config.Providers["spotify"] = originalConfig })
+
+	originalGetProviderUserInfoURL := config.GetProviderUserInfoURL
+	config.GetProviderUserInfoURL = func(provider string) (string, error) {
+		return setup.Server.URL + "/mock-oauth/link-me", nil
+	}
+	t.Cleanup(func() { config.GetProviderUserInfoURL = originalGetProviderUserInfoURL })
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post("/mock-oauth/link-token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "linked-access-token", "refresh_token": "linked-refresh-token", "expires_in": 3600, "token_type": "Bearer"}`))
+	})
+	router.Get("/mock-oauth/link-me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "linked-spotify-user", "display_name": "Linked User", "email": "linked@example.com"}`))
+	})
+
+	// The browser is already signed in with a tidal account.
+	sessionID := "linking-session"
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", mocks.NewMockUser("tidal", "tidal-user", "Tidal User", ""), mocks.NewMockOAuth2Token("tidal", time.Hour)))
+	user, err := services.ResolveSessionUser(context.Background(), sessionID, "tidal", "tidal-user", false)
+	assert.NoError(t, err)
+
+	stateToken := "mock-link-state"
+	assert.NoError(t, services.SavePKCEData(stateToken, services.PKCEData{
+		CodeVerifier:  "mock-code-verifier",
+		LinkSessionID: sessionID,
+	}))
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/spotify/callback", "mock-auth-code", stateToken+"|"+mockRedirectURI)
+	assert.NoError(t, err)
+	return sessionID, user, reqURL.String()
+}
+
+func Test_Callback_LinkingLogin_ShouldJoinSessionAndLinkToItsUser(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID, user, callbackURL := startLinkingLogin(t, setup)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(createSessionRequest(t, "GET", callbackURL, sessionID))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	// The account joins the existing session instead of starting a new one.
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "session_id" {
+			cookie = c
+		}
+	}
+	if assert.NotNil(t, cookie) {
+		assert.Equal(t, sessionID, cookie.Value)
+	}
+	_, found := services.GetAuthToken(sessionID, "spotify", "linked-spotify-user")
+	assert.True(t, found)
+
+	linked, err := services.GetSessionUser(context.Background(), sessionID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, linked.ID)
+	assert.Len(t, linked.Identities, 2)
+}
+
+func Test_Callback_LinkingLoginFromAnotherBrowser_ShouldReturn403(t *testing.T) {
+	for name, cookie := range map[string]string{"missing cookie": "", "other session": "victim-session"} {
+		t.Run(name, func(t *testing.T) {
+			setup := tests.InitializeTestEnvironment(t)
+			defer setup.Cleanup()
+			sessionID, _, callbackURL := startLinkingLogin(t, setup)
+
+			// The link was started by one browser and its provider login finished by another.
+			req, err := http.NewRequest("GET", callbackURL, nil)
+			assert.NoError(t, err)
+			if cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: cookie})
+			}
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+
+			problem := tests.DecodeProblem(t, resp)
+			assert.Equal(t, http.StatusForbidden, problem.Status)
+			assert.Equal(t, "link_session_mismatch", problem.Code)
+			for _, c := range resp.Cookies() {
+				assert.NotEqual(t, "session_id", c.Name, "The linking session must not be handed to the other browser")
+			}
+			_, found := services.GetAuthToken(sessionID, "spotify", "linked-spotify-user")
+			assert.False(t, found)
+		})
+	}
+}
+
+func Test_Callback_LoginDeniedByPolicy_ShouldRedirectWithReasonAndStoreNothing(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	mockRedirectURI := setup.Server.URL + "/mock-callback"
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	defer os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	policyPath := filepath.Join(t.TempDir(), "login_policy.json")
+	assert.NoError(t, os.WriteFile(policyPath, []byte(`{"rules": [
+		{"name": "staff-only", "effect": "deny", "reason": "staff_only", "unless": {"email_domains": ["example.com"]}}
+	]}`), 0o600))
+	os.Setenv("LOGIN_POLICY_FILE", policyPath)
+	assert.NoError(t, config.ReloadLoginPolicy())
+	defer func() {
+		os.Unsetenv("LOGIN_POLICY_FILE")
+		config.ReloadLoginPolicy()
+	}()
+
+	originalConfig := config.Providers["spotify"]
+	mockConfig := *originalConfig
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:  setup.Server.URL + "/mock-oauth/authorize",
+		TokenURL: setup.Server.URL + "/mock-oauth/denied-token",
+	}
+	config.Providers["spotify"] = &mockConfig
+
+	originalGetProviderUserInfoURL := config.GetProviderUserInfoURL
+	config.GetProviderUserInfoURL = func(provider string) (string, error) {
+		return setup.Server.URL + "/mock-oauth/denied-me", nil
+	}
+	defer func() { config.GetProviderUserInfoURL = originalGetProviderUserInfoURL }()
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post("/mock-oauth/denied-token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "denied-access-token", "expires_in": 3600, "token_type": "Bearer"}`))
+	})
+	router.Get("/mock-oauth/denied-me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "outside-user", "display_name": "Outside User", "email": "outside@gmail.com"}`))
+	})
+
+	stateToken := "mock-denied-state"
+	assert.NoError(t, services.StorePKCEData(stateToken, "mock-code-verifier"))
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/spotify/callback", "mock-auth-code", stateToken+"|"+mockRedirectURI)
+	assert.NoError(t, err)
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	location, err := resp.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+	assert.Equal(t, "staff_only", location.Query().Get("error_reason"))
+	for _, c := range resp.Cookies() {
+		assert.NotEqual(t, "session_id", c.Name)
+	}
+
+	events, err := services.GetAuditEvents(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, events) {
+		assert.Equal(t, services.AuditLoginDenied, events[0].Type)
+		assert.Equal(t, "outside-user", events[0].UserID)
+		assert.Equal(t, "staff-only", events[0].Details["rule"])
+	}
+}
+
+// linkSpotifyAccount logs a Spotify account in to a new session and links the Tidal account to its user,
+// so later Tidal logins restore the Spotify account. It returns the session.
+func linkSpotifyAccount(t *testing.T, spotifyUserID, tidalUserID string) string {
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", mocks.NewMockUser("spotify", spotifyUserID, "Spotify User", ""), mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	_, err := services.ResolveSessionUser(ctx, sessionID, "spotify", spotifyUserID, false)
+	assert.NoError(t, err)
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", mocks.NewMockUser("tidal", tidalUserID, "Tidal User", ""), mocks.NewMockOAuth2Token("tidal", time.Hour)))
+	_, err = services.ResolveSessionUser(ctx, sessionID, "tidal", tidalUserID, true)
+	assert.NoError(t, err)
+	return sessionID
+}
+
+// limitSpotifySessions allows each Spotify account one session under the policy.
+func limitSpotifySessions(policy string) func() {
+	original := config.GetSessionLimit
+	config.GetSessionLimit = func(provider string) config.SessionLimit {
+		if provider != "spotify" {
+			return config.SessionLimit{}
+		}
+		return config.SessionLimit{Max: 1, Policy: policy}
+	}
+	return func() { config.GetSessionLimit = original }
+}
+
+func Test_Callback_SessionLimitEvictOldest_ShouldRevokeEvictedToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	var revoked []string
+	defer stubRevocation(t, "tidal", http.StatusOK, &revoked)()
+	original := config.GetSessionLimit
+	config.GetSessionLimit = func(provider string) config.SessionLimit {
+		return config.SessionLimit{Max: 1, Policy: config.SessionLimitEvictOldest}
+	}
+	defer func() { config.GetSessionLimit = original }()
+
+	firstSessionID := loginThroughCallback(t, setup, "evicted-tidal-user", "evicted-refresh-token")
+	sessionID := loginThroughCallback(t, setup, "evicted-tidal-user", "evicting-refresh-token")
+
+	_, found := services.GetAuthToken(firstSessionID, "tidal", "evicted-tidal-user")
+	assert.False(t, found)
+	_, found = services.GetAuthToken(sessionID, "tidal", "evicted-tidal-user")
+	assert.True(t, found)
+	assert.Equal(t, []string{"evicted-refresh-token"}, revoked, "The evicted session's token should be revoked at the provider")
+}
+
+func Test_Callback_RestoredLinkedAccount_ShouldEvictOldestSessionAtLimit(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	firstSessionID := linkSpotifyAccount(t, "restored-spotify-user", "restoring-tidal-user")
+	defer limitSpotifySessions(config.SessionLimitEvictOldest)()
+
+	sessionID := loginThroughCallback(t, setup, "restoring-tidal-user", "restoring-refresh-token")
+
+	_, found := services.GetAuthToken(sessionID, "spotify", "restored-spotify-user")
+	assert.True(t, found)
+	_, found = services.GetAuthToken(firstSessionID, "spotify", "restored-spotify-user")
+	assert.False(t, found)
+}
+
+func Test_Callback_RestoredLinkedAccount_ShouldBeSkippedWhenLimitRejects(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	firstSessionID := linkSpotifyAccount(t, "capped-spotify-user", "capped-tidal-user")
+	defer limitSpotifySessions(config.SessionLimitReject)()
+
+	sessionID := loginThroughCallback(t, setup, "capped-tidal-user", "capped-refresh-token")
+
+	// The Tidal login succeeds, without the Spotify account that is at its limit.
+	_, found := services.GetAuthToken(sessionID, "tidal", "capped-tidal-user")
+	assert.True(t, found)
+	_, found = services.GetAuthToken(sessionID, "spotify", "capped-spotify-user")
+	assert.False(t, found)
+	_, found = services.GetAuthToken(firstSessionID, "spotify", "capped-spotify-user")
+	assert.True(t, found)
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -188,3 +189,162 @@ func Test_WhenOAuthLoginIsTriggered_ShouldRedirectToProvider(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	assert.Contains(t, string(bodyBytes), "Mock callback received")
 }
+
+func buildClientRequestURL(baseURL, redirectURI, clientID string) (*url.URL, error) {
+	reqURL, err := buildRequestURL(baseURL, redirectURI)
+	if err != nil {
+		return nil, err
+	}
+	query := reqURL.Query()
+	query.Set("client_id", clientID)
+	reqURL.RawQuery = query.Encode()
+	return reqURL, nil
+}
+
+func Test_WhenClientIsUnknown_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	reqURL, err := buildClientRequestURL(setup.Server.URL+"/auth/spotify/login", "http://localhost:3000/callback", "unknown-client")
+	assert.NoError(t, err)
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), "Unknown client")
+}
+
+func Test_WhenProviderIsNotPermittedForClient_ShouldReturn403(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// The partner widget is only registered for SoundCloud.
+	reqURL, err := buildClientRequestURL(setup.Server.URL+"/auth/spotify/login", "https://widget.partner.example/callback", "partner-widget")
+	assert.NoError(t, err)
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), "Provider is not permitted for this client")
+}
+
+func Test_WhenRedirectURIIsNotRegisteredForClient_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// localhost is globally allowed, but not registered for the partner widget.
+	reqURL, err := buildClientRequestURL(setup.Server.URL+"/auth/soundcloud/login", "http://localhost:3000/callback", "partner-widget")
+	assert.NoError(t, err)
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(bodyBytes), "invalid redirect URI")
+}
+
+func Test_WhenClientLoginIsTriggered_ShouldRequestClientScopes(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	reqURL, err := buildClientRequestURL(setup.Server.URL+"/auth/spotify/login", "http://localhost:3000/callback", "web-app")
+	assert.NoError(t, err)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	location, err := resp.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, "user-read-email user-read-private", location.Query().Get("scope"))
+
+	// The client is recorded with the PKCE data so the callback can enforce its policy.
+	stateToken := strings.SplitN(location.Query().Get("state"), "|", 2)[0]
+	pkceData, err := services.GetPKCEData(stateToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "web-app", pkceData.ClientID)
+}
+
+func Test_WhenRedirectURIIsMissing_ShouldReturnInvalidRequestProblem(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp, err := http.Get(setup.Server.URL + "/auth/spotify/login")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "invalid_request", problem.Code)
+}
+
+func Test_WhenLinkingWithoutSession_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	reqURL, err := buildRequestURL(setup.Server.URL+"/auth/spotify/login", "http://localhost:3000/callback")
+	assert.NoError(t, err)
+	query := reqURL.Query()
+	query.Set("link", "true")
+	reqURL.RawQuery = query.Encode()
+
+	resp, err := http.Get(reqURL.String())
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "session_missing", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_WhenLinking_ShouldRememberSessionForCallback(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	reqURL, err := buildRequestURL(setup.Server.URL+"/auth/spotify/login", "http://localhost:3000/callback")
+	assert.NoError(t, err)
+	query := reqURL.Query()
+	query.Set("link", "true")
+	reqURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", reqURL.String(), nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "existing-session"})
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	location, err := resp.Location()
+	assert.NoError(t, err)
+	stateToken := strings.SplitN(location.Query().Get("state"), "|", 2)[0]
+	pkceData, err := services.GetPKCEData(stateToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "existing-session", pkceData.LinkSessionID)
+}
@@ -4,10 +4,14 @@ import (
 	"auth-service/models"
 	"auth-service/services"
 	"auth-service/tests"
+	"auth-service/utils"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/oauth2"
 	"io"
+	"net"
 	"net/http"
 	"testing"
 	"time"
@@ -51,7 +55,7 @@ func Test_GetAuthProviderToken_MissingSessionCookie_ShouldReturn400(t *testing.T
 	assert.Contains(t, string(body), "Session ID is required")
 }
 
-func Test_GetAuthProviderToken_MissingUserID_ShouldReturn400(t *testing.T) {
+func Test_GetAuthProviderToken_MissingUserIDWithoutAccounts_ShouldReturn404(t *testing.T) {
 	setup := tests.InitializeTestEnvironment(t)
 	defer setup.Cleanup()
 
@@ -68,11 +72,12 @@ func Test_GetAuthProviderToken_MissingUserID_ShouldReturn400(t *testing.T) {
 	assert.NoError(t, err)
 	defer resp.Body.Close()
 
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	// Without user_id the primary account is used, and this session has none.
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 
 	body, err := io.ReadAll(resp.Body)
 	assert.NoError(t, err)
-	assert.Contains(t, string(body), "Query argument user_id is required, but not found")
+	assert.Contains(t, string(body), "Token not found")
 }
 
 func Test_GetAuthProviderToken_TokenNotFound_ShouldReturn404(t *testing.T) {
@@ -145,3 +150,208 @@ func Test_GetAuthProviderToken_ValidToken_ShouldReturn200(t *testing.T) {
 
 	assert.Equal(t, "valid-access-token", response["access_token"])
 }
+
+func Test_GetAuthProviderToken_SoundCloud_ValidToken_ShouldReturn200(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	validToken := &oauth2.Token{
+		AccessToken:  "valid-soundcloud-access-token",
+		RefreshToken: "valid-soundcloud-refresh-token",
+		Expiry:       time.Now().Add(1 * time.Hour),
+	}
+
+	// SoundCloud does not share an email address with us.
+	mockUser := &models.UserInfo{
+		ID:          "123456",
+		DisplayName: "SoundCloudUser",
+	}
+
+	err := services.StoreAuthToken("mock-session-id", "soundcloud", mockUser, validToken)
+	assert.NoError(t, err)
+
+	url := setup.Server.URL + "/auth/soundcloud/token?user_id=123456"
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+
+	req.AddCookie(&http.Cookie{
+		Name:  "session_id",
+		Value: "mock-session-id",
+	})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var response map[string]interface{}
+	err = json.Unmarshal(body, &response)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "valid-soundcloud-access-token", response["access_token"])
+}
+
+func Test_GetAuthProviderToken_TokenNotFound_ShouldReturnProblem(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/auth/spotify/token?user_id=mock-user-id", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "invalid-session"})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "token_not_found", problem.Code)
+	assert.Equal(t, "Not Found", problem.Title)
+	assert.Equal(t, "/auth/spotify/token", *problem.Instance)
+}
+
+func Test_GetAuthProviderToken_RefreshFails_ShouldReturnReauthRequired(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sessionID := "test-session-refresh-fails"
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "Mock User", Email: "mock@example.com"}
+	err := services.StoreAuthToken(sessionID, "spotify", user, &oauth2.Token{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "revoked-refresh-token",
+		Expiry:       time.Now().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		return nil, fmt.Errorf("failed to refresh token: %w", &oauth2.RetrieveError{
+			Response:         &http.Response{StatusCode: http.StatusBadRequest},
+			ErrorCode:        "invalid_grant",
+			ErrorDescription: "Refresh token revoked",
+		})
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/auth/spotify/token?user_id=mock-user-id", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+	assert.Equal(t, "reauth_required", problem.Code)
+	// The provider's error is logged, not returned.
+	assert.NotContains(t, *problem.Detail, "invalid_grant")
+}
+
+func Test_GetAuthProviderToken_RefreshFailsTransiently_ShouldRetryOnNextRequest(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sessionID := "test-session-refresh-transient"
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "Mock User", Email: "mock@example.com"}
+	err := services.StoreAuthToken(sessionID, "spotify", user, &oauth2.Token{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+
+	// The provider cannot be reached for the first refresh, then recovers.
+	attempts := 0
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, fmt.Errorf("failed to refresh token: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+		}
+		return &oauth2.Token{AccessToken: "refreshed-access-token", RefreshToken: refreshToken, Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	getToken := func() *http.Response {
+		req, err := http.NewRequest("GET", setup.Server.URL+"/auth/spotify/token?user_id=mock-user-id", nil)
+		assert.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	failed := getToken()
+	problem := tests.DecodeProblem(t, failed)
+	failed.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, problem.Status)
+	assert.Equal(t, "provider_error", problem.Code)
+	assert.NotContains(t, *problem.Detail, "connection refused")
+	authData, found := services.GetAuthToken(sessionID, "spotify", "mock-user-id")
+	assert.True(t, found)
+	assert.False(t, authData.ReauthRequired)
+
+	resp := getToken()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var response map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "refreshed-access-token", response["access_token"])
+	assert.Equal(t, 2, attempts)
+}
+
+func Test_GetAuthProviderToken_ShouldBeMarkedDeprecated(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken("deprecated-session-id", "spotify", user, &oauth2.Token{
+		AccessToken:  "valid-access-token",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/auth/spotify/token?user_id=mock-user-id", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "deprecated-session-id"})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Deprecation"))
+	assert.Equal(t, `</v2/auth/spotify/token>; rel="successor-version"`, resp.Header.Get("Link"))
+}
+
+func Test_GetAuthProviderToken_BrowserSession_ShouldWithholdRefreshToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken("browser-session-id", "spotify", user, &oauth2.Token{
+		AccessToken:  "valid-access-token",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/auth/spotify/token?user_id=mock-user-id", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "browser-session-id"})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var response map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "valid-access-token", response["access_token"])
+	assert.NotContains(t, response, "refresh_token")
+}
@@ -1,6 +1,7 @@
 package auth_handler
 
 import (
+	"auth-service/generated"
 	"auth-service/services"
 	"auth-service/tests"
 	"auth-service/tests/mocks"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
 )
 
 func Test_GetAuthStatus_ShouldReturnConnectedProviders(t *testing.T) {
@@ -23,10 +25,12 @@ func Test_GetAuthStatus_ShouldReturnConnectedProviders(t *testing.T) {
 	mockSpotifyToken1 := mocks.NewMockOAuth2Token("spotify-1", time.Hour)
 	mockSpotifyToken2 := mocks.NewMockOAuth2Token("spotify-2", time.Hour)
 	mockTidalToken := mocks.NewMockOAuth2Token("tidal", time.Hour)
+	mockSoundCloudToken := mocks.NewMockOAuth2Token("soundcloud", time.Hour)
 
 	mockSpotifyUser1 := mocks.NewMockUser("spotify", "mock-spotify-user1-id", "Spotify User One", "spotify1@example.com")
 	mockSpotifyUser2 := mocks.NewMockUser("spotify", "mock-spotify-user2-id", "Spotify User Two", "spotify2@example.com")
 	mockTidalUser := mocks.NewMockUser("tidal", "mock-tidal-user-id", "Tidal User", "tidal@example.com")
+	mockSoundCloudUser := mocks.NewMockUser("soundcloud", "123456", "SoundCloud User", "")
 
 	// Store tokens in Redis.
 	err := services.StoreAuthToken(sessionID, "spotify", mockSpotifyUser1, mockSpotifyToken1)
@@ -35,6 +39,8 @@ func Test_GetAuthStatus_ShouldReturnConnectedProviders(t *testing.T) {
 	assert.NoError(t, err)
 	err = services.StoreAuthToken(sessionID, "tidal", mockTidalUser, mockTidalToken)
 	assert.NoError(t, err)
+	err = services.StoreAuthToken(sessionID, "soundcloud", mockSoundCloudUser, mockSoundCloudToken)
+	assert.NoError(t, err)
 
 	// Create an HTTP client with a cookie jar.
 	jar, err := cookiejar.New(nil)
@@ -66,11 +72,11 @@ func Test_GetAuthStatus_ShouldReturnConnectedProviders(t *testing.T) {
 	err = json.Unmarshal(bodyBytes, &response)
 	assert.NoError(t, err)
 
-	// Expect three logged-in providers.
-	assert.Len(t, response, 3)
+	// Expect four logged-in providers.
+	assert.Len(t, response, 4)
 
-	// Validate that both Spotify accounts and the Tidal account are present.
-	var foundSpotify1, foundSpotify2, foundTidal bool
+	// Validate that both Spotify accounts, the Tidal account and the SoundCloud account are present.
+	var foundSpotify1, foundSpotify2, foundTidal, foundSoundCloud bool
 	for _, provider := range response {
 		if provider.Provider == "spotify" && provider.UserID == mockSpotifyUser1.ID {
 			foundSpotify1 = true
@@ -90,10 +96,17 @@ func Test_GetAuthStatus_ShouldReturnConnectedProviders(t *testing.T) {
 			assert.Equal(t, mockTidalUser.Email, provider.Email)
 			assert.True(t, provider.LoggedIn)
 		}
+		if provider.Provider == "soundcloud" && provider.UserID == mockSoundCloudUser.ID {
+			foundSoundCloud = true
+			assert.Equal(t, mockSoundCloudUser.DisplayName, provider.DisplayName)
+			assert.Empty(t, provider.Email)
+			assert.True(t, provider.LoggedIn)
+		}
 	}
 	assert.True(t, foundSpotify1, "Spotify User One should be in the response")
 	assert.True(t, foundSpotify2, "Spotify User Two should be in the response")
 	assert.True(t, foundTidal, "Tidal User should be in the response")
+	assert.True(t, foundSoundCloud, "SoundCloud User should be in the response")
 }
 
 func Test_GetAuthStatus_ShouldReturnUnauthorised(t *testing.T) {
@@ -114,3 +127,83 @@ func Test_GetAuthStatus_ShouldReturnUnauthorised(t *testing.T) {
 	// Unauthorized response
 	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
 }
+
+// getStatus calls the status endpoint with the session cookie.
+func getStatus(t *testing.T, serverURL, sessionID string) map[string]generated.LinkedAccount {
+	req, err := http.NewRequest("GET", serverURL+"/auth/status", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response []generated.LinkedAccount
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	accounts := map[string]generated.LinkedAccount{}
+	for _, account := range response {
+		accounts[account.Provider+"/"+account.UserId] = account
+	}
+	return accounts
+}
+
+func Test_GetAuthStatus_ShouldReportTokenHealth(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID := uuid.New().String()
+
+	// A healthy token with all the scopes we request.
+	healthy := mocks.NewMockOAuth2Token("spotify", time.Hour).WithExtra(map[string]interface{}{
+		"scope": "playlist-read-private playlist-modify-public user-read-email user-read-private",
+	})
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", mocks.NewMockUser("spotify", "healthy-user", "Healthy", ""), healthy))
+
+	// A token granted before we asked for playlist scopes.
+	outdated := mocks.NewMockOAuth2Token("spotify", time.Hour).WithExtra(map[string]interface{}{
+		"scope": "user-read-email user-read-private",
+	})
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", mocks.NewMockUser("spotify", "outdated-user", "Outdated", ""), outdated))
+
+	// A token about to expire.
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", mocks.NewMockUser("tidal", "expiring-user", "Expiring", ""),
+		mocks.NewMockOAuth2Token("tidal", time.Minute)))
+
+	// An expired token that cannot be refreshed.
+	assert.NoError(t, services.StoreAuthToken(sessionID, "soundcloud", mocks.NewMockUser("soundcloud", "expired-user", "Expired", ""),
+		&oauth2.Token{AccessToken: "expired-access-token", Expiry: time.Now().Add(-time.Minute)}))
+
+	accounts := getStatus(t, setup.Server.URL, sessionID)
+
+	assert.Len(t, accounts, 4)
+	assert.Equal(t, "ok", accounts["spotify/healthy-user"].Health)
+	assert.Equal(t, "needs_upgrade", accounts["spotify/outdated-user"].Health)
+	assert.Equal(t, "expiring", accounts["tidal/expiring-user"].Health)
+	assert.Equal(t, "needs_reauth", accounts["soundcloud/expired-user"].Health)
+
+	healthyAccount := accounts["spotify/healthy-user"]
+	assert.NotNil(t, healthyAccount.LinkedAt)
+	assert.Nil(t, healthyAccount.LastRefreshedAt)
+	assert.WithinDuration(t, healthy.Expiry, *healthyAccount.ExpiresAt, time.Second)
+	assert.Equal(t, []string{"playlist-read-private", "playlist-modify-public", "user-read-email", "user-read-private"}, *healthyAccount.Scopes)
+}
+
+func Test_GetAuthStatus_ShouldMarkEarliestLinkedAccountPrimary(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID := uuid.New().String()
+
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", mocks.NewMockUser("spotify", "earliest-user", "Earliest", ""),
+		mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", mocks.NewMockUser("spotify", "later-user", "Later", ""),
+		mocks.NewMockOAuth2Token("spotify", time.Hour)))
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", mocks.NewMockUser("tidal", "tidal-user", "Tidal", ""),
+		mocks.NewMockOAuth2Token("tidal", time.Hour)))
+
+	accounts := getStatus(t, setup.Server.URL, sessionID)
+
+	assert.True(t, accounts["spotify/earliest-user"].Primary)
+	assert.False(t, accounts["spotify/later-user"].Primary)
+	assert.True(t, accounts["tidal/tidal-user"].Primary)
+}
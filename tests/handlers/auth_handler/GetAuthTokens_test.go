@@ -0,0 +1,112 @@
+package auth_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/models"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/utils"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// getAllTokens calls the bulk token endpoint with the session cookie.
+func getAllTokens(t *testing.T, url, sessionID string) generated.AccountTokens {
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.AccountTokens
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return response
+}
+
+func Test_GetAuthTokens_ShouldReturnEveryLinkedAccount(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	spotifyUser := &models.UserInfo{ID: "spotify-user-id", DisplayName: "John Doe"}
+	tidalUser := &models.UserInfo{ID: "tidal-user-id", DisplayName: "John Doe"}
+	assert.NoError(t, services.StoreAuthToken("bulk-session-id", "spotify", spotifyUser, &oauth2.Token{
+		AccessToken: "spotify-access-token", RefreshToken: "spotify-refresh-token", Expiry: time.Now().Add(time.Hour),
+	}))
+	assert.NoError(t, services.StoreAuthToken("bulk-session-id", "tidal", tidalUser, &oauth2.Token{
+		AccessToken: "tidal-access-token", RefreshToken: "tidal-refresh-token", Expiry: time.Now().Add(time.Hour),
+	}))
+
+	response := getAllTokens(t, setup.Server.URL+"/auth/tokens", "bulk-session-id")
+
+	assert.Len(t, response.Accounts, 2)
+	assert.Equal(t, "spotify", response.Accounts[0].Provider)
+	assert.Equal(t, "spotify-access-token", response.Accounts[0].Token.AccessToken)
+	assert.Equal(t, "tidal", response.Accounts[1].Provider)
+	assert.Equal(t, "tidal-access-token", response.Accounts[1].Token.AccessToken)
+	assert.Nil(t, response.Accounts[1].Token.RefreshToken)
+}
+
+func Test_GetAuthTokens_ProviderFilter_ShouldOnlyReturnThatProvider(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	assert.NoError(t, services.StoreAuthToken("bulk-filter-session-id", "spotify", &models.UserInfo{ID: "spotify-user-id"}, &oauth2.Token{
+		AccessToken: "spotify-access-token", Expiry: time.Now().Add(time.Hour),
+	}))
+	assert.NoError(t, services.StoreAuthToken("bulk-filter-session-id", "tidal", &models.UserInfo{ID: "tidal-user-id"}, &oauth2.Token{
+		AccessToken: "tidal-access-token", Expiry: time.Now().Add(time.Hour),
+	}))
+
+	response := getAllTokens(t, setup.Server.URL+"/auth/tokens?provider=tidal", "bulk-filter-session-id")
+
+	assert.Len(t, response.Accounts, 1)
+	assert.Equal(t, "tidal-user-id", response.Accounts[0].ProviderUserId)
+}
+
+func Test_GetAuthTokens_OneRefreshFails_ShouldReportItIndividually(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	assert.NoError(t, services.StoreAuthToken("bulk-partial-session-id", "spotify", &models.UserInfo{ID: "spotify-user-id"}, &oauth2.Token{
+		AccessToken: "expired-access-token", RefreshToken: "revoked-refresh-token", Expiry: time.Now().Add(-time.Minute),
+	}))
+	assert.NoError(t, services.StoreAuthToken("bulk-partial-session-id", "tidal", &models.UserInfo{ID: "tidal-user-id"}, &oauth2.Token{
+		AccessToken: "tidal-access-token", Expiry: time.Now().Add(time.Hour),
+	}))
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		return nil, fmt.Errorf("oauth2: \"invalid_grant\" \"Refresh token revoked\"")
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	response := getAllTokens(t, setup.Server.URL+"/auth/tokens", "bulk-partial-session-id")
+
+	assert.Len(t, response.Accounts, 2)
+	if assert.NotNil(t, response.Accounts[0].Error) {
+		assert.Equal(t, "reauth_required", response.Accounts[0].Error.Code)
+	}
+	assert.Nil(t, response.Accounts[0].Token)
+	assert.Equal(t, "tidal-access-token", response.Accounts[1].Token.AccessToken)
+}
+
+func Test_GetAuthTokens_MissingSession_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp, err := http.Get(setup.Server.URL + "/auth/tokens")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+	assert.Equal(t, "session_missing", problem.Code)
+}
@@ -0,0 +1,211 @@
+package auth_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/models"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/utils"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// getV2Token calls the v2 token endpoint with the session cookie.
+func getV2Token(t *testing.T, serverURL, provider, userID, sessionID string) *http.Response {
+	req, err := http.NewRequest("GET", serverURL+"/v2/auth/"+provider+"/token?user_id="+userID, nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func Test_GetV2AuthProviderToken_ValidToken_ShouldReturnExpiryAndScopes(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	expiry := time.Now().Add(time.Hour)
+	token := (&oauth2.Token{
+		AccessToken:  "valid-access-token",
+		TokenType:    "bearer",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       expiry,
+	}).WithExtra(map[string]interface{}{"scope": "user-read-email user-read-private"})
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	assert.NoError(t, services.StoreAuthToken("v2-session-id", "spotify", user, token))
+
+	resp := getV2Token(t, setup.Server.URL, "spotify", "mock-user-id", "v2-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "valid-access-token", response.AccessToken)
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, "spotify", response.Provider)
+	assert.Equal(t, "mock-user-id", response.ProviderUserId)
+	assert.Equal(t, "valid", response.RefreshStatus)
+	assert.Equal(t, []string{"user-read-email", "user-read-private"}, *response.Scope)
+	assert.WithinDuration(t, expiry, *response.ExpiresAt, time.Second)
+	assert.InDelta(t, 3600, *response.ExpiresIn, 5)
+}
+
+func Test_GetV2AuthProviderToken_ExpiredToken_ShouldRefreshAndKeepScopes(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	token := (&oauth2.Token{
+		AccessToken:  "expired-access-token",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(-time.Minute),
+	}).WithExtra(map[string]interface{}{"scope": "user-read-email"})
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	assert.NoError(t, services.StoreAuthToken("v2-refresh-session-id", "spotify", user, token))
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		return &oauth2.Token{
+			AccessToken:  "refreshed-access-token",
+			TokenType:    "Bearer",
+			RefreshToken: refreshToken,
+			Expiry:       time.Now().Add(time.Hour),
+		}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	resp := getV2Token(t, setup.Server.URL, "spotify", "mock-user-id", "v2-refresh-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "refreshed-access-token", response.AccessToken)
+	assert.Equal(t, "refreshed", response.RefreshStatus)
+	assert.Equal(t, []string{"user-read-email"}, *response.Scope)
+
+	stored, found := services.GetAuthToken("v2-refresh-session-id", "spotify", "mock-user-id")
+	assert.True(t, found)
+	assert.Equal(t, "refreshed-access-token", stored.Token.AccessToken)
+	assert.Equal(t, []string{"user-read-email"}, stored.Scopes)
+}
+
+func Test_GetV2AuthProviderToken_TokenWithoutExpiry_ShouldOmitExpiry(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// Credential provider tokens never expire.
+	token := &oauth2.Token{AccessToken: "qobuz-user-auth-token", TokenType: "X-User-Auth-Token"}
+	user := &models.UserInfo{ID: "1234567", DisplayName: "Qobuz User"}
+	assert.NoError(t, services.StoreAuthToken("v2-qobuz-session-id", "qobuz", user, token))
+
+	resp := getV2Token(t, setup.Server.URL, "qobuz", "1234567", "v2-qobuz-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "X-User-Auth-Token", response.TokenType)
+	assert.Equal(t, "not_refreshable", response.RefreshStatus)
+	assert.Nil(t, response.ExpiresAt)
+	assert.Nil(t, response.ExpiresIn)
+	assert.Nil(t, response.RefreshToken)
+}
+
+func Test_GetV2AuthProviderToken_TokenNotFound_ShouldReturnProblem(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := getV2Token(t, setup.Server.URL, "spotify", "mock-user-id", "unknown-session")
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "token_not_found", problem.Code)
+}
+
+func Test_GetV2AuthProviderToken_BrowserSession_ShouldWithholdRefreshToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	token := &oauth2.Token{AccessToken: "valid-access-token", RefreshToken: "valid-refresh-token", Expiry: time.Now().Add(time.Hour)}
+	assert.NoError(t, services.StoreClientAuthToken("v2-browser-session-id", "spotify", "web-app", user, token))
+
+	resp := getV2Token(t, setup.Server.URL, "spotify", "mock-user-id", "v2-browser-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "valid-access-token", response.AccessToken)
+	assert.Nil(t, response.RefreshToken)
+}
+
+func Test_GetV2AuthProviderToken_BackendClient_ShouldReturnRefreshToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	token := &oauth2.Token{AccessToken: "valid-access-token", RefreshToken: "valid-refresh-token", Expiry: time.Now().Add(time.Hour)}
+	assert.NoError(t, services.StoreClientAuthToken("v2-backend-session-id", "spotify", "backend-service", user, token))
+
+	resp := getV2Token(t, setup.Server.URL, "spotify", "mock-user-id", "v2-backend-session-id")
+	defer resp.Body.Close()
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "valid-refresh-token", *response.RefreshToken)
+}
+
+func Test_GetV2AuthProviderToken_BackendClientFromBrowser_ShouldWithholdRefreshToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	token := &oauth2.Token{AccessToken: "valid-access-token", RefreshToken: "valid-refresh-token", Expiry: time.Now().Add(time.Hour)}
+	assert.NoError(t, services.StoreClientAuthToken("v2-stolen-session-id", "spotify", "backend-service", user, token))
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/v2/auth/spotify/token?user_id=mock-user-id", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "v2-stolen-session-id"})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Nil(t, response.RefreshToken)
+}
+
+func Test_GetV2AuthProviderToken_InternalCaller_ShouldReturnRefreshToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	token := &oauth2.Token{AccessToken: "valid-access-token", RefreshToken: "valid-refresh-token", Expiry: time.Now().Add(time.Hour)}
+	assert.NoError(t, services.StoreClientAuthToken("v2-internal-session-id", "spotify", "web-app", user, token))
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/v2/auth/spotify/token?user_id=mock-user-id", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Internal-Api-Key", "test-internal-api-key")
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "v2-internal-session-id"})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "valid-refresh-token", *response.RefreshToken)
+}
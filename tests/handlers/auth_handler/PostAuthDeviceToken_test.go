@@ -0,0 +1,298 @@
+package auth_handler
+
+import (
+	"auth-service/config"
+	"auth-service/services"
+	"auth-service/tests"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+)
+
+// startDeviceGrant requests a new device and user code for the provider.
+func startDeviceGrant(t *testing.T, setup *tests.TestSetup, provider string) map[string]interface{} {
+	resp, err := http.Post(setup.Server.URL+"/auth/"+provider+"/device/code", "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return response
+}
+
+// pollDeviceToken polls the device token endpoint once and decodes the response.
+func pollDeviceToken(t *testing.T, setup *tests.TestSetup, deviceCode string) (int, map[string]string) {
+	body, err := json.Marshal(map[string]string{"device_code": deviceCode})
+	assert.NoError(t, err)
+
+	resp, err := http.Post(setup.Server.URL+"/auth/device/token", "application/json", bytes.NewReader(body))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var response map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return resp.StatusCode, response
+}
+
+func Test_PostAuthProviderDeviceCode_InvalidProvider_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp, err := http.Post(setup.Server.URL+"/auth/invalid-provider/device/code", "application/json", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func Test_PostAuthDeviceToken_UnknownDeviceCode_ShouldReturnExpiredToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	status, response := pollDeviceToken(t, setup, "unknown-device-code")
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "expired_token", response["error"])
+}
+
+func Test_PostAuthDeviceToken_PollingTooQuickly_ShouldReturnPendingThenSlowDown(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	grant := startDeviceGrant(t, setup, "spotify")
+	deviceCode := grant["device_code"].(string)
+	assert.Regexp(t, `^[A-Z]{4}-[A-Z]{4}$`, grant["user_code"])
+	assert.Contains(t, grant["verification_uri_complete"], grant["user_code"])
+
+	status, response := pollDeviceToken(t, setup, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "authorization_pending", response["error"])
+
+	// Polling again straight away is faster than the interval allows.
+	status, response = pollDeviceToken(t, setup, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "slow_down", response["error"])
+}
+
+func Test_DeviceFlow_UserApprovesOnPhone_ShouldHandSessionToDevice(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	defer os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	// Mock OAuth Config for Spotify.
+	originalConfig := config.Providers["spotify"]
+	mockConfig := *originalConfig
+	mockConfig.Endpoint = oauth2.Endpoint{
+		AuthURL:  setup.Server.URL + "/mock-oauth/authorize",
+		TokenURL: setup.Server.URL + "/mock-oauth/token",
+	}
+	config.Providers["spotify"] = &mockConfig
+
+	originalGetProviderUserInfoURL := config.GetProviderUserInfoURL
+	config.GetProviderUserInfoURL = func(provider string) (string, error) {
+		if provider == "spotify" {
+			return setup.Server.URL + "/mock-oauth/me", nil
+		}
+		return "", fmt.Errorf("provider not supported")
+	}
+	defer func() { config.GetProviderUserInfoURL = originalGetProviderUserInfoURL }()
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post("/mock-oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "mocked-access-token", "refresh_token": "mocked-refresh-token", "expires_in": 3600, "token_type": "Bearer"}`))
+	})
+	router.Get("/mock-oauth/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "mock-user-id", "display_name": "Mock User", "email": "mockuser@example.com"}`))
+	})
+
+	grant := startDeviceGrant(t, setup, "spotify")
+	deviceCode := grant["device_code"].(string)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// The user opens the verification URL on their phone and is asked to confirm the device.
+	resp, err := client.Get(grant["verification_uri_complete"].(string))
+	assert.NoError(t, err)
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(page), grant["user_code"])
+	assert.Contains(t, string(page), "Go-http-client")
+
+	// Confirming sends the user to the provider.
+	resp, err = client.Post(grant["verification_uri_complete"].(string), "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	authURL, err := resp.Location()
+	assert.NoError(t, err)
+	state := authURL.Query().Get("state")
+	assert.NotEmpty(t, authURL.Query().Get("code_challenge"))
+
+	// The provider redirects the phone back to the callback.
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/spotify/callback", "mock-auth-code", state)
+	assert.NoError(t, err)
+	resp, err = client.Get(reqURL.String())
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// The phone lands on the completion page without being given the device's session.
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	redirectLocation, err := resp.Location()
+	assert.NoError(t, err)
+	assert.Equal(t, os.Getenv("DEVICE_COMPLETE_REDIRECT_URI"), redirectLocation.String())
+	for _, c := range resp.Cookies() {
+		assert.NotEqual(t, "session_id", c.Name)
+	}
+
+	// The device collects the session on its next poll.
+	status, response := pollDeviceToken(t, setup, deviceCode)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "spotify", response["provider"])
+	assert.Equal(t, "mock-user-id", response["user_id"])
+
+	token, found := services.GetAuthToken(response["session_id"], "spotify", "mock-user-id")
+	assert.True(t, found)
+	assert.Equal(t, "mocked-access-token", token.Token.AccessToken)
+
+	// The session can only be collected once.
+	status, response = pollDeviceToken(t, setup, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "expired_token", response["error"])
+}
+
+func Test_DeviceFlow_UserDeclinesAtProvider_ShouldReturnAccessDenied(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	grant := startDeviceGrant(t, setup, "spotify")
+	deviceCode := grant["device_code"].(string)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Post(grant["verification_uri_complete"].(string), "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	authURL, err := resp.Location()
+	assert.NoError(t, err)
+
+	// The provider redirects back with an error instead of a code.
+	reqURL, err := buildCallbackURL(setup.Server.URL+"/auth/spotify/callback", "", authURL.Query().Get("state"))
+	assert.NoError(t, err)
+	query := reqURL.Query()
+	query.Del("code")
+	query.Set("error", "access_denied")
+	reqURL.RawQuery = query.Encode()
+
+	resp, err = client.Get(reqURL.String())
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+
+	status, response := pollDeviceToken(t, setup, deviceCode)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "access_denied", response["error"])
+}
+
+func Test_GetAuthDevice_ShouldConfirmBeforeStartingLogin(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	grant := startDeviceGrant(t, setup, "spotify")
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(grant["verification_uri_complete"].(string))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Opening the link alone must not send the user to the provider.
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Location"))
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+}
+
+func Test_GetAuthDevice_ViewingConfirmation_ShouldNotUseUpCode(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	grant := startDeviceGrant(t, setup, "spotify")
+
+	// Anyone who sees the code can open the page, but that must not lock the user out.
+	for i := 0; i < 10; i++ {
+		resp, err := http.Get(grant["verification_uri_complete"].(string))
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func Test_PostAuthDevice_TooManyConfirmationsForCode_ShouldReturn429(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	grant := startDeviceGrant(t, setup, "spotify")
+	verificationURL := grant["verification_uri_complete"].(string)
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.Post(verificationURL, "", nil)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.NotEqual(t, http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	resp, err := client.Post(verificationURL, "", nil)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusTooManyRequests, problem.Status)
+	assert.Equal(t, "rate_limited", problem.Code)
+}
+
+func Test_GetAuthDevice_GuessingCodes_ShouldBeLimitedPerAddress(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// Each guess is a different code, so only the per-address limit stops them.
+	const alphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	var status int
+	for i := 0; i < 21; i++ {
+		code := fmt.Sprintf("BCDF-GH%c%c", alphabet[i/len(alphabet)], alphabet[i%len(alphabet)])
+		resp, err := http.Get(setup.Server.URL + "/auth/device?user_code=" + code)
+		assert.NoError(t, err)
+		status = resp.StatusCode
+		resp.Body.Close()
+	}
+	assert.Equal(t, http.StatusTooManyRequests, status)
+}
@@ -0,0 +1,208 @@
+package auth_handler
+
+import (
+	"auth-service/config"
+	"auth-service/services"
+	"auth-service/tests"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// mockQobuzLogin points the qobuz credential provider at a mock login endpoint on the test server.
+func mockQobuzLogin(t *testing.T, setup *tests.TestSetup, handler http.HandlerFunc) {
+	originalConfig := config.CredentialProviders["qobuz"]
+	mockConfig := *originalConfig
+	mockConfig.LoginURL = setup.Server.URL + "/mock-qobuz/login"
+	config.CredentialProviders["qobuz"] = &mockConfig
+	t.Cleanup(func() { config.CredentialProviders["qobuz"] = originalConfig })
+
+	router := setup.Server.Config.Handler.(*chi.Mux)
+	router.Post("/mock-qobuz/login", handler)
+}
+
+func Test_PostAuthProviderCredentials_InvalidProvider_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// OAuth providers cannot be logged in to with credentials.
+	url := setup.Server.URL + "/auth/spotify/credentials"
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{"username":"user","password":"pass"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Unsupported provider")
+}
+
+func Test_PostAuthProviderCredentials_MissingPassword_ShouldReturn400(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	url := setup.Server.URL + "/auth/qobuz/credentials"
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{"username":"user"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Username and password are required")
+}
+
+func Test_PostAuthProviderCredentials_InvalidCredentials_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	mockQobuzLogin(t, setup, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status":"error","code":401,"message":"Invalid username/email and password combination"}`))
+	})
+
+	url := setup.Server.URL + "/auth/qobuz/credentials"
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{"username":"user","password":"wrong"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Invalid username or password")
+}
+
+func Test_PostAuthProviderCredentials_Qobuz_SuccessfulLogin_ShouldStoreTokenAndAppearInStatus(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	passwordHash := md5.Sum([]byte("secret"))
+	mockQobuzLogin(t, setup, func(w http.ResponseWriter, r *http.Request) {
+		// Qobuz expects the app ID and the MD5 hash of the password, never the plain password.
+		if r.FormValue("app_id") != config.CredentialProviders["qobuz"].AppID ||
+			r.FormValue("password") != hex.EncodeToString(passwordHash[:]) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{
+			"user_auth_token": "mock-qobuz-user-auth-token",
+			"user": {
+				"id": 1234567,
+				"login": "qobuzuser",
+				"display_name": "Qobuz User",
+				"email": "qobuz@example.com"
+			}
+		}`))
+	})
+
+	url := setup.Server.URL + "/auth/qobuz/credentials"
+	resp, err := http.Post(url, "application/json", strings.NewReader(`{"username":"qobuzuser","password":"secret"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == "session_id" {
+			cookie = c
+			break
+		}
+	}
+	if !assert.NotNil(t, cookie) {
+		return
+	}
+
+	// The user auth token is stored without the password.
+	token, found := services.GetAuthToken(cookie.Value, "qobuz", "1234567")
+	assert.True(t, found)
+	assert.Equal(t, "mock-qobuz-user-auth-token", token.Token.AccessToken)
+	assert.Empty(t, token.Token.RefreshToken)
+
+	// The account appears in the auth status like any OAuth-linked account.
+	req := createSessionRequest(t, "GET", setup.Server.URL+"/auth/status", cookie.Value)
+	statusResp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer statusResp.Body.Close()
+
+	var providers []services.LoggedInProvider
+	err = json.NewDecoder(statusResp.Body).Decode(&providers)
+	assert.NoError(t, err)
+	assert.Len(t, providers, 1)
+	assert.Equal(t, "qobuz", providers[0].Provider)
+	assert.Equal(t, "1234567", providers[0].UserID)
+	assert.Equal(t, "Qobuz User", providers[0].DisplayName)
+	assert.True(t, providers[0].LoggedIn)
+	if assert.NotNil(t, providers[0].Profile, "Credential logins should be validated like OAuth logins") {
+		assert.True(t, providers[0].Profile.Valid)
+	}
+}
+
+func Test_PostAuthProviderCredentials_Qobuz_MissingEmail_ShouldLogInWithInvalidProfile(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	mockQobuzLogin(t, setup, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user_auth_token": "mock-qobuz-user-auth-token", "user": {"id": 1234567, "login": "qobuzuser"}}`))
+	})
+
+	resp, err := http.Post(setup.Server.URL+"/auth/qobuz/credentials", "application/json", strings.NewReader(`{"username":"qobuzuser","password":"secret"}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var sessionID string
+	for _, c := range resp.Cookies() {
+		if c.Name == "session_id" {
+			sessionID = c.Value
+		}
+	}
+	token, found := services.GetAuthToken(sessionID, "qobuz", "1234567")
+	if assert.True(t, found) && assert.NotNil(t, token.Profile) {
+		assert.False(t, token.Profile.Valid)
+		assert.Equal(t, []string{config.ProfileFieldEmail}, token.Profile.Missing)
+	}
+}
+
+func Test_PostAuthProviderCredentials_SessionLimitReject_ShouldReturn409(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	original := config.GetSessionLimit
+	config.GetSessionLimit = func(provider string) config.SessionLimit {
+		return config.SessionLimit{Max: 1, Policy: config.SessionLimitReject}
+	}
+	defer func() { config.GetSessionLimit = original }()
+
+	mockQobuzLogin(t, setup, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user_auth_token": "mock-qobuz-user-auth-token", "user": {"id": 7654321, "login": "qobuzuser", "display_name": "Qobuz User", "email": "qobuz@example.com"}}`))
+	})
+
+	url := setup.Server.URL + "/auth/qobuz/credentials"
+	first, err := http.Post(url, "application/json", strings.NewReader(`{"username":"qobuzuser","password":"secret"}`))
+	assert.NoError(t, err)
+	first.Body.Close()
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+
+	// Credential logins count towards the session limit like OAuth logins.
+	second, err := http.Post(url, "application/json", strings.NewReader(`{"username":"qobuzuser","password":"secret"}`))
+	assert.NoError(t, err)
+	defer second.Body.Close()
+
+	problem := tests.DecodeProblem(t, second)
+	assert.Equal(t, http.StatusConflict, problem.Status)
+	assert.Equal(t, "session_limit_reached", problem.Code)
+}
@@ -1,13 +1,22 @@
 package auth_handler
 
 import (
+	"auth-service/config"
+	"auth-service/redisclient"
 	"auth-service/services"
 	"auth-service/tests"
 	"auth-service/tests/mocks"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -161,3 +170,152 @@ func Test_PostAuthProviderLogout_AllUsers_ShouldReturn200(t *testing.T) {
 	assert.False(t, found1, "User 1 token should be removed")
 	assert.False(t, found2, "User 2 token should be removed")
 }
+
+// stubRevocation points the provider's revocation endpoint at a test server answering with status.
+func stubRevocation(t *testing.T, provider string, status int, revoked *[]string) func() {
+	revocationServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		*revoked = append(*revoked, r.PostForm.Get("token"))
+		w.WriteHeader(status)
+	}))
+	original := config.GetProviderRevocation
+	config.GetProviderRevocation = func(name string) (*config.RevocationConfig, bool) {
+		if name != provider {
+			return original(name)
+		}
+		return &config.RevocationConfig{URL: revocationServer.URL, AuthStyle: oauth2.AuthStyleInParams}, true
+	}
+	return func() {
+		config.GetProviderRevocation = original
+		revocationServer.Close()
+	}
+}
+
+// logout logs the session out of the provider and returns the reported revocation status.
+func logout(t *testing.T, serverURL, provider, sessionID string) string {
+	resp, err := http.DefaultClient.Do(createSessionRequest(t, "POST", serverURL+"/auth/"+provider+"/logout", sessionID))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body["revocation"]
+}
+
+func Test_PostAuthProviderLogout_ShouldRevokeRefreshTokenAtProvider(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	var revoked []string
+	defer stubRevocation(t, "tidal", http.StatusOK, &revoked)()
+
+	sessionID := uuid.New().String()
+	user := mocks.NewMockUser("tidal", "tidal-user", "Tidal User", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", user, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+
+	assert.Equal(t, services.RevocationRevoked, logout(t, setup.Server.URL, "tidal", sessionID))
+	assert.Equal(t, []string{"mock-refresh-token-tidal"}, revoked)
+}
+
+func Test_PostAuthProviderLogout_ProviderWithoutRevocation_ShouldReportLocalOnly(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sessionID := uuid.New().String()
+	user := mocks.NewMockUser("spotify", "spotify-user", "Spotify User", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", user, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+
+	assert.Equal(t, services.RevocationLocalOnly, logout(t, setup.Server.URL, "spotify", sessionID))
+}
+
+func Test_PostAuthProviderLogout_ProviderUnavailable_ShouldQueueRevocation(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	var revoked []string
+	defer stubRevocation(t, "tidal", http.StatusServiceUnavailable, &revoked)()
+
+	sessionID := uuid.New().String()
+	user := mocks.NewMockUser("tidal", "tidal-user", "Tidal User", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", user, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+
+	assert.Equal(t, services.RevocationPending, logout(t, setup.Server.URL, "tidal", sessionID))
+	queued, err := redisclient.Client.ZCard(context.Background(), "token_revocations").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), queued)
+
+	// The token is logged out locally regardless.
+	_, found := services.GetAuthToken(sessionID, "tidal", "tidal-user")
+	assert.False(t, found)
+}
+
+func Test_PostAuthProviderLogout_AfterCallbackLogin_ShouldRevokeLinkedAccountToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	var revoked []string
+	defer stubRevocation(t, "tidal", http.StatusOK, &revoked)()
+
+	// Logging in through the callback links the account to a user.
+	sessionID := loginThroughCallback(t, setup, "callback-tidal-user", "callback-refresh-token")
+	user, err := services.GetSessionUser(context.Background(), sessionID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, services.RevocationRevoked, logout(t, setup.Server.URL, "tidal", sessionID))
+	assert.Equal(t, []string{"callback-refresh-token"}, revoked)
+
+	// The revoked token is not restored into the user's next session or handed to backend jobs.
+	_, _, err = services.GetOfflineToken(context.Background(), user.ID, "tidal")
+	assert.ErrorIs(t, err, services.ErrTokenNotFound)
+}
+
+func Test_PostAuthProviderLogout_RetainLinkedTokens_ShouldKeepLinkedAccountToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	var revoked []string
+	defer stubRevocation(t, "tidal", http.StatusOK, &revoked)()
+	originalRetain := config.GetRetainLinkedTokens
+	config.GetRetainLinkedTokens = func() bool { return true }
+	defer func() { config.GetRetainLinkedTokens = originalRetain }()
+
+	sessionID := loginThroughCallback(t, setup, "retained-tidal-user", "retained-refresh-token")
+
+	assert.Equal(t, services.RevocationRetained, logout(t, setup.Server.URL, "tidal", sessionID))
+	assert.Empty(t, revoked)
+}
+
+func Test_PostAuthProviderLogout_LastClientAccount_ShouldExpireCookieWithClientSettings(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	registry := filepath.Join(t.TempDir(), "clients.json")
+	assert.NoError(t, os.WriteFile(registry, []byte(`[{"id": "web", "redirect_uris": ["https://app.example.com/"], "cookie": {"domain": "example.com", "same_site": "none"}}]`), 0o600))
+	os.Setenv("CLIENT_REGISTRY_FILE", registry)
+	assert.NoError(t, config.ReloadClients())
+	defer func() {
+		os.Unsetenv("CLIENT_REGISTRY_FILE")
+		config.ReloadClients()
+	}()
+
+	sessionID := uuid.NewString()
+	mockUser := mocks.NewMockUser("spotify", "mock-user-1", "User One", "user1@example.com")
+	err := services.StoreClientAuthToken(sessionID, "spotify", "web", mockUser, mocks.NewMockOAuth2Token("spotify", time.Hour))
+	assert.NoError(t, err)
+
+	req := createSessionRequest(t, "POST", setup.Server.URL+"/auth/spotify/logout?user_id=mock-user-1", sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The cookie must be expired with the domain and SameSite it was set with, or the browser keeps it.
+	var expired *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			expired = cookie
+		}
+	}
+	if assert.NotNil(t, expired, "The session cookie should be expired") {
+		assert.Equal(t, -1, expired.MaxAge)
+		assert.Equal(t, "example.com", expired.Domain)
+		assert.Equal(t, http.SameSiteNoneMode, expired.SameSite)
+	}
+}
@@ -0,0 +1,183 @@
+package auth_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/models"
+	"auth-service/redisclient"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// postRefresh calls the force-refresh endpoint with the session cookie.
+func postRefresh(t *testing.T, serverURL, provider, userID, sessionID string) *http.Response {
+	req, err := http.NewRequest("POST", serverURL+"/auth/"+provider+"/refresh?user_id="+userID, nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func Test_PostAuthProviderRefresh_UnexpiredToken_ShouldRefresh(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken("refresh-session-id", "spotify", user, &oauth2.Token{
+		AccessToken:  "rejected-access-token",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		assert.Equal(t, "valid-refresh-token", refreshToken)
+		return &oauth2.Token{AccessToken: "forced-access-token", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	resp := postRefresh(t, setup.Server.URL, "spotify", "mock-user-id", "refresh-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "forced-access-token", response.AccessToken)
+	assert.Equal(t, "refreshed", response.RefreshStatus)
+
+	stored, found := services.GetAuthToken("refresh-session-id", "spotify", "mock-user-id")
+	assert.True(t, found)
+	assert.Equal(t, "forced-access-token", stored.Token.AccessToken)
+}
+
+func Test_PostAuthProviderRefresh_ConcurrentRequests_ShouldRefreshOnce(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken("refresh-concurrent-session-id", "spotify", user, &oauth2.Token{
+		AccessToken:  "rejected-access-token",
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	var refreshes atomic.Int32
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		refreshes.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		return &oauth2.Token{AccessToken: "forced-access-token", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := postRefresh(t, setup.Server.URL, "spotify", "mock-user-id", "refresh-concurrent-session-id")
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), refreshes.Load())
+}
+
+func Test_PostAuthProviderRefresh_RefreshFails_ShouldReturnReauthRequired(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken("refresh-revoked-session-id", "spotify", user, &oauth2.Token{
+		AccessToken:  "rejected-access-token",
+		RefreshToken: "revoked-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		return nil, fmt.Errorf("oauth2: \"invalid_grant\" \"Refresh token revoked\"")
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	resp := postRefresh(t, setup.Server.URL, "spotify", "mock-user-id", "refresh-revoked-session-id")
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+	assert.Equal(t, "reauth_required", problem.Code)
+}
+
+func Test_PostAuthProviderRefresh_DuringMassRevocation_ShouldNotRefresh(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "mock-user-id", DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken("refresh-suspended-session-id", "spotify", user, &oauth2.Token{
+		AccessToken:  "revoked-access-token",
+		RefreshToken: "revoked-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, redisclient.Client.Set(context.Background(), "mass_revocation_active:spotify:*", "running-revocation", 0).Err())
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		t.Error("A token being mass revoked should not be refreshed")
+		return nil, fmt.Errorf("unexpected refresh")
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	resp := postRefresh(t, setup.Server.URL, "spotify", "mock-user-id", "refresh-suspended-session-id")
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+	assert.Equal(t, "reauth_required", problem.Code)
+}
+
+func Test_PostAuthProviderRefresh_CredentialProvider_ShouldReturnNotRefreshable(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	user := &models.UserInfo{ID: "1234567", DisplayName: "Qobuz User"}
+	token := &oauth2.Token{AccessToken: "qobuz-user-auth-token", TokenType: "X-User-Auth-Token"}
+	assert.NoError(t, services.StoreAuthToken("refresh-qobuz-session-id", "qobuz", user, token))
+
+	resp := postRefresh(t, setup.Server.URL, "qobuz", "1234567", "refresh-qobuz-session-id")
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusConflict, problem.Status)
+	assert.Equal(t, "token_not_refreshable", problem.Code)
+}
+
+func Test_PostAuthProviderRefresh_TokenNotFound_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := postRefresh(t, setup.Server.URL, "spotify", "mock-user-id", "invalid-session")
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "token_not_found", problem.Code)
+}
@@ -0,0 +1,125 @@
+package auth_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/redisclient"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// putPrimary sets the session's primary account for the provider.
+func putPrimary(t *testing.T, serverURL, provider, userID, sessionID string) *http.Response {
+	body, err := json.Marshal(generated.PrimaryAccountRequest{UserId: userID})
+	assert.NoError(t, err)
+	req, err := http.NewRequest("PUT", serverURL+"/auth/"+provider+"/primary", bytes.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+// getPrimaryToken calls the v2 token endpoint without a user ID and returns the account it resolved to.
+func getPrimaryToken(t *testing.T, serverURL, provider, sessionID string) string {
+	req, err := http.NewRequest("GET", serverURL+"/v2/auth/"+provider+"/token", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return response.ProviderUserId
+}
+
+// storeSpotifyAccounts links the users to the session in order.
+func storeSpotifyAccounts(t *testing.T, sessionID string, userIDs ...string) {
+	for _, userID := range userIDs {
+		user := mocks.NewMockUser("spotify", userID, userID, "")
+		assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", user, mocks.NewMockOAuth2Token("spotify", time.Hour)))
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_PutAuthProviderPrimary_ShouldBeUsedWhenUserIDIsOmitted(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID := uuid.New().String()
+	storeSpotifyAccounts(t, sessionID, "first-user", "second-user")
+
+	// Until one is chosen, the earliest linked account is primary.
+	assert.Equal(t, "first-user", getPrimaryToken(t, setup.Server.URL, "spotify", sessionID))
+
+	resp := putPrimary(t, setup.Server.URL, "spotify", "second-user", sessionID)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	assert.Equal(t, "second-user", getPrimaryToken(t, setup.Server.URL, "spotify", sessionID))
+}
+
+func Test_PutAuthProviderPrimary_PrimaryLoggedOut_ShouldReassign(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID := uuid.New().String()
+	storeSpotifyAccounts(t, sessionID, "first-user", "second-user", "third-user")
+
+	resp := putPrimary(t, setup.Server.URL, "spotify", "third-user", sessionID)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	req, err := http.NewRequest("POST", setup.Server.URL+"/auth/spotify/logout?user_id=third-user", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, "first-user", getPrimaryToken(t, setup.Server.URL, "spotify", sessionID))
+}
+
+func Test_PutAuthProviderPrimary_ResolvedPrimary_ShouldBeRecordedAndRepairedWhenExpired(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+	storeSpotifyAccounts(t, sessionID, "first-user", "second-user")
+
+	// The primary account is recorded, so later token requests do not scan the session's accounts.
+	assert.Equal(t, "first-user", getPrimaryToken(t, setup.Server.URL, "spotify", sessionID))
+	recorded, err := redisclient.Client.HGet(ctx, "session_primary:"+sessionID, "spotify").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "first-user", recorded)
+
+	// A recorded primary whose token has expired falls back to the earliest remaining account.
+	assert.NoError(t, redisclient.Client.Del(ctx, "session:"+sessionID+"_spotify_first-user").Err())
+	assert.Equal(t, "second-user", getPrimaryToken(t, setup.Server.URL, "spotify", sessionID))
+}
+
+func Test_PutAuthProviderPrimary_UnknownAccount_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID := uuid.New().String()
+	storeSpotifyAccounts(t, sessionID, "first-user")
+
+	resp := putPrimary(t, setup.Server.URL, "spotify", "someone-else", sessionID)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+	assert.Equal(t, "token_not_found", problem.Code)
+}
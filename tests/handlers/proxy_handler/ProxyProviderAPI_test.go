@@ -0,0 +1,177 @@
+package proxy_handler
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockProviderAPI points the proxy's spotify upstream at the handler for the duration of the test.
+func mockProviderAPI(t *testing.T, handler http.HandlerFunc) {
+	upstream := httptest.NewServer(handler)
+	original := config.GetProviderAPIBaseURL
+	config.GetProviderAPIBaseURL = func(provider string) (string, error) {
+		return upstream.URL, nil
+	}
+	t.Cleanup(func() {
+		config.GetProviderAPIBaseURL = original
+		upstream.Close()
+	})
+}
+
+func storeSpotifyToken(t *testing.T, sessionID, userID, accessToken string) {
+	user := &models.UserInfo{ID: userID, DisplayName: "John Doe", Email: "john@example.com"}
+	err := services.StoreAuthToken(sessionID, "spotify", user, &oauth2.Token{
+		AccessToken:  accessToken,
+		RefreshToken: "valid-refresh-token",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	assert.NoError(t, err)
+}
+
+func proxyRequest(t *testing.T, method, url, sessionID string) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func Test_ProxyProviderAPI_ShouldInjectTokenAndStripCallerCredentials(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	mockProviderAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/me/playlists", r.URL.Path)
+		assert.Equal(t, "limit=5", r.URL.RawQuery)
+		assert.Equal(t, "Bearer proxy-access-token", r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("Cookie"))
+		w.Header().Set("Set-Cookie", "provider=1")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items": []}`))
+	})
+	storeSpotifyToken(t, "proxy-session-id", "mock-user-id", "proxy-access-token")
+
+	resp := proxyRequest(t, "GET", setup.Server.URL+"/proxy/spotify/v1/me/playlists?limit=5", "proxy-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Set-Cookie"))
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"items": []}`, string(body))
+}
+
+func Test_ProxyProviderAPI_Unauthorized_ShouldRefreshAndRetryOnce(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	var calls atomic.Int32
+	mockProviderAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer refreshed-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id": "mock-user-id"}`))
+	})
+	storeSpotifyToken(t, "proxy-revoked-session-id", "mock-user-id", "revoked-access-token")
+
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		return &oauth2.Token{AccessToken: "refreshed-access-token", RefreshToken: refreshToken, Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	resp := proxyRequest(t, "GET", setup.Server.URL+"/proxy/spotify/v1/me", "proxy-revoked-session-id")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+
+	stored, found := services.GetAuthToken("proxy-revoked-session-id", "spotify", "mock-user-id")
+	assert.True(t, found)
+	assert.Equal(t, "refreshed-access-token", stored.Token.AccessToken)
+}
+
+func Test_ProxyProviderAPI_RetryAfter_ShouldHoldBackFurtherRequests(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	var calls atomic.Int32
+	mockProviderAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	storeSpotifyToken(t, "proxy-limited-session-id", "mock-user-id", "proxy-access-token")
+
+	resp := proxyRequest(t, "GET", setup.Server.URL+"/proxy/spotify/v1/me", "proxy-limited-session-id")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "30", resp.Header.Get("Retry-After"))
+
+	resp = proxyRequest(t, "GET", setup.Server.URL+"/proxy/spotify/v1/me", "proxy-limited-session-id")
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, "rate_limited", problem.Code)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func Test_ProxyProviderAPI_SeveralAccounts_ShouldUsePrimaryUnlessSelected(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	var authorization atomic.Value
+	mockProviderAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("X-Provider-User-Id"))
+		authorization.Store(r.Header.Get("Authorization"))
+	})
+	storeSpotifyToken(t, "proxy-multi-session-id", "first-user-id", "first-access-token")
+	time.Sleep(10 * time.Millisecond)
+	storeSpotifyToken(t, "proxy-multi-session-id", "second-user-id", "second-access-token")
+
+	// The earliest linked account is primary.
+	resp := proxyRequest(t, "GET", setup.Server.URL+"/proxy/spotify/v1/me", "proxy-multi-session-id")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer first-access-token", authorization.Load())
+
+	req, err := http.NewRequest("GET", setup.Server.URL+"/proxy/spotify/v1/me", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Provider-User-Id", "second-user-id")
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "proxy-multi-session-id"})
+
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer second-access-token", authorization.Load())
+}
+
+func Test_ProxyProviderAPI_MissingSession_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp, err := http.Get(setup.Server.URL + "/proxy/spotify/v1/me")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	problem := tests.DecodeProblem(t, resp)
+	assert.Equal(t, http.StatusUnauthorized, problem.Status)
+	assert.Equal(t, "session_missing", problem.Code)
+}
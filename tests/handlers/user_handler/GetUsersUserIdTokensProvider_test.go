@@ -0,0 +1,153 @@
+package user_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"auth-service/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// getOfflineToken calls the offline token endpoint as an internal caller.
+func getOfflineToken(t *testing.T, serverURL, userID, provider string) *http.Response {
+	req, err := http.NewRequest("GET", serverURL+"/users/"+userID+"/tokens/"+provider, nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Internal-Api-Key", os.Getenv("INTERNAL_API_KEY"))
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+// loginUser logs the account in to a new session and returns the session and its internal user.
+func loginUser(t *testing.T, provider, userID string, expiresIn time.Duration) (string, *services.User) {
+	sessionID := uuid.New().String()
+	user := mocks.NewMockUser(provider, userID, userID, "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, provider, user, mocks.NewMockOAuth2Token(provider, expiresIn)))
+	internalUser, err := services.ResolveSessionUser(context.Background(), sessionID, provider, userID, false)
+	assert.NoError(t, err)
+	return sessionID, internalUser
+}
+
+func Test_GetUsersUserIdTokensProvider_WithoutInternalKey_ShouldReturn401(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp, err := http.Get(setup.Server.URL + "/users/some-user/tokens/spotify")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "unauthorized", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_GetUsersUserIdTokensProvider_ShouldReturnGrantWithoutRefreshToken(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	_, user := loginUser(t, "spotify", "spotify-user", time.Hour)
+
+	resp := getOfflineToken(t, setup.Server.URL, user.ID, "spotify")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var token generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	assert.Equal(t, "mock-access-token-spotify", token.AccessToken)
+	assert.Equal(t, "spotify-user", token.ProviderUserId)
+	assert.Equal(t, services.RefreshStatusValid, token.RefreshStatus)
+	assert.Nil(t, token.RefreshToken)
+}
+
+func Test_GetUsersUserIdTokensProvider_ExpiredGrant_ShouldRefreshAndShareWithSessions(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID, user := loginUser(t, "spotify", "spotify-user", -time.Hour)
+
+	var refreshes atomic.Int32
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		refreshes.Add(1)
+		return &oauth2.Token{AccessToken: "offline-access-token", RefreshToken: "rotated-refresh-token", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	resp := getOfflineToken(t, setup.Server.URL, user.ID, "spotify")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var token generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	assert.Equal(t, "offline-access-token", token.AccessToken)
+	assert.Equal(t, services.RefreshStatusRefreshed, token.RefreshStatus)
+
+	// The session adopts the grant's token instead of spending its now-rotated refresh token.
+	authData, _, err := services.GetValidAuthToken(context.Background(), sessionID, "spotify", "spotify-user")
+	assert.NoError(t, err)
+	assert.Equal(t, "offline-access-token", authData.Token.AccessToken)
+	assert.Equal(t, "rotated-refresh-token", authData.Token.RefreshToken)
+	assert.Equal(t, int32(1), refreshes.Load())
+}
+
+func Test_GetUsersUserIdTokensProvider_TransientRefreshFailure_ShouldKeepGrantUsable(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	_, user := loginUser(t, "spotify", "spotify-user", -time.Hour)
+
+	// The provider cannot be reached for the first refresh, then recovers.
+	var refreshes atomic.Int32
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		if refreshes.Add(1) == 1 {
+			return nil, fmt.Errorf("failed to refresh token: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+		}
+		return &oauth2.Token{AccessToken: "offline-access-token", RefreshToken: refreshToken, Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	failed := getOfflineToken(t, setup.Server.URL, user.ID, "spotify")
+	failed.Body.Close()
+	assert.NotEqual(t, http.StatusOK, failed.StatusCode)
+
+	resp := getOfflineToken(t, setup.Server.URL, user.ID, "spotify")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var token generated.ProviderToken
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	assert.Equal(t, "offline-access-token", token.AccessToken)
+	assert.Equal(t, int32(2), refreshes.Load())
+}
+
+func Test_GetUsersUserIdTokensProvider_UnlinkedIdentity_ShouldRevokeGrant(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	ctx := context.Background()
+	sessionID, user := loginUser(t, "spotify", "spotify-user", time.Hour)
+	tidalUser := mocks.NewMockUser("tidal", "tidal-user", "tidal-user", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "tidal", tidalUser, mocks.NewMockOAuth2Token("tidal", time.Hour)))
+	_, err := services.LinkIdentity(ctx, sessionID, "tidal", "tidal-user")
+	assert.NoError(t, err)
+
+	resp := getOfflineToken(t, setup.Server.URL, user.ID, "tidal")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = services.UnlinkIdentity(ctx, sessionID, "tidal", "tidal-user")
+	assert.NoError(t, err)
+
+	resp = getOfflineToken(t, setup.Server.URL, user.ID, "tidal")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "token_not_found", tests.DecodeProblem(t, resp).Code)
+}
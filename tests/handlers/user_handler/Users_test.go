@@ -0,0 +1,152 @@
+package user_handler
+
+import (
+	"auth-service/generated"
+	"auth-service/services"
+	"auth-service/tests"
+	"auth-service/tests/mocks"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// usersRequest calls a /users endpoint with the session cookie.
+func usersRequest(t *testing.T, method, url, sessionID string, body interface{}) *http.Response {
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+// storeAccount logs the account in to the session without resolving a user, like sessions from
+// before internal users.
+func storeAccount(t *testing.T, sessionID, provider, userID string) {
+	user := mocks.NewMockUser(provider, userID, userID, userID+"@example.com")
+	assert.NoError(t, services.StoreAuthToken(sessionID, provider, user, mocks.NewMockOAuth2Token(provider, time.Hour)))
+}
+
+func Test_GetUsersMe_SessionWithoutUser_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := usersRequest(t, "GET", setup.Server.URL+"/users/me", uuid.New().String(), nil)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "user_not_found", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_PostUsersMeIdentities_ShouldLinkSessionAccounts(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	sessionID := uuid.New().String()
+	storeAccount(t, sessionID, "spotify", "spotify-user")
+	storeAccount(t, sessionID, "tidal", "tidal-user")
+
+	resp := usersRequest(t, "POST", setup.Server.URL+"/users/me/identities", sessionID, generated.LinkIdentityRequest{Provider: "spotify", UserId: "spotify-user"})
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = usersRequest(t, "POST", setup.Server.URL+"/users/me/identities", sessionID, generated.LinkIdentityRequest{Provider: "tidal", UserId: "tidal-user"})
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var user generated.User
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&user))
+	assert.NotEmpty(t, user.Id)
+	if assert.Len(t, user.Identities, 2) {
+		assert.Equal(t, "spotify", user.Identities[0].Provider)
+		assert.Equal(t, "tidal-user", user.Identities[1].UserId)
+		assert.Equal(t, "tidal-user@example.com", user.Identities[1].Email)
+	}
+
+	me := usersRequest(t, "GET", setup.Server.URL+"/users/me", sessionID, nil)
+	defer me.Body.Close()
+	assert.Equal(t, http.StatusOK, me.StatusCode)
+}
+
+func Test_PostUsersMeIdentities_AccountNotInSession_ShouldReturn404(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := usersRequest(t, "POST", setup.Server.URL+"/users/me/identities", uuid.New().String(), generated.LinkIdentityRequest{Provider: "spotify", UserId: "someone-else"})
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "token_not_found", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_PostUsersMeIdentities_IdentityOfAnotherUser_ShouldReturn409(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	ctx := context.Background()
+
+	// The spotify account already belongs to a user from another session.
+	otherSession := uuid.New().String()
+	storeAccount(t, otherSession, "spotify", "spotify-user")
+	_, err := services.ResolveSessionUser(ctx, otherSession, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+
+	sessionID := uuid.New().String()
+	storeAccount(t, sessionID, "tidal", "tidal-user")
+	_, err = services.ResolveSessionUser(ctx, sessionID, "tidal", "tidal-user", false)
+	assert.NoError(t, err)
+	storeAccount(t, sessionID, "spotify", "spotify-user")
+
+	resp := usersRequest(t, "POST", setup.Server.URL+"/users/me/identities", sessionID, generated.LinkIdentityRequest{Provider: "spotify", UserId: "spotify-user"})
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "identity_linked", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_DeleteUsersMeIdentities_ShouldUnlinkButRefuseLastIdentity(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+	storeAccount(t, sessionID, "spotify", "spotify-user")
+	storeAccount(t, sessionID, "tidal", "tidal-user")
+	_, err := services.ResolveSessionUser(ctx, sessionID, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	_, err = services.LinkIdentity(ctx, sessionID, "tidal", "tidal-user")
+	assert.NoError(t, err)
+
+	resp := usersRequest(t, "DELETE", setup.Server.URL+"/users/me/identities/tidal/tidal-user", sessionID, nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var user generated.User
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&user))
+	assert.Len(t, user.Identities, 1)
+
+	// The account is logged out of the session, as its token was revoked.
+	_, found := services.GetAuthToken(sessionID, "tidal", "tidal-user")
+	assert.False(t, found)
+
+	last := usersRequest(t, "DELETE", setup.Server.URL+"/users/me/identities/spotify/spotify-user", sessionID, nil)
+	defer last.Body.Close()
+	assert.Equal(t, http.StatusConflict, last.StatusCode)
+	assert.Equal(t, "last_identity", tests.DecodeProblem(t, last).Code)
+
+	missing := usersRequest(t, "DELETE", setup.Server.URL+"/users/me/identities/tidal/tidal-user", sessionID, nil)
+	defer missing.Body.Close()
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+	assert.Equal(t, "identity_not_found", tests.DecodeProblem(t, missing).Code)
+}
@@ -0,0 +1,19 @@
+package tests
+
+import (
+	"auth-service/generated"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// DecodeProblem asserts that the response is an application/problem+json error and decodes it.
+func DecodeProblem(t *testing.T, resp *http.Response) generated.Problem {
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var problem generated.Problem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	assert.Equal(t, resp.StatusCode, problem.Status)
+	return problem
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"auth-service/tests"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func sendPreflight(t *testing.T, url, origin string) *http.Response {
+	req, err := http.NewRequest(http.MethodOptions, url, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func Test_CORS_ListedOrigin_ShouldAllowCredentials(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := sendPreflight(t, setup.Server.URL+"/auth/status", "http://localhost:5173")
+	defer resp.Body.Close()
+
+	assert.Equal(t, "http://localhost:5173", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+func Test_CORS_ClientRegistryOrigin_ShouldAllowCredentials(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// The partner widget origin comes from the client registry.
+	resp := sendPreflight(t, setup.Server.URL+"/auth/status", "https://widget.partner.example")
+	defer resp.Body.Close()
+
+	assert.Equal(t, "https://widget.partner.example", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+func Test_CORS_WildcardOrigin_ShouldNotAllowCredentials(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := sendPreflight(t, setup.Server.URL+"/auth/status", "https://app.staging.example.com")
+	defer resp.Body.Close()
+
+	assert.Equal(t, "https://app.staging.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+}
+
+func Test_CORS_UnknownOrigin_ShouldNotBeAllowed(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := sendPreflight(t, setup.Server.URL+"/auth/status", "https://evil.example.org")
+	defer resp.Body.Close()
+
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"auth-service/tests"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// sendLogout posts a logout with the session cookie and the given browser headers.
+func sendLogout(t *testing.T, serverURL string, headers map[string]string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, serverURL+"/auth/spotify/logout", nil)
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "test-session-id"})
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp
+}
+
+func Test_CSRF_CrossSiteFormPost_ShouldReturn403(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := sendLogout(t, setup.Server.URL, map[string]string{
+		"Origin":         "https://evil.example.org",
+		"Sec-Fetch-Site": "cross-site",
+	})
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "cross_origin_request", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_CSRF_WildcardOrigin_ShouldReturn403(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	// Wildcard origins may call the API, but not with the session cookie.
+	resp := sendLogout(t, setup.Server.URL, map[string]string{
+		"Origin":         "https://app.staging.example.com",
+		"Sec-Fetch-Site": "same-site",
+	})
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func Test_CSRF_AllowedOrigin_ShouldPass(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	resp := sendLogout(t, setup.Server.URL, map[string]string{
+		"Origin":         "http://localhost:5173",
+		"Sec-Fetch-Site": "cross-site",
+	})
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func Test_CSRF_SameOriginOrNonBrowser_ShouldPass(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	sameOrigin := sendLogout(t, setup.Server.URL, map[string]string{"Sec-Fetch-Site": "same-origin"})
+	defer sameOrigin.Body.Close()
+	assert.Equal(t, http.StatusOK, sameOrigin.StatusCode)
+
+	nonBrowser := sendLogout(t, setup.Server.URL, nil)
+	defer nonBrowser.Body.Close()
+	assert.Equal(t, http.StatusOK, nonBrowser.StatusCode)
+}
+
+func Test_CSRF_CrossOriginProxyPost_ShouldReturn403(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+
+	req, err := http.NewRequest(http.MethodPost, setup.Server.URL+"/proxy/spotify/v1/me/playlists", strings.NewReader(`{"name": "Hijacked"}`))
+	assert.NoError(t, err)
+	req.AddCookie(&http.Cookie{Name: "session_id", Value: "test-session-id"})
+	req.Header.Set("Origin", "https://evil.example.org")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "cross_origin_request", tests.DecodeProblem(t, resp).Code)
+}
+
+func Test_CSRF_CrossSiteSignOutLink_ShouldReturn403(t *testing.T) {
+	setup := tests.InitializeTestEnvironment(t)
+	defer setup.Cleanup()
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost,127.0.0.1")
+	defer os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	signOut := func(secFetchSite string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, setup.Server.URL+"/auth/logout?redirect_uri="+url.QueryEscape(setup.Server.URL+"/signed-out"), nil)
+		assert.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: "test-session-id"})
+		req.Header.Set("Sec-Fetch-Site", secFetchSite)
+		client := &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	crossSite := signOut("cross-site")
+	defer crossSite.Body.Close()
+	assert.Equal(t, http.StatusForbidden, crossSite.StatusCode)
+	assert.Equal(t, "cross_origin_request", tests.DecodeProblem(t, crossSite).Code)
+
+	sameSite := signOut("same-site")
+	defer sameSite.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, sameSite.StatusCode)
+}
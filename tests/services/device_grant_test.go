@@ -0,0 +1,60 @@
+package services
+
+import (
+	"auth-service/services"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollDeviceGrant_ConcurrentPolls_ShouldHandOverSessionOnce(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	grant, err := services.CreateDeviceGrant("spotify", "192.0.2.1", "TV")
+	assert.NoError(t, err)
+	assert.NoError(t, services.ApproveDeviceGrant(grant.DeviceCode, "session-1", "user-1"))
+
+	const polls = 10
+	var wg sync.WaitGroup
+	results := make(chan error, polls)
+	for i := 0; i < polls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := services.PollDeviceGrant(grant.DeviceCode)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	collected := 0
+	for err := range results {
+		if err == nil {
+			collected++
+			continue
+		}
+		assert.True(t, errors.Is(err, services.ErrExpiredToken), "unexpected error: %v", err)
+	}
+	assert.Equal(t, 1, collected)
+}
+
+func TestDeviceGrant_SettledGrant_ShouldNotBeChangedByPollsOrLaterDecisions(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	grant, err := services.CreateDeviceGrant("spotify", "192.0.2.1", "TV")
+	assert.NoError(t, err)
+	_, err = services.PollDeviceGrant(grant.DeviceCode)
+	assert.ErrorIs(t, err, services.ErrAuthorizationPending)
+
+	assert.NoError(t, services.ApproveDeviceGrant(grant.DeviceCode, "session-1", "user-1"))
+	assert.ErrorIs(t, services.DenyDeviceGrant(grant.DeviceCode), services.ErrDeviceGrantSettled)
+
+	collected, err := services.PollDeviceGrant(grant.DeviceCode)
+	assert.NoError(t, err)
+	assert.Equal(t, "session-1", collected.SessionID)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"auth-service/services"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"testing"
+)
+
+// grantingScopes returns a token whose response granted the scopes.
+func grantingScopes(scope string) *oauth2.Token {
+	return (&oauth2.Token{AccessToken: "access-token"}).WithExtra(map[string]interface{}{"scope": scope})
+}
+
+func TestValidateProfile_MissingRequiredDisplayName_ShouldBeReportedMissing(t *testing.T) {
+	user := &models.UserInfo{ID: "spotify-user", Email: "user@example.com"}
+
+	validation := services.ValidateProfile("spotify", user, grantingScopes("user-read-email user-read-private"))
+
+	assert.False(t, validation.Valid)
+	assert.Equal(t, []string{config.ProfileFieldDisplayName}, validation.Missing)
+	assert.Empty(t, validation.Fallbacks)
+	assert.Equal(t, "spotify-user", user.DisplayName, "The user ID should still stand in for the display name")
+	assert.Same(t, validation, user.Validation)
+}
+
+func TestValidateProfile_MissingOptionalDisplayName_ShouldFallBackToUserID(t *testing.T) {
+	original := config.GetProfileRequirements
+	config.GetProfileRequirements = func(provider string) config.ProfileRequirements {
+		return config.ProfileRequirements{Required: []string{config.ProfileFieldEmail}}
+	}
+	defer func() { config.GetProfileRequirements = original }()
+	user := &models.UserInfo{ID: "spotify-user", Email: "user@example.com"}
+
+	validation := services.ValidateProfile("spotify", user, grantingScopes("user-read-email"))
+
+	assert.True(t, validation.Valid)
+	assert.Equal(t, []string{config.ProfileFieldDisplayName}, validation.Fallbacks)
+	assert.Equal(t, "spotify-user", user.DisplayName)
+}
+
+func TestValidateProfile_MissingEmail_ShouldOnlyBeRequiredWhenScopeGranted(t *testing.T) {
+	withScope := services.ValidateProfile("spotify", &models.UserInfo{ID: "user1", DisplayName: "User"}, grantingScopes("user-read-email"))
+	assert.False(t, withScope.Valid)
+	assert.Equal(t, []string{config.ProfileFieldEmail}, withScope.Missing)
+
+	withoutScope := services.ValidateProfile("spotify", &models.UserInfo{ID: "user1", DisplayName: "User"}, grantingScopes("user-read-private"))
+	assert.True(t, withoutScope.Valid)
+	assert.Empty(t, withoutScope.Missing)
+}
+
+func TestValidateProfile_EmailNotRequired_ShouldBeValid(t *testing.T) {
+	original := config.GetProfileRequirements
+	config.GetProfileRequirements = func(provider string) config.ProfileRequirements {
+		return config.ProfileRequirements{Required: []string{config.ProfileFieldDisplayName}}
+	}
+	defer func() { config.GetProfileRequirements = original }()
+
+	validation := services.ValidateProfile("tidal", &models.UserInfo{ID: "user1", DisplayName: "User"}, &oauth2.Token{AccessToken: "access-token"})
+
+	assert.True(t, validation.Valid)
+}
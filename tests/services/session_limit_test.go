@@ -0,0 +1,118 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/services"
+	"auth-service/tests/mocks"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// limitSessions caps the provider's sessions per account for the test.
+func limitSessions(max int, policy string) func() {
+	original := config.GetSessionLimit
+	config.GetSessionLimit = func(provider string) config.SessionLimit {
+		return config.SessionLimit{Max: max, Policy: policy}
+	}
+	return func() { config.GetSessionLimit = original }
+}
+
+// loginSessions logs the account in to count new sessions, oldest first.
+func loginSessions(t *testing.T, provider, userID string, count int) []string {
+	user := mocks.NewMockUser(provider, userID, userID, "")
+	var sessionIDs []string
+	for i := 0; i < count; i++ {
+		sessionID := uuid.New().String()
+		assert.NoError(t, services.StoreAuthToken(sessionID, provider, user, mocks.NewMockOAuth2Token(provider, time.Hour)))
+		sessionIDs = append(sessionIDs, sessionID)
+		time.Sleep(5 * time.Millisecond) // Keep the login times apart.
+	}
+	return sessionIDs
+}
+
+// loginWithinLimit logs the account in to the session under its session limit.
+func loginWithinLimit(t *testing.T, provider, userID, sessionID string) error {
+	user := mocks.NewMockUser(provider, userID, userID, "")
+	token := mocks.NewMockOAuth2Token(provider, time.Hour)
+	token.AccessToken += "-" + sessionID
+	token.RefreshToken += "-" + sessionID
+	return services.EnforceSessionLimit(context.Background(), provider, userID, sessionID, token, func() error {
+		return services.StoreAuthToken(sessionID, provider, user, token)
+	})
+}
+
+func TestEnforceSessionLimit_EvictOldest_ShouldLogOutOldestSessionAndAudit(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	defer limitSessions(2, config.SessionLimitEvictOldest)()
+	ctx := context.Background()
+
+	sessionIDs := loginSessions(t, "spotify", "spotify-user", 2)
+	newSession := uuid.New().String()
+
+	assert.NoError(t, loginWithinLimit(t, "spotify", "spotify-user", newSession))
+
+	_, found := services.GetAuthToken(sessionIDs[0], "spotify", "spotify-user")
+	assert.False(t, found, "The oldest session should be evicted")
+	_, found = services.GetAuthToken(sessionIDs[1], "spotify", "spotify-user")
+	assert.True(t, found)
+	_, found = services.GetAuthToken(newSession, "spotify", "spotify-user")
+	assert.True(t, found)
+
+	events, err := services.GetAuditEvents(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, services.AuditSessionEvicted, events[0].Type)
+		assert.Equal(t, sessionIDs[0], events[0].SessionID)
+		assert.Equal(t, newSession, events[0].Details["new_session_id"])
+	}
+}
+
+func TestEnforceSessionLimit_Reject_ShouldRefuseNewSessionOnly(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	defer limitSessions(2, config.SessionLimitReject)()
+
+	sessionIDs := loginSessions(t, "spotify", "spotify-user", 2)
+
+	rejectedSession := uuid.New().String()
+	err := loginWithinLimit(t, "spotify", "spotify-user", rejectedSession)
+	assert.ErrorIs(t, err, services.ErrSessionLimitReached)
+	_, found := services.GetAuthToken(rejectedSession, "spotify", "spotify-user")
+	assert.False(t, found, "A rejected login should not be stored")
+	// Logging in again to a session that already holds the account is not a new session.
+	assert.NoError(t, loginWithinLimit(t, "spotify", "spotify-user", sessionIDs[1]))
+
+	// Logged-out sessions no longer count.
+	assert.NoError(t, services.DeleteAuthToken(sessionIDs[0], "spotify", "spotify-user"))
+	assert.NoError(t, loginWithinLimit(t, "spotify", "spotify-user", uuid.New().String()))
+}
+
+func TestEnforceSessionLimit_ConcurrentLogins_ShouldNotExceedLimit(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	defer limitSessions(1, config.SessionLimitReject)()
+
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := loginWithinLimit(t, "spotify", "spotify-user", uuid.New().String())
+			if err == nil {
+				accepted.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, services.ErrSessionLimitReached)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), accepted.Load(), "Only one of the concurrent logins should fit under the limit")
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"auth-service/config"
+	"auth-service/redisclient"
+	"auth-service/services"
+	"auth-service/tests/mocks"
+	"auth-service/utils"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// loginAs stores the account in the session and resolves the session's user, as a login does.
+func loginAs(t *testing.T, sessionID, provider, userID string, link bool) (*services.User, error) {
+	user := mocks.NewMockUser(provider, userID, userID, "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, provider, user, mocks.NewMockOAuth2Token(provider, time.Hour)))
+	return services.ResolveSessionUser(context.Background(), sessionID, provider, userID, link)
+}
+
+func TestResolveSessionUser_LinkedIdentity_ShouldRestoreAccountsIntoNewSession(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// First login creates the user; the tidal account is then linked in the same session.
+	firstSession := uuid.New().String()
+	user, err := loginAs(t, firstSession, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	linked, err := loginAs(t, firstSession, "tidal", "tidal-user", true)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, linked.ID)
+	assert.Len(t, linked.Identities, 2)
+
+	// A later login with either identity, in a fresh session, resolves to the same user.
+	secondSession := uuid.New().String()
+	resolved, err := loginAs(t, secondSession, "tidal", "tidal-user", false)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, resolved.ID)
+
+	restored, found := services.GetAuthToken(secondSession, "spotify", "spotify-user")
+	assert.True(t, found, "The user's other linked accounts should be restored into the new session")
+	assert.NotEmpty(t, restored.Token.RefreshToken)
+
+	sessionUser, err := services.GetSessionUser(ctx, secondSession)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, sessionUser.ID)
+}
+
+func TestResolveSessionUser_UnknownIdentity_ShouldCreateSeparateUsers(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	first, err := loginAs(t, uuid.New().String(), "spotify", "first-user", false)
+	assert.NoError(t, err)
+	second, err := loginAs(t, uuid.New().String(), "spotify", "second-user", false)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Len(t, first.Identities, 1)
+}
+
+func TestResolveSessionUser_LinkIdentityOfAnotherUser_ShouldFail(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	_, err := loginAs(t, uuid.New().String(), "tidal", "tidal-user", false)
+	assert.NoError(t, err)
+
+	_, err = loginAs(t, uuid.New().String(), "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	otherSession := uuid.New().String()
+	_, err = loginAs(t, otherSession, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+
+	_, err = loginAs(t, otherSession, "tidal", "tidal-user", true)
+	assert.ErrorIs(t, err, services.ErrIdentityLinked)
+}
+
+func TestUnlinkIdentity_ShouldStopRestoringAccount(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	sessionID := uuid.New().String()
+	_, err := loginAs(t, sessionID, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	_, err = loginAs(t, sessionID, "tidal", "tidal-user", true)
+	assert.NoError(t, err)
+
+	user, err := services.UnlinkIdentity(ctx, sessionID, "tidal", "tidal-user")
+	assert.NoError(t, err)
+	assert.Len(t, user.Identities, 1)
+
+	_, err = services.UnlinkIdentity(ctx, sessionID, "spotify", "spotify-user")
+	assert.ErrorIs(t, err, services.ErrLastIdentity)
+
+	newSession := uuid.New().String()
+	_, err = loginAs(t, newSession, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	_, found := services.GetAuthToken(newSession, "tidal", "tidal-user")
+	assert.False(t, found, "Unlinked accounts should not be restored")
+}
+
+func TestResolveSessionUser_DuringMassRevocation_ShouldNotRestoreAccount(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	sessionID := uuid.New().String()
+	_, err := loginAs(t, sessionID, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	_, err = loginAs(t, sessionID, "tidal", "tidal-user", true)
+	assert.NoError(t, err)
+
+	// The revocation has not reached the tidal identity token yet.
+	assert.NoError(t, redisclient.Client.Set(ctx, "mass_revocation_active:tidal:*", "running-revocation", 0).Err())
+
+	newSession := uuid.New().String()
+	_, err = loginAs(t, newSession, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	_, found := services.GetAuthToken(newSession, "tidal", "tidal-user")
+	assert.False(t, found, "Accounts being mass revoked should not be restored")
+}
+
+func TestLinkedAccount_ConcurrentSessionAndOfflineRefresh_ShouldRefreshOnce(t *testing.T) {
+	_, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	originalProviders := config.Providers
+	config.Providers = map[string]*oauth2.Config{"spotify": {}}
+	defer func() { config.Providers = originalProviders }()
+
+	sessionID := uuid.New().String()
+	user, err := loginAs(t, sessionID, "spotify", "spotify-user", false)
+	assert.NoError(t, err)
+	// Expire the token; the session and the offline grant now share one expired refresh token.
+	account := mocks.NewMockUser("spotify", "spotify-user", "spotify-user", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "spotify", account, mocks.NewMockOAuth2Token("spotify", -time.Minute)))
+
+	var refreshes atomic.Int32
+	originalRefresh := utils.RefreshAccessTokenFunc
+	utils.RefreshAccessTokenFunc = func(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+		refreshes.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		return &oauth2.Token{AccessToken: "refreshed-access-token", RefreshToken: "rotated-refresh-token", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	defer func() { utils.RefreshAccessTokenFunc = originalRefresh }()
+
+	var wg sync.WaitGroup
+	var sessionToken, offlineToken *services.AuthData
+	var sessionErr, offlineErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sessionToken, _, sessionErr = services.GetValidAuthToken(ctx, sessionID, "spotify", "spotify-user")
+	}()
+	go func() {
+		defer wg.Done()
+		offlineToken, _, offlineErr = services.GetOfflineToken(ctx, user.ID, "spotify")
+	}()
+	wg.Wait()
+
+	assert.NoError(t, sessionErr)
+	assert.NoError(t, offlineErr)
+	assert.Equal(t, int32(1), refreshes.Load(), "The shared refresh token should only be spent once")
+	assert.Equal(t, "refreshed-access-token", sessionToken.Token.AccessToken)
+	assert.Equal(t, "refreshed-access-token", offlineToken.Token.AccessToken)
+}
+
+func TestStoreAuthToken_NonExpiringToken_ShouldExpireWithAbandonedSession(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// Credential provider tokens, such as Qobuz's, have neither an expiry nor a refresh token.
+	sessionID := uuid.New().String()
+	user := mocks.NewMockUser("qobuz", "qobuz-user", "qobuz-user", "")
+	assert.NoError(t, services.StoreAuthToken(sessionID, "qobuz", user, &oauth2.Token{AccessToken: "qobuz-user-auth-token"}))
+	_, err := services.ResolveSessionUser(ctx, sessionID, "qobuz", "qobuz-user", false)
+	assert.NoError(t, err)
+
+	for _, key := range []string{"session:" + sessionID + "_qobuz_qobuz-user", "identity_token:qobuz:qobuz-user"} {
+		ttl, err := client.TTL(ctx, key).Result()
+		assert.NoError(t, err)
+		assert.Greater(t, ttl, time.Duration(0), "%s should expire", key)
+		assert.LessOrEqual(t, ttl, 30*24*time.Hour, "%s should expire", key)
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"auth-service/utils"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ClientIP_WithoutTrustedProxy_ShouldIgnoreForwardedFor(t *testing.T) {
+	req := httptest.NewRequest("GET", "/auth/device", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	assert.Equal(t, "203.0.113.7", utils.ClientIP(req, nil))
+}
+
+func Test_ClientIP_BehindTrustedProxy_ShouldUseClosestUntrustedHop(t *testing.T) {
+	proxies, err := utils.ParseTrustedProxies("10.0.0.0/8, 192.0.2.10")
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/auth/device", nil)
+	req.RemoteAddr = "10.1.2.3:443"
+	// The client claimed to be 198.51.100.1; the load balancer appended its real address, and an inner
+	// proxy appended the load balancer's.
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 192.0.2.10")
+
+	assert.Equal(t, "203.0.113.7", utils.ClientIP(req, proxies))
+}
+
+func Test_ParseTrustedProxies_Invalid_ShouldFail(t *testing.T) {
+	_, err := utils.ParseTrustedProxies("10.0.0.0/8,not-an-ip")
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"auth-service/utils"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func mustParseRules(t *testing.T, patterns ...string) []utils.RedirectRule {
+	var rules []utils.RedirectRule
+	for _, pattern := range patterns {
+		rule, err := utils.ParseRedirectRule(pattern)
+		assert.NoError(t, err)
+		rules = append(rules, *rule)
+	}
+	return rules
+}
+
+func Test_ValidateRedirectURI_ShouldFailForDomainSharingSuffix(t *testing.T) {
+	allowedDomains := []string{"example.com"}
+
+	result := utils.ValidateRedirectURI("http://evilexample.com/callback", allowedDomains)
+	assert.False(t, result)
+}
+
+func Test_ParseRedirectRule_ShouldRejectHTTPForNonLocalhost(t *testing.T) {
+	_, err := utils.ParseRedirectRule("http://app.example.com")
+	assert.Error(t, err)
+
+	_, err = utils.ParseRedirectRule("http://localhost:*")
+	assert.NoError(t, err)
+}
+
+func Test_ParseRedirectRule_ShouldRejectInvalidWildcards(t *testing.T) {
+	_, err := utils.ParseRedirectRule("https://app.*.example.com")
+	assert.Error(t, err)
+
+	_, err = utils.ParseRedirectRule("https://*")
+	assert.Error(t, err)
+}
+
+func Test_ValidateRedirectURIAgainstRules_ExactHost(t *testing.T) {
+	rules := mustParseRules(t, "https://app.example.com")
+
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://app.example.com/callback", rules))
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://APP.example.com:443/callback", rules))
+
+	err := utils.ValidateRedirectURIAgainstRules("https://sub.app.example.com/callback", rules)
+	assert.EqualError(t, err, "invalid redirect URI: host sub.app.example.com is not registered")
+
+	err = utils.ValidateRedirectURIAgainstRules("https://evilapp.example.com/callback", rules)
+	assert.Error(t, err)
+}
+
+func Test_ValidateRedirectURIAgainstRules_WildcardSubdomain(t *testing.T) {
+	rules := mustParseRules(t, "https://*.example.com")
+
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://app.example.com/callback", rules))
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://a.b.example.com/callback", rules))
+
+	// The wildcard does not cover the apex domain or look-alike domains.
+	assert.Error(t, utils.ValidateRedirectURIAgainstRules("https://example.com/callback", rules))
+	assert.Error(t, utils.ValidateRedirectURIAgainstRules("https://evilexample.com/callback", rules))
+	assert.Error(t, utils.ValidateRedirectURIAgainstRules("https://example.com.evil.com/callback", rules))
+}
+
+func Test_ValidateRedirectURIAgainstRules_ShouldRequireHTTPSExceptLocalhost(t *testing.T) {
+	rules := mustParseRules(t, "https://app.example.com", "http://localhost:5173")
+
+	err := utils.ValidateRedirectURIAgainstRules("http://app.example.com/callback", rules)
+	assert.EqualError(t, err, "invalid redirect URI: scheme must be https; http is only allowed for localhost")
+
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("http://localhost:5173/callback", rules))
+}
+
+func Test_ValidateRedirectURIAgainstRules_PortRules(t *testing.T) {
+	rules := mustParseRules(t, "https://app.example.com", "https://api.example.com:8443", "http://localhost:*")
+
+	err := utils.ValidateRedirectURIAgainstRules("https://app.example.com:8443/callback", rules)
+	assert.EqualError(t, err, "invalid redirect URI: port 8443 is not allowed for host app.example.com")
+
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://api.example.com:8443/callback", rules))
+	assert.Error(t, utils.ValidateRedirectURIAgainstRules("https://api.example.com/callback", rules))
+
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("http://localhost:3000/callback", rules))
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("http://localhost:5173/callback", rules))
+}
+
+func Test_ValidateRedirectURIAgainstRules_PathPrefix(t *testing.T) {
+	rules := mustParseRules(t, "https://app.example.com/auth/")
+
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://app.example.com/auth", rules))
+	assert.NoError(t, utils.ValidateRedirectURIAgainstRules("https://app.example.com/auth/callback", rules))
+
+	err := utils.ValidateRedirectURIAgainstRules("https://app.example.com/authz/callback", rules)
+	assert.EqualError(t, err, "invalid redirect URI: path /authz/callback is not under the registered path /auth")
+
+	err = utils.ValidateRedirectURIAgainstRules("https://app.example.com/auth/../admin", rules)
+	assert.EqualError(t, err, "invalid redirect URI: the path must not contain dot segments")
+}
+
+func Test_ValidateRedirectURIAgainstRules_ShouldRejectUserInfo(t *testing.T) {
+	rules := mustParseRules(t, "https://app.example.com")
+
+	err := utils.ValidateRedirectURIAgainstRules("https://attacker@app.example.com/callback", rules)
+	assert.EqualError(t, err, "invalid redirect URI: the URI must not contain user info")
+}
+
+func Test_ValidateRedirectURIFromEnv_ShouldUseRegisteredPatterns(t *testing.T) {
+	os.Setenv("ALLOWED_REDIRECT_URIS", "https://*.example.com,http://localhost:*")
+	defer os.Unsetenv("ALLOWED_REDIRECT_URIS")
+	os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	assert.NoError(t, utils.ValidateRedirectURIFromEnv("https://app.example.com/callback"))
+	assert.NoError(t, utils.ValidateRedirectURIFromEnv("http://localhost:3000/callback"))
+
+	err := utils.ValidateRedirectURIFromEnv("http://app.example.com/callback")
+	assert.ErrorContains(t, err, "invalid redirect URI")
+}
+
+func Test_ValidateRedirectURIFromEnv_ShouldCombinePatternsWithLegacyDomains(t *testing.T) {
+	os.Setenv("ALLOWED_REDIRECT_URIS", "https://app.example.com")
+	defer os.Unsetenv("ALLOWED_REDIRECT_URIS")
+	os.Setenv("ALLOWED_REDIRECT_DOMAINS", "localhost")
+	defer os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	assert.NoError(t, utils.ValidateRedirectURIFromEnv("https://app.example.com/callback"))
+	assert.NoError(t, utils.ValidateRedirectURIFromEnv("http://localhost:3000/callback"))
+}
+
+func Test_ValidateRedirectURIFromEnv_ShouldFailWhenNothingIsRegistered(t *testing.T) {
+	os.Unsetenv("ALLOWED_REDIRECT_URIS")
+	os.Unsetenv("ALLOWED_REDIRECT_DOMAINS")
+
+	err := utils.ValidateRedirectURIFromEnv("https://app.example.com/callback")
+	assert.Error(t, err)
+}
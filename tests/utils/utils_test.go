@@ -3,8 +3,12 @@ package utils
 import (
 	"auth-service/utils"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 )
 
 func Test_ValidateRedirectURIFromEnv_ValidURI(t *testing.T) {
@@ -134,3 +138,19 @@ func Test_GetAllowedRedirectDomains_ShouldReturnError_WhenEmptyString(t *testing
 	assert.Nil(t, domains)
 	assert.EqualError(t, err, "ALLOWED_REDIRECT_DOMAINS is not set in the environment")
 }
+
+func Test_RefreshAccessToken_SlowProvider_ShouldTimeOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	oauthConfig := &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{TokenURL: server.URL}}
+	started := time.Now()
+	_, err := utils.RefreshAccessToken(oauthConfig, "refresh-token")
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), utils.ProviderRequestTimeout+time.Second)
+}
@@ -0,0 +1,233 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// RedirectRule is a registered redirect URI pattern, e.g. "https://app.example.com/callback",
+// "https://*.example.com" or "http://localhost:*".
+type RedirectRule struct {
+	// Schemes lists the allowed schemes. Registered patterns allow exactly one; https is required
+	// except for localhost.
+	Schemes []string
+	// Host is matched exactly, case-insensitively.
+	Host string
+	// WildcardSubdomains matches any subdomain of Host, but not Host itself.
+	WildcardSubdomains bool
+	// IncludeApex additionally matches Host itself when WildcardSubdomains is set.
+	IncludeApex bool
+	// Port is the required port. Empty means the scheme's default port and "*" means any port.
+	Port string
+	// PathPrefix, when set, must match the start of the path at a segment boundary.
+	PathPrefix string
+}
+
+// RedirectURIError explains why a redirect URI was rejected.
+type RedirectURIError struct {
+	Reason string
+}
+
+func (e *RedirectURIError) Error() string {
+	return "invalid redirect URI: " + e.Reason
+}
+
+func redirectURIError(format string, args ...interface{}) error {
+	return &RedirectURIError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// ParseRedirectRule parses a registered redirect pattern of the form scheme://[*.]host[:port|:*][/path-prefix].
+func ParseRedirectRule(pattern string) (*RedirectRule, error) {
+	pattern = strings.TrimSpace(pattern)
+	scheme, rest, found := strings.Cut(pattern, "://")
+	if !found || rest == "" {
+		return nil, fmt.Errorf("redirect pattern %q must include a scheme and host", pattern)
+	}
+	scheme = strings.ToLower(scheme)
+
+	hostPort, pathPrefix := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		hostPort, pathPrefix = rest[:i], rest[i:]
+	}
+	if strings.ContainsAny(pathPrefix, "?#") {
+		return nil, fmt.Errorf("redirect pattern %q must not include a query or fragment", pattern)
+	}
+
+	host, port := hostPort, ""
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		host, port = h, p
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+
+	rule := &RedirectRule{
+		Schemes:    []string{scheme},
+		Host:       host,
+		Port:       port,
+		PathPrefix: strings.TrimSuffix(pathPrefix, "/"),
+	}
+	if strings.HasPrefix(host, "*.") {
+		rule.Host = strings.TrimPrefix(host, "*.")
+		rule.WildcardSubdomains = true
+	}
+	if rule.Host == "" || strings.Contains(rule.Host, "*") {
+		return nil, fmt.Errorf("redirect pattern %q has an invalid host; only a leading \"*.\" wildcard is supported", pattern)
+	}
+
+	switch scheme {
+	case "https":
+	case "http":
+		if !isLoopbackHost(rule.Host) {
+			return nil, fmt.Errorf("redirect pattern %q must use https; http is only allowed for localhost", pattern)
+		}
+	default:
+		return nil, fmt.Errorf("redirect pattern %q has an unsupported scheme %q", pattern, scheme)
+	}
+	return rule, nil
+}
+
+// LegacyDomainRule converts an ALLOWED_REDIRECT_DOMAINS entry into a rule that keeps its original
+// behavior: the domain and its subdomains on any port and path, over http or https.
+func LegacyDomainRule(domain string) RedirectRule {
+	return RedirectRule{
+		Schemes:            []string{"http", "https"},
+		Host:               strings.ToLower(strings.TrimSpace(domain)),
+		WildcardSubdomains: true,
+		IncludeApex:        true,
+		Port:               "*",
+	}
+}
+
+// GetRedirectRulesFromEnv builds the redirect rules from ALLOWED_REDIRECT_URIS, a comma-separated list
+// of registered patterns, and the legacy ALLOWED_REDIRECT_DOMAINS list.
+func GetRedirectRulesFromEnv() ([]RedirectRule, error) {
+	var rules []RedirectRule
+
+	if patterns := os.Getenv("ALLOWED_REDIRECT_URIS"); patterns != "" {
+		for _, pattern := range strings.Split(patterns, ",") {
+			if strings.TrimSpace(pattern) == "" {
+				continue
+			}
+			rule, err := ParseRedirectRule(pattern)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, *rule)
+		}
+	}
+
+	if domains := os.Getenv("ALLOWED_REDIRECT_DOMAINS"); domains != "" {
+		for _, domain := range strings.Split(domains, ",") {
+			if strings.TrimSpace(domain) == "" {
+				continue
+			}
+			rules = append(rules, LegacyDomainRule(domain))
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("neither ALLOWED_REDIRECT_URIS nor ALLOWED_REDIRECT_DOMAINS is set in the environment")
+	}
+	return rules, nil
+}
+
+// ValidateRedirectURIAgainstRules returns a RedirectURIError explaining why the URI matches none of the rules.
+func ValidateRedirectURIAgainstRules(uri string, rules []RedirectRule) error {
+	parsedURL, err := url.Parse(uri)
+	if err != nil {
+		return redirectURIError("the URI could not be parsed")
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" || parsedURL.Hostname() == "" {
+		return redirectURIError("the URI must be absolute with a scheme and host")
+	}
+	if parsedURL.User != nil {
+		return redirectURIError("the URI must not contain user info")
+	}
+	for _, segment := range strings.Split(parsedURL.Path, "/") {
+		if segment == "." || segment == ".." {
+			return redirectURIError("the path must not contain dot segments")
+		}
+	}
+
+	// Report the reason from a rule whose host matched, as that is the registration the caller meant.
+	var hostMatchErr error
+	for _, rule := range rules {
+		if !rule.matchesHost(parsedURL.Hostname()) {
+			continue
+		}
+		err := rule.matchRest(parsedURL)
+		if err == nil {
+			return nil
+		}
+		if hostMatchErr == nil {
+			hostMatchErr = err
+		}
+	}
+	if hostMatchErr != nil {
+		return hostMatchErr
+	}
+	return redirectURIError("host %s is not registered", strings.ToLower(parsedURL.Hostname()))
+}
+
+func (r RedirectRule) matchesHost(hostname string) bool {
+	host := strings.ToLower(hostname)
+	if host == r.Host {
+		return !r.WildcardSubdomains || r.IncludeApex
+	}
+	return r.WildcardSubdomains && strings.HasSuffix(host, "."+r.Host)
+}
+
+func (r RedirectRule) matchRest(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if !containsString(r.Schemes, scheme) {
+		if scheme == "http" && !isLoopbackHost(u.Hostname()) {
+			return redirectURIError("scheme must be https; http is only allowed for localhost")
+		}
+		return redirectURIError("scheme %s is not allowed for host %s", scheme, strings.ToLower(u.Hostname()))
+	}
+
+	if r.Port != "*" {
+		port := u.Port()
+		if port == "" {
+			port = defaultPort(scheme)
+		}
+		expected := r.Port
+		if expected == "" {
+			expected = defaultPort(scheme)
+		}
+		if port != expected {
+			return redirectURIError("port %s is not allowed for host %s", port, strings.ToLower(u.Hostname()))
+		}
+	}
+
+	if r.PathPrefix != "" {
+		path := u.Path
+		if path != r.PathPrefix && !strings.HasPrefix(path, r.PathPrefix+"/") {
+			return redirectURIError("path %s is not under the registered path %s", path, r.PathPrefix)
+		}
+	}
+	return nil
+}
+
+func defaultPort(scheme string) string {
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+func isLoopbackHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -4,20 +4,20 @@ import (
 	"fmt"
 	"golang.org/x/net/context"
 	"golang.org/x/oauth2"
-	"net/url"
+	"golang.org/x/oauth2/clientcredentials"
 	"os"
 	"strings"
+	"time"
 )
 
+// ValidateRedirectURIFromEnv checks the URI against the redirect rules registered in the environment.
+// The returned error explains why the URI was rejected.
 func ValidateRedirectURIFromEnv(uri string) error {
-	allowedDomains, err := GetAllowedRedirectDomains()
+	rules, err := GetRedirectRulesFromEnv()
 	if err != nil {
 		return err
 	}
-	if !ValidateRedirectURI(uri, allowedDomains) {
-		return fmt.Errorf("invalid redirect URI")
-	}
-	return nil
+	return ValidateRedirectURIAgainstRules(uri, rules)
 }
 
 func GetAllowedRedirectDomains() ([]string, error) {
@@ -28,40 +28,51 @@ func GetAllowedRedirectDomains() ([]string, error) {
 	return strings.Split(domains, ","), nil
 }
 
-// ValidateRedirectURI checks if the URI is valid and belongs to an allowed domain.
+// ValidateRedirectURI checks if the URI is valid and belongs to an allowed domain or one of its subdomains.
 func ValidateRedirectURI(uri string, allowedDomains []string) bool {
-	parsedURL, err := url.Parse(uri)
-	if err != nil {
-		return false // Invalid URL
-	}
-
-	// Ensure the URL has a scheme and host
-	if parsedURL.Scheme == "" || parsedURL.Host == "" {
-		return false
-	}
-
-	// Extract the hostname (without port) and convert to lowercase
-	host := strings.ToLower(parsedURL.Hostname())
-
-	// Check if the domain is in the allowed list (case-insensitive)
+	rules := make([]RedirectRule, 0, len(allowedDomains))
 	for _, domain := range allowedDomains {
-		if strings.HasSuffix(host, strings.ToLower(domain)) {
-			return true
-		}
+		rules = append(rules, LegacyDomainRule(domain))
 	}
-
-	return false
+	return ValidateRedirectURIAgainstRules(uri, rules) == nil
 }
 
+// ProviderRequestTimeout bounds every request to a provider. It is shorter than the locks held around
+// token refreshes and app token fetches, so a request always finishes or fails while its lock is held
+// and a slow provider cannot let a second caller spend the same refresh token.
+const ProviderRequestTimeout = 5 * time.Second
+
 // Declare a variable that defaults to the actual implementation
 var RefreshAccessTokenFunc = RefreshAccessToken
 
 // Actual function to refresh token
 func RefreshAccessToken(oauthConfig *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ProviderRequestTimeout)
+	defer cancel()
 	token := &oauth2.Token{RefreshToken: refreshToken}
-	newToken, err := oauthConfig.TokenSource(context.Background(), token).Token()
+	newToken, err := oauthConfig.TokenSource(ctx, token).Token()
 	if err != nil {
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 	return newToken, nil
 }
+
+// Declare a variable that defaults to the actual implementation
+var FetchClientCredentialsTokenFunc = FetchClientCredentialsToken
+
+// FetchClientCredentialsToken performs the client-credentials grant for an app token that is not tied to any user
+func FetchClientCredentialsToken(oauthConfig *oauth2.Config) (*oauth2.Token, error) {
+	ccConfig := &clientcredentials.Config{
+		ClientID:     oauthConfig.ClientID,
+		ClientSecret: oauthConfig.ClientSecret,
+		TokenURL:     oauthConfig.Endpoint.TokenURL,
+		AuthStyle:    oauthConfig.Endpoint.AuthStyle,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ProviderRequestTimeout)
+	defer cancel()
+	token, err := ccConfig.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch client credentials token: %w", err)
+	}
+	return token, nil
+}